@@ -7,6 +7,7 @@ import (
 	"github.com/TimeForCoin/Server/app/controllers"
 	"github.com/TimeForCoin/Server/app/libs"
 	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/services"
 	"github.com/json-iterator/go/extra"
 	"github.com/kataras/iris/v12"
 	"github.com/rs/zerolog"
@@ -18,6 +19,10 @@ func initService(config utils.Config) {
 	if err := models.InitDB(&config.Db); err != nil {
 		panic(err)
 	}
+	// 迁移旧版数据
+	if err := models.RunMigrations(); err != nil {
+		panic(err)
+	}
 	// 初始化 Redis
 	if err := models.InitRedis(&config.Redis); err != nil {
 		panic(err)
@@ -48,6 +53,8 @@ func Run(configPath string) {
 	initService(config)
 	// 启动服务器
 	app := controllers.NewApp()
+	// 启动定时任务
+	services.StartScheduler()
 
 	// 关闭数据库
 	iris.RegisterOnInterrupt(func() {
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/TimeForCoin/Server/app/models"
 	"github.com/TimeForCoin/Server/app/services"
 	"github.com/TimeForCoin/Server/app/utils"
 	"github.com/kataras/iris/v12"
@@ -21,6 +22,13 @@ func BindArticleController(app *iris.Application) {
 	articleRoute.Handle(new(ArticleController))
 }
 
+// AfterActivation 声明需要权限的接口
+func (c *ArticleController) AfterActivation(a mvc.AfterActivation) {
+	Guard{
+		"Post": models.PermissionManageArticles,
+	}.apply(a)
+}
+
 // GetArticlesRes 公告文章列表数据
 type ArticlesListRes struct {
 	Pagination PaginationRes
@@ -74,7 +82,7 @@ func (c *ArticleController) Post() int {
 		images = append(images, fileID)
 	}
 
-	c.Service.AddArticle(id, req.Title, req.Content, req.Publisher, images)
+	c.Service.AddArticle(id, req.Title, req.Content, req.Publisher, images, c.getRequestMeta())
 	return iris.StatusOK
 }
 
@@ -100,6 +108,6 @@ func (c *ArticleController) SetBy(id string) int {
 		utils.AssertErr(err, "invalid_value", 400)
 		imageIDs = append(imageIDs, imageID)
 	}
-	c.Service.SetArticleByID(userID, articleID, req.Title, req.Content, req.Publisher, imageIDs)
+	c.Service.SetArticleByID(userID, articleID, req.Title, req.Content, req.Publisher, imageIDs, c.getRequestMeta())
 	return iris.StatusOK
 }
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/services"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditController 管理操作审计相关API
+type AuditController struct {
+	BaseController
+	Service services.AuditService
+}
+
+// BindAuditController 绑定审计控制器
+func BindAuditController(app *iris.Application) {
+	auditService := services.GetServiceManger().Audit
+
+	auditRoute := mvc.New(app.Party("/audits"))
+	auditRoute.Register(auditService, getSession().Start)
+	auditRoute.Handle(new(AuditController))
+}
+
+// AuditListRes 审计记录列表数据
+type AuditListRes struct {
+	Pagination PaginationRes
+	Data       []services.AuditDetail
+}
+
+// Get 查询审计记录[超级管理员]
+func (c *AuditController) Get() int {
+	rootID := c.checkLogin()
+	page, size := c.getPaginationData()
+
+	filter := models.AuditFilter{
+		TargetType: models.AuditTarget(c.Ctx.URLParam("target_type")),
+		Target:     c.Ctx.URLParam("target"),
+	}
+	if actor := c.Ctx.URLParam("actor"); actor != "" {
+		actorID, err := primitive.ObjectIDFromHex(actor)
+		utils.AssertErr(err, "invalid_actor", 400)
+		filter.Actor = actorID
+	}
+	if action := c.Ctx.URLParam("action"); action != "" {
+		for _, str := range strings.Split(action, ",") {
+			filter.Actions = append(filter.Actions, models.AuditAction(str))
+		}
+	}
+	var err error
+	filter.StartTime, err = strconv.ParseInt(c.Ctx.URLParamDefault("start_date", "0"), 10, 64)
+	utils.AssertErr(err, "invalid_date", 400)
+	filter.EndTime, err = strconv.ParseInt(c.Ctx.URLParamDefault("end_date", "0"), 10, 64)
+	utils.AssertErr(err, "invalid_date", 400)
+
+	count, audits := c.Service.GetAudits(rootID, filter, page, size)
+	c.JSON(AuditListRes{
+		Pagination: PaginationRes{
+			Page:  page,
+			Size:  size,
+			Total: count,
+		},
+		Data: audits,
+	})
+	return iris.StatusOK
+}
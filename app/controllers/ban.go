@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/services"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BanController 封禁相关API
+type BanController struct {
+	BaseController
+	Service services.BanService
+}
+
+// BindBanController 绑定封禁控制器
+func BindBanController(app *iris.Application) {
+	banService := services.GetServiceManger().Ban
+
+	banRoute := mvc.New(app.Party("/bans"))
+	banRoute.Register(banService, getSession().Start)
+	banRoute.Handle(new(BanController))
+}
+
+// AfterActivation 声明需要权限的接口
+func (c *BanController) AfterActivation(a mvc.AfterActivation) {
+	Guard{
+		"Get":      models.PermissionModerateContent,
+		"Post":     models.PermissionModerateContent,
+		"DeleteBy": models.PermissionModerateContent,
+	}.apply(a)
+}
+
+// banExempt 被封禁用户仍可使用的登陆接口
+var banExempt = map[string]bool{
+	iris.MethodPost + " /session/wechat":        true,
+	iris.MethodPost + " /session/wechat/finish": true,
+	iris.MethodPost + " /session/refresh":       true,
+}
+
+// banWriteGets 会修改数据的 GET 接口
+var banWriteGets = map[string]bool{
+	"/session/bind/violet": true,
+}
+
+// isBanExempt 是否不需要封禁检查：只读接口、登陆接口和注销接口
+func isBanExempt(method, path string) bool {
+	switch method {
+	case iris.MethodGet:
+		return !banWriteGets[path]
+	case iris.MethodHead, iris.MethodOptions:
+		return true
+	case iris.MethodDelete:
+		// 注销登陆会话和撤销令牌
+		return path == "/session" || strings.HasPrefix(path, "/session/")
+	}
+	return banExempt[method+" "+path]
+}
+
+// NewBanHandler 封禁检查 Handler
+// 拒绝被封禁用户的写操作，登陆和注销接口除外
+func NewBanHandler() iris.Handler {
+	return func(ctx iris.Context) {
+		if isBanExempt(ctx.Method(), ctx.Path()) {
+			ctx.Next()
+			return
+		}
+		id, err := primitive.ObjectIDFromHex(getLoginID(ctx))
+		if err == nil {
+			utils.Assert(!services.GetServiceManger().Ban.IsBanned(id), "banned", 403)
+		}
+		ctx.Next()
+	}
+}
+
+// BanListRes 封禁记录列表数据
+type BanListRes struct {
+	Pagination PaginationRes
+	Data       []services.BanDetail
+}
+
+// Get 获取用户封禁记录[管理员]
+func (c *BanController) Get() int {
+	adminID := c.checkLogin()
+	page, size := c.getPaginationData()
+	userID, err := primitive.ObjectIDFromHex(c.Ctx.URLParam("user_id"))
+	utils.AssertErr(err, "invalid_id", 400)
+
+	count, bans := c.Service.GetBans(adminID, userID, page, size)
+	c.JSON(BanListRes{
+		Pagination: PaginationRes{
+			Page:  page,
+			Size:  size,
+			Total: count,
+		},
+		Data: bans,
+	})
+	return iris.StatusOK
+}
+
+// GetMe 获取自己当前生效的封禁
+func (c *BanController) GetMe() int {
+	id := c.checkLogin()
+	ban := c.Service.GetActiveBan(id)
+	utils.Assert(ban != nil, "not_banned", 404)
+	c.JSON(ban)
+	return iris.StatusOK
+}
+
+// PostBanReq 封禁用户请求
+type PostBanReq struct {
+	UserID string `json:"user_id"`
+	Reason string
+	Days   int64 // 封禁天数，0 为永久
+}
+
+// Post 封禁用户[管理员]
+func (c *BanController) Post() int {
+	adminID := c.checkLogin()
+	req := PostBanReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	utils.AssertErr(err, "invalid_id", 400)
+	utils.Assert(req.Reason != "" && len(req.Reason) < 512, "invalid_reason", 400)
+	utils.Assert(req.Days >= 0, "invalid_days", 400)
+
+	c.Service.BanUser(adminID, userID, req.Reason, req.Days, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// DeleteBy 解除封禁[管理员]
+func (c *BanController) DeleteBy(id string) int {
+	adminID := c.checkLogin()
+	userID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+
+	c.Service.UnbanUser(adminID, userID, c.getRequestMeta())
+	return iris.StatusOK
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/services"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CampusController 校区相关API
+type CampusController struct {
+	BaseController
+	Service services.CampusService
+}
+
+// BindCampusController 绑定校区控制器
+func BindCampusController(app *iris.Application) {
+	campusService := services.GetServiceManger().Campus
+
+	campusRoute := mvc.New(app.Party("/campus"))
+	campusRoute.Register(campusService, getSession().Start)
+	campusRoute.Handle(new(CampusController))
+}
+
+// AfterActivation 声明需要权限的接口
+func (c *CampusController) AfterActivation(a mvc.AfterActivation) {
+	Guard{
+		"Post":            models.PermissionManageCampus,
+		"DeleteBy":        models.PermissionManageCampus,
+		"PostByAdminBy":   models.PermissionManageCampus,
+		"DeleteByAdminBy": models.PermissionManageCampus,
+	}.apply(a)
+}
+
+// Get 获取校区列表
+func (c *CampusController) Get() int {
+	c.JSON(c.Service.GetCampuses())
+	return iris.StatusOK
+}
+
+// GetMe 获取自己认证的校区
+func (c *CampusController) GetMe() int {
+	id := c.checkLogin()
+	campuses := c.Service.GetUserCampuses(id)
+	if campuses == nil {
+		campuses = []primitive.ObjectID{}
+	}
+	c.JSON(campuses)
+	return iris.StatusOK
+}
+
+// GetBy 获取校区详情
+func (c *CampusController) GetBy(id string) int {
+	campusID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.JSON(c.Service.GetCampus(campusID))
+	return iris.StatusOK
+}
+
+// CampusReq 添加/修改校区请求
+type CampusReq struct {
+	Name    string
+	Domains []string // 属于该校区的认证邮箱后缀
+}
+
+// Post 添加校区
+func (c *CampusController) Post() int {
+	id := c.checkLogin()
+	req := CampusReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	utils.Assert(req.Name != "" && len(req.Name) < 64, "invalid_name", 400)
+	campusID := c.Service.AddCampus(id, req.Name, req.Domains, c.getRequestMeta())
+	c.JSON(struct {
+		ID string `json:"id"`
+	}{
+		ID: campusID.Hex(),
+	})
+	return iris.StatusOK
+}
+
+// PutBy 修改校区，校区管理员只能修改邮箱后缀
+func (c *CampusController) PutBy(id string) int {
+	userID := c.checkLogin()
+	campusID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	req := CampusReq{}
+	err = c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	utils.Assert(len(req.Name) < 64, "invalid_name", 400)
+	c.Service.SetCampus(userID, campusID, req.Name, req.Domains, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// DeleteBy 删除校区
+func (c *CampusController) DeleteBy(id string) int {
+	userID := c.checkLogin()
+	campusID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.RemoveCampus(userID, campusID, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// PostByAdminBy 添加校区管理员
+func (c *CampusController) PostByAdminBy(id, user string) int {
+	userID := c.checkLogin()
+	campusID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	adminID, err := primitive.ObjectIDFromHex(user)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.AddCampusAdmin(userID, campusID, adminID, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// DeleteByAdminBy 移除校区管理员
+func (c *CampusController) DeleteByAdminBy(id, user string) int {
+	userID := c.checkLogin()
+	campusID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	adminID, err := primitive.ObjectIDFromHex(user)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.RemoveCampusAdmin(userID, campusID, adminID, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// PutByTaskBy 屏蔽或恢复校区内的任务[校区管理员]
+func (c *CampusController) PutByTaskBy(id, task string) int {
+	userID := c.checkLogin()
+	campusID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	taskID, err := primitive.ObjectIDFromHex(task)
+	utils.AssertErr(err, "invalid_id", 400)
+	req := struct {
+		Hidden bool
+	}{}
+	err = c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	c.Service.SetTaskHidden(userID, campusID, taskID, req.Hidden, c.getRequestMeta())
+	return iris.StatusOK
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/TimeForCoin/Server/app/services"
 	"github.com/TimeForCoin/Server/app/utils"
 	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -14,6 +15,19 @@ type CertificationController struct {
 	Service services.UserService
 }
 
+// AfterActivation 声明需要权限的接口
+func (c *CertificationController) AfterActivation(a mvc.AfterActivation) {
+	Guard{
+		"GetCertification": models.PermissionReviewCertification,
+		"GetAuto":          models.PermissionReviewCertification,
+		"PostAuto":         models.PermissionReviewCertification,
+		"DeleteAutoBy":     models.PermissionReviewCertification,
+		"GetInvite":        models.PermissionReviewCertification,
+		"PostInvite":       models.PermissionReviewCertification,
+		"DeleteInviteBy":   models.PermissionReviewCertification,
+	}.apply(a)
+}
+
 // PostCertificationReq 申请认证请求
 type PostCertificationReq struct {
 	Identity   models.UserIdentity
@@ -21,6 +35,17 @@ type PostCertificationReq struct {
 	Type       string
 	Attachment []string
 	Email      string
+	Code       string // 邀请码，认证身份由邀请码决定
+}
+
+// GetIdentities 获取可认证的身份类型及认证方式
+func (c *CertificationController) GetIdentities() int {
+	c.JSON(struct {
+		Data []utils.IdentityConfig
+	}{
+		Data: c.Service.GetIdentities(),
+	})
+	return iris.StatusOK
 }
 
 // Post 提交用户认证
@@ -29,9 +54,9 @@ func (c *CertificationController) Post() int {
 	req := PostCertificationReq{}
 	err := c.Ctx.ReadJSON(&req)
 	utils.AssertErr(err, "invalid_valid", 400)
-	// 目前只支持学生认证
-	utils.Assert(req.Identity == models.IdentityStudent, "invalid_identity", 400)
-	// 目前只支持邮箱认证
+	if req.Type != "invite" {
+		utils.Assert(utils.IsIdentity(string(req.Identity)), "invalid_identity", 400)
+	}
 	switch req.Type {
 	case "email":
 		utils.Assert(utils.IsEmail(req.Email), "invalid_email", 400)
@@ -45,6 +70,9 @@ func (c *CertificationController) Post() int {
 		}
 		utils.Assert(len(material) > 0, "invalid_attachment", 400)
 		c.Service.AddMaterialCertification(req.Identity, id, req.Data, material)
+	case "invite":
+		utils.Assert(req.Code != "", "invalid_code", 400)
+		c.Service.AddInviteCertification(id, req.Code)
 	default:
 		utils.Assert(false, "invalid_type", 400)
 	}
@@ -113,13 +141,15 @@ func (c *CertificationController) GetAuto() int {
 func (c *CertificationController) PostAuto() int {
 	userID := c.checkLogin()
 	req := struct {
-		Key   string
-		Value string
+		Key        string
+		Value      string
+		Identities []models.UserIdentity // 适用的身份，为空时仅适用学生
+		ValidDays  int64                 // 认证有效天数，0 为使用默认值
 	}{}
 	err := c.Ctx.ReadJSON(&req)
 	utils.AssertErr(err, "invalid_value", 400)
-	utils.Assert(req.Key != "" && req.Value != "", "invalid_value", 400)
-	c.Service.AddAutoCertification(userID, req.Key, req.Value)
+	utils.Assert(req.Key != "" && req.Value != "" && req.ValidDays >= 0, "invalid_value", 400)
+	c.Service.AddAutoCertification(userID, req.Key, req.Value, req.Identities, req.ValidDays, c.getRequestMeta())
 	return iris.StatusOK
 }
 
@@ -127,7 +157,60 @@ func (c *CertificationController) PostAuto() int {
 func (c *CertificationController) DeleteAutoBy(key string) int {
 	userID := c.checkLogin()
 	utils.Assert(key != "", "invalid_key", 400)
-	c.Service.RemoveAutoCertification(userID, key)
+	c.Service.RemoveAutoCertification(userID, key, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// GetInvite 获取认证邀请码列表
+func (c *CertificationController) GetInvite() int {
+	userID := c.checkLogin()
+	page, size := c.getPaginationData()
+	count, invites := c.Service.GetInvites(userID, page, size)
+	c.JSON(struct {
+		Pagination PaginationRes
+		Data       []models.InviteSchema
+	}{
+		Pagination: PaginationRes{
+			Page:  page,
+			Size:  size,
+			Total: count,
+		},
+		Data: invites,
+	})
+	return iris.StatusOK
+}
+
+// PostInviteReq 发放邀请码请求
+type PostInviteReq struct {
+	Identity models.UserIdentity
+	Data     string
+	MaxUse   int64 // 可使用次数，0 为不限制
+	Days     int64 // 有效天数，0 为永久有效
+}
+
+// PostInvite 发放认证邀请码
+func (c *CertificationController) PostInvite() int {
+	userID := c.checkLogin()
+	req := PostInviteReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	utils.Assert(req.Data != "", "invalid_data", 400)
+	utils.Assert(req.MaxUse >= 0 && req.Days >= 0, "invalid_value", 400)
+	code := c.Service.AddInvite(userID, req.Identity, req.Data, req.MaxUse, req.Days, c.getRequestMeta())
+	c.JSON(struct {
+		Code string
+	}{
+		Code: code,
+	})
+	return iris.StatusOK
+}
+
+// DeleteInviteBy 删除认证邀请码
+func (c *CertificationController) DeleteInviteBy(id string) int {
+	userID := c.checkLogin()
+	inviteID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.RemoveInvite(userID, inviteID, c.getRequestMeta())
 	return iris.StatusOK
 }
 
@@ -148,6 +231,7 @@ func (c *CertificationController) GetAuth() string {
 
 // PutUserReq 更新认证请求
 type PutUserReq struct {
+	Identity models.UserIdentity
 	Operate  string
 	Data     string
 	Feedback string
@@ -167,17 +251,14 @@ func (c *CertificationController) PutUserBy(userID string) int {
 	req := PutUserReq{}
 	err := c.Ctx.ReadJSON(&req)
 	utils.AssertErr(err, "invalid_value", 400)
+	utils.Assert(req.Identity != "", "invalid_identity", 400)
 	if req.Operate == "cancel" {
-		c.Service.CancelCertification(opUser)
+		c.Service.CancelCertification(opUser, req.Identity)
 	} else if req.Operate == "true" || req.Operate == "false" {
-		// 验证管理员权限
-		user, err := models.GetRedis().Cache.GetUserBaseInfo(sessionUser)
-		utils.AssertErr(err, "invalid_session", 401)
-		utils.Assert(user.Type == models.UserTypeRoot || user.Type == models.UserTypeAdmin, "permission_deny", 403)
 		if req.Operate == "true" {
-			c.Service.UpdateCertification(opUser, req.Operate, req.Data)
+			c.Service.UpdateCertification(sessionUser, opUser, req.Identity, req.Operate, req.Data, c.getRequestMeta())
 		} else {
-			c.Service.UpdateCertification(opUser, req.Operate, req.Feedback)
+			c.Service.UpdateCertification(sessionUser, opUser, req.Identity, req.Operate, req.Feedback, c.getRequestMeta())
 		}
 	} else {
 		utils.Assert(false, "invalid_operate", 400)
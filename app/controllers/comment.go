@@ -30,7 +30,7 @@ func (c *CommentController) GetBy(id string) int {
 	contentID, err := primitive.ObjectIDFromHex(id)
 	utils.AssertErr(err, "invalid_id", 400)
 
-	res := c.Service.GetComments(contentID, c.Session.GetString("id"), page, size, sort)
+	res := c.Service.GetComments(contentID, c.getUserID(), page, size, sort)
 	c.JSON(struct {
 		Pagination PaginationRes
 		Data       []services.CommentData
@@ -81,7 +81,7 @@ func (c *CommentController) DeleteBy(id string) int {
 	commentID, err := primitive.ObjectIDFromHex(id)
 	utils.AssertErr(err, "invalid_id", 400)
 
-	c.Service.RemoveComment(userID, commentID)
+	c.Service.RemoveComment(userID, commentID, c.getRequestMeta())
 
 	return iris.StatusOK
 }
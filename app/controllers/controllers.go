@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/TimeForCoin/Server/app/models"
 	"github.com/TimeForCoin/Server/app/utils"
 
 	"github.com/kataras/iris/v12/sessions/sessiondb/redis"
@@ -29,6 +30,10 @@ func NewApp() *iris.Application {
 
 	app.Use(utils.NewErrorHandler())
 
+	app.Use(NewTokenHandler())
+
+	app.Use(NewBanHandler())
+
 	BindUserController(app)
 	BindArticleController(app)
 	BindTaskController(app)
@@ -37,6 +42,12 @@ func NewApp() *iris.Application {
 	BindCommentController(app)
 	BindMessageController(app)
 	BindUtilsController(app)
+	BindReportController(app)
+	BindFilterController(app)
+	BindBanController(app)
+	BindRoleController(app)
+	BindAuditController(app)
+	BindCampusController(app)
 
 	return app
 }
@@ -58,7 +69,7 @@ type PaginationRes struct {
 
 // 检查登陆状态
 func (b *BaseController) checkLogin() primitive.ObjectID {
-	id := b.Session.GetString("id")
+	id := b.getUserID()
 	_id, err := primitive.ObjectIDFromHex(id)
 	utils.Assert(err == nil, "invalid_session", 401)
 	// login := b.Session.GetString("login")
@@ -66,6 +77,44 @@ func (b *BaseController) checkLogin() primitive.ObjectID {
 	return _id
 }
 
+// 检查登陆状态，不允许使用个人 API 令牌，用于账号和令牌管理等敏感操作
+func (b *BaseController) checkUserLogin() primitive.ObjectID {
+	id := b.checkLogin()
+	auth, ok := getTokenAuth(b.Ctx)
+	utils.Assert(!ok || auth.Type != utils.TokenAPI, "permission_deny", 403)
+	return id
+}
+
+// 获取当前登陆用户 ID，未登录时为空
+func (b *BaseController) getUserID() string {
+	if auth, ok := getTokenAuth(b.Ctx); ok {
+		return auth.User.Hex()
+	}
+	return checkCookieSession(b.Ctx, b.Session)
+}
+
+// 获取当前登陆会话 ID
+func (b *BaseController) getSessionID() string {
+	if auth, ok := getTokenAuth(b.Ctx); ok {
+		return auth.Session
+	}
+	return b.Session.GetString("sid")
+}
+
+// 获取请求信息，用于记录管理操作
+func (b *BaseController) getRequestMeta() models.RequestMeta {
+	return requestMeta(b.Ctx)
+}
+
+func requestMeta(ctx iris.Context) models.RequestMeta {
+	return models.RequestMeta{
+		IP:        ctx.RemoteAddr(),
+		UserAgent: ctx.GetHeader("User-Agent"),
+		Method:    ctx.Method(),
+		Path:      ctx.Path(),
+	}
+}
+
 func (b *BaseController) getPaginationData() (page, size int64) {
 	var err error
 	pageStr := b.Ctx.URLParamDefault("page", "1")
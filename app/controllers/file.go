@@ -71,7 +71,7 @@ type DeleteUselessReq struct {
 // DeleteUseless 删除用户未使用文件
 func (c *FileController) DeleteUseless() int {
 	userID := c.checkLogin()
-	count := c.Service.RemoveUselessFile(userID, false)
+	count := c.Service.RemoveUselessFile(userID, false, c.getRequestMeta())
 
 	c.JSON(DeleteUselessReq{
 		RemoveCount: count,
@@ -83,7 +83,7 @@ func (c *FileController) DeleteUseless() int {
 // DeleteUselessAll 删除所有未使用文件
 func (c *FileController) DeleteUselessAll() int {
 	userID := c.checkLogin()
-	count := c.Service.RemoveUselessFile(userID, true)
+	count := c.Service.RemoveUselessFile(userID, true, c.getRequestMeta())
 
 	c.JSON(DeleteUselessReq{
 		RemoveCount: count,
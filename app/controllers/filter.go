@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/services"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+)
+
+// FilterController 敏感词过滤相关API
+type FilterController struct {
+	BaseController
+	Service services.FilterService
+}
+
+// BindFilterController 绑定敏感词过滤控制器
+func BindFilterController(app *iris.Application) {
+	filterService := services.GetServiceManger().Filter
+
+	filterRoute := mvc.New(app.Party("/filter"))
+	filterRoute.Register(filterService, getSession().Start)
+	filterRoute.Handle(new(FilterController))
+}
+
+// AfterActivation 声明需要权限的接口
+func (c *FilterController) AfterActivation(a mvc.AfterActivation) {
+	Guard{
+		"GetWords":      models.PermissionModerateContent,
+		"PostWords":     models.PermissionModerateContent,
+		"DeleteWordsBy": models.PermissionModerateContent,
+	}.apply(a)
+}
+
+// WordListRes 敏感词列表数据
+type WordListRes struct {
+	Pagination PaginationRes
+	Data       []models.SystemSchemas
+}
+
+// GetWords 获取敏感词列表
+func (c *FilterController) GetWords() int {
+	userID := c.checkLogin()
+	page, size := c.getPaginationData()
+
+	count, words := c.Service.GetWords(userID, page, size)
+	if words == nil {
+		words = []models.SystemSchemas{}
+	}
+
+	c.JSON(WordListRes{
+		Pagination: PaginationRes{
+			Page:  page,
+			Size:  size,
+			Total: count,
+		},
+		Data: words,
+	})
+	return iris.StatusOK
+}
+
+// PostWordsReq 添加敏感词请求
+type PostWordsReq struct {
+	Word   string
+	Action string // 处理方式 reject/mask/review
+}
+
+// PostWords 添加或修改敏感词
+func (c *FilterController) PostWords() int {
+	userID := c.checkLogin()
+	req := PostWordsReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+
+	c.Service.AddWord(userID, req.Word, models.FilterAction(req.Action), c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// DeleteWordsBy 移除敏感词
+func (c *FilterController) DeleteWordsBy(word string) int {
+	userID := c.checkLogin()
+	c.Service.RemoveWord(userID, word, c.getRequestMeta())
+	return iris.StatusOK
+}
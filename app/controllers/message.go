@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/TimeForCoin/Server/app/models"
 	"github.com/TimeForCoin/Server/app/services"
 	"github.com/TimeForCoin/Server/app/utils"
 	"github.com/kataras/iris/v12"
@@ -23,6 +24,13 @@ func BindMessageController(app *iris.Application) {
 	messageRoute.Handle(new(MessageController))
 }
 
+// AfterActivation 声明需要权限的接口
+func (c *MessageController) AfterActivation(a mvc.AfterActivation) {
+	Guard{
+		"PostSystem": models.PermissionSendSystemMessage,
+	}.apply(a)
+}
+
 // GetMessagesRes 获取会话列表数据
 type GetMessagesRes struct {
 	Pagination PaginationRes
@@ -67,7 +75,7 @@ func (c *MessageController) PostSystem() int {
 		aboutID, err = primitive.ObjectIDFromHex(req.About)
 		utils.AssertErr(err, "invalid_id")
 	}
-	c.Service.SendSystemMessage(userID, aboutID, req.Title, req.Content)
+	c.Service.SendSystemMessage(userID, aboutID, req.Title, req.Content, c.getRequestMeta())
 
 	return iris.StatusOK
 }
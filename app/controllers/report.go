@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/services"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportController 举报相关API
+type ReportController struct {
+	BaseController
+	Service services.ReportService
+}
+
+// BindReportController 绑定举报控制器
+func BindReportController(app *iris.Application) {
+	reportService := services.GetServiceManger().Report
+
+	reportRoute := mvc.New(app.Party("/reports"))
+	reportRoute.Register(reportService, getSession().Start)
+	reportRoute.Handle(new(ReportController))
+}
+
+// AfterActivation 声明需要权限的接口
+func (c *ReportController) AfterActivation(a mvc.AfterActivation) {
+	Guard{
+		"Get":   models.PermissionModerateContent,
+		"PutBy": models.PermissionModerateContent,
+	}.apply(a)
+}
+
+// PostReportReq 举报请求
+type PostReportReq struct {
+	Type    string // 举报内容类型
+	ID      string `json:"id"` // 举报内容 ID (聊天消息为会话 ID)
+	Time    int64  // 聊天消息发送时间
+	Reason  string
+	Content string
+}
+
+// Post 举报内容
+func (c *ReportController) Post() int {
+	userID := c.checkLogin()
+	req := PostReportReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+
+	targetID, err := primitive.ObjectIDFromHex(req.ID)
+	utils.AssertErr(err, "invalid_id", 400)
+
+	targetType := models.ReportTargetType(req.Type)
+	utils.Assert(targetType == models.ReportTargetTask || targetType == models.ReportTargetComment ||
+		targetType == models.ReportTargetChat || targetType == models.ReportTargetUser, "invalid_type", 400)
+	utils.Assert(targetType != models.ReportTargetChat || req.Time != 0, "invalid_time", 400)
+
+	reason := models.ReportReason(req.Reason)
+	utils.Assert(reason == models.ReportReasonSpam || reason == models.ReportReasonAbuse ||
+		reason == models.ReportReasonPorn || reason == models.ReportReasonFraud ||
+		reason == models.ReportReasonIllegal || reason == models.ReportReasonOther, "invalid_reason", 400)
+	utils.Assert(len(req.Content) < 512, "content_too_long", 403)
+
+	id := c.Service.AddReport(userID, targetType, targetID, reason, req.Content, req.Time)
+	c.JSON(struct {
+		ID string `json:"id"`
+	}{
+		ID: id.Hex(),
+	})
+	return iris.StatusOK
+}
+
+// ReportListRes 举报列表数据
+type ReportListRes struct {
+	Pagination PaginationRes
+	Data       []services.ReportDetail
+}
+
+// Get 获取举报处理队列
+func (c *ReportController) Get() int {
+	userID := c.checkLogin()
+	page, size := c.getPaginationData()
+	status := c.Ctx.URLParamDefault("status", "wait")
+	targetType := c.Ctx.URLParamDefault("type", "all")
+
+	count, reports := c.Service.GetReports(userID, status, targetType, page, size)
+	if reports == nil {
+		reports = []services.ReportDetail{}
+	}
+
+	c.JSON(ReportListRes{
+		Pagination: PaginationRes{
+			Page:  page,
+			Size:  size,
+			Total: count,
+		},
+		Data: reports,
+	})
+	return iris.StatusOK
+}
+
+// PutReportReq 处理举报请求
+type PutReportReq struct {
+	Action   string
+	Feedback string
+}
+
+// PutBy 处理举报
+func (c *ReportController) PutBy(id string) int {
+	userID := c.checkLogin()
+	reportID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+
+	req := PutReportReq{}
+	err = c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+
+	c.Service.HandleReport(userID, reportID, models.ReportAction(req.Action), req.Feedback, c.getRequestMeta())
+	return iris.StatusOK
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/services"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoleController 角色权限相关API
+type RoleController struct {
+	BaseController
+	Service services.RoleService
+}
+
+// BindRoleController 绑定角色控制器
+func BindRoleController(app *iris.Application) {
+	roleService := services.GetServiceManger().Role
+
+	roleRoute := mvc.New(app.Party("/roles"))
+	roleRoute.Register(roleService, getSession().Start)
+	roleRoute.Handle(new(RoleController))
+}
+
+// Guard 控制器方法所需的权限，键为控制器方法名
+// 在控制器的 AfterActivation 中调用 apply 声明，例如
+//
+//	func (c *XController) AfterActivation(a mvc.AfterActivation) {
+//		Guard{"Get": models.PermissionModerateContent}.apply(a)
+//	}
+type Guard map[string]models.Permission
+
+// apply 为控制器方法的路由添加权限检查
+// 检查插入在控制器方法之前，位于全局中间件(错误处理等)之后
+func (g Guard) apply(a mvc.AfterActivation) {
+	for method, permission := range g {
+		routes := a.GetRoutes(method)
+		if len(routes) == 0 {
+			panic("guard: unknown method " + a.Name() + "." + method)
+		}
+		for _, route := range routes {
+			n := len(route.Handlers)
+			route.Handlers = append(route.Handlers[:n-1:n-1], requirePermission(permission), route.Handlers[n-1])
+		}
+	}
+}
+
+// requirePermission 权限检查 Handler
+func requirePermission(permission models.Permission) iris.Handler {
+	return func(ctx iris.Context) {
+		id, err := primitive.ObjectIDFromHex(getLoginID(ctx))
+		utils.AssertErr(err, "invalid_session", 401)
+		if auth, ok := getTokenAuth(ctx); ok {
+			utils.Assert(auth.HasScope(models.ScopeAdmin), "insufficient_scope", 403)
+		}
+		utils.Assert(services.GetServiceManger().Role.HasPermission(id, permission), "permission_deny", 403)
+		ctx.Next()
+	}
+}
+
+// GetPermissions 获取全部可分配的权限
+func (c *RoleController) GetPermissions() int {
+	c.JSON(models.AllPermissions)
+	return iris.StatusOK
+}
+
+// GetMe 获取自己拥有的权限
+func (c *RoleController) GetMe() int {
+	id := c.checkLogin()
+	c.JSON(c.Service.GetPermissions(id))
+	return iris.StatusOK
+}
+
+// Get 获取角色列表[超级管理员]
+func (c *RoleController) Get() int {
+	id := c.checkLogin()
+	c.JSON(c.Service.GetRoles(id))
+	return iris.StatusOK
+}
+
+// RoleReq 添加/修改角色请求
+type RoleReq struct {
+	Name        string
+	Description string
+	Permissions []models.Permission
+}
+
+func (c *RoleController) readRoleReq() RoleReq {
+	req := RoleReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	utils.Assert(req.Name != "" && len(req.Name) < 64, "invalid_name", 400)
+	utils.Assert(len(req.Description) < 512, "invalid_description", 400)
+	return req
+}
+
+// Post 添加角色[超级管理员]
+func (c *RoleController) Post() int {
+	id := c.checkLogin()
+	req := c.readRoleReq()
+	roleID := c.Service.AddRole(id, req.Name, req.Description, req.Permissions, c.getRequestMeta())
+	c.JSON(struct {
+		ID string `json:"id"`
+	}{
+		ID: roleID.Hex(),
+	})
+	return iris.StatusOK
+}
+
+// PutBy 修改角色[超级管理员]
+func (c *RoleController) PutBy(roleIDString string) int {
+	id := c.checkLogin()
+	roleID, err := primitive.ObjectIDFromHex(roleIDString)
+	utils.AssertErr(err, "invalid_id", 400)
+	req := c.readRoleReq()
+	c.Service.SetRole(id, roleID, req.Name, req.Description, req.Permissions, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// DeleteBy 删除角色[超级管理员]
+func (c *RoleController) DeleteBy(roleIDString string) int {
+	id := c.checkLogin()
+	roleID, err := primitive.ObjectIDFromHex(roleIDString)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.RemoveRole(id, roleID, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// GetByUsers 获取被授予角色的用户[超级管理员]
+func (c *RoleController) GetByUsers(roleIDString string) int {
+	id := c.checkLogin()
+	roleID, err := primitive.ObjectIDFromHex(roleIDString)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.JSON(c.Service.GetRoleUsers(id, roleID))
+	return iris.StatusOK
+}
+
+// PostByUsersBy 授予用户角色[超级管理员]
+func (c *RoleController) PostByUsersBy(roleIDString, userIDString string) int {
+	id := c.checkLogin()
+	roleID, err := primitive.ObjectIDFromHex(roleIDString)
+	utils.AssertErr(err, "invalid_id", 400)
+	userID, err := primitive.ObjectIDFromHex(userIDString)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.GrantRole(id, roleID, userID, c.getRequestMeta())
+	return iris.StatusOK
+}
+
+// DeleteByUsersBy 撤销用户角色[超级管理员]
+func (c *RoleController) DeleteByUsersBy(roleIDString, userIDString string) int {
+	id := c.checkLogin()
+	roleID, err := primitive.ObjectIDFromHex(roleIDString)
+	utils.AssertErr(err, "invalid_id", 400)
+	userID, err := primitive.ObjectIDFromHex(userIDString)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.RevokeRole(id, roleID, userID, c.getRequestMeta())
+	return iris.StatusOK
+}
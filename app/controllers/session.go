@@ -1,9 +1,14 @@
 package controllers
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/TimeForCoin/Server/app/models"
 	"github.com/TimeForCoin/Server/app/services"
 	"github.com/TimeForCoin/Server/app/utils"
 	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/sessions"
 	"github.com/skip2/go-qrcode"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -14,6 +19,89 @@ type SessionController struct {
 	Service services.UserService
 }
 
+// 请求上下文中的键名
+const (
+	tokenAuthKey     = "token-auth"     // 令牌认证结果
+	cookieCheckedKey = "cookie-checked" // 已检查的 Cookie 登陆用户 ID
+)
+
+// NewTokenHandler 令牌认证 Handler
+// 解析 Authorization: Bearer 请求头中的访问令牌或个人 API 令牌，没有 write 权限范围的令牌只能进行读操作
+func NewTokenHandler() iris.Handler {
+	return func(ctx iris.Context) {
+		header := ctx.GetHeader("Authorization")
+		// 访问令牌过期后仍可刷新
+		if header == "" || ctx.Path() == "/session/refresh" {
+			ctx.Next()
+			return
+		}
+		utils.Assert(strings.HasPrefix(header, "Bearer "), "invalid_token", 401)
+		auth, ok := services.GetServiceManger().Token.Authenticate(strings.TrimPrefix(header, "Bearer "), requestMeta(ctx))
+		utils.Assert(ok, "invalid_token", 401)
+		method := ctx.Method()
+		if method != iris.MethodGet && method != iris.MethodHead && method != iris.MethodOptions {
+			utils.Assert(auth.HasScope(models.ScopeWrite), "insufficient_scope", 403)
+		}
+		ctx.Values().Set(tokenAuthKey, auth)
+		ctx.Next()
+	}
+}
+
+// getTokenAuth 获取请求的令牌认证结果，未使用令牌时返回 false
+func getTokenAuth(ctx iris.Context) (services.TokenAuth, bool) {
+	auth, ok := ctx.Values().Get(tokenAuthKey).(services.TokenAuth)
+	return auth, ok
+}
+
+// getLoginID 获取当前登陆用户 ID，优先使用令牌认证结果
+func getLoginID(ctx iris.Context) string {
+	if auth, ok := getTokenAuth(ctx); ok {
+		return auth.User.Hex()
+	}
+	return checkCookieSession(ctx, getSession().Start(ctx))
+}
+
+// checkCookieSession 检查 Cookie 登陆状态对应的登陆会话，会话已被注销时清除登陆状态
+// 旧版本的登陆状态没有登陆会话记录，无法注销，需要重新登陆
+func checkCookieSession(ctx iris.Context, session *sessions.Session) string {
+	if checked, ok := ctx.Values().Get(cookieCheckedKey).(string); ok {
+		return checked
+	}
+	id := session.GetString("id")
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return id
+	}
+	sessionID := session.GetString("sid")
+	if sessionID == "" || !services.GetServiceManger().Token.CheckSession(sessionID, userID, requestMeta(ctx)) {
+		session.Set("login", "none")
+		session.Delete("id")
+		session.Delete("sid")
+		id = ""
+	}
+	ctx.Values().Set(cookieCheckedKey, id)
+	return id
+}
+
+// switchUser 绑定账号后若合并到了另一个账号，则切换 Cookie 登陆状态到该账号
+func (c *SessionController) switchUser(userID primitive.ObjectID, id, method string) {
+	if id == userID.Hex() {
+		return
+	}
+	newID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "", 500)
+	c.login(newID, method, "")
+}
+
+// login 设置 Cookie 登陆状态，sessionID 为空时创建新的登陆会话
+func (c *SessionController) login(userID primitive.ObjectID, method, sessionID string) {
+	if sessionID == "" {
+		sessionID = services.GetServiceManger().Token.AddSession(userID, method, c.getRequestMeta())
+	}
+	c.Session.Set("id", userID.Hex())
+	c.Session.Set("sid", sessionID)
+}
+
 // GetSessionRes 获取登陆URL返回值
 type GetSessionRes struct {
 	URL string `json:"url"`
@@ -46,17 +134,53 @@ func (c *SessionController) GetViolet() int {
 	utils.Assert(state == rightState, "状态校验失败，请重试")
 	c.Session.Delete("state")
 
-	id, _ := c.Service.LoginByViolet(code)
+	// 已登录用户绑定 Violet 账号
+	if bind := c.Session.GetString("bind"); bind != "" {
+		c.Session.Delete("bind")
+		userID := c.checkLogin()
+		id := c.Service.BindViolet(userID, code, bind == "merge")
+		c.switchUser(userID, id, "violet")
+		c.Session.Set("login", "violet")
+		return iris.StatusOK
+	}
+
+	id, newUser := c.Service.LoginByViolet(code)
 	utils.Assert(id != "", "登陆已过期，请重试")
+	userID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "", 500)
 
-	c.Session.Set("id", id)
-	c.Session.Set("login", "violet")
+	tokens := services.GetServiceManger().Token.IssueTokens(userID, "violet", c.getRequestMeta())
+	c.JSON(tokens)
+	c.login(userID, "violet", tokens.Session)
+	if newUser {
+		c.Session.Set("login", "violet_new")
+	} else {
+		c.Session.Set("login", "violet")
+	}
+	return iris.StatusOK
+}
+
+// GetBindViolet 获取绑定 Violet 账号的授权 URL
+// merge 为 true 时，若该 Violet 账号已注册则合并两个账号
+func (c *SessionController) GetBindViolet() int {
+	c.checkLogin()
+	loginURL, state := c.Service.GetLoginURL()
+	c.Session.Set("state", state)
+	if c.Ctx.URLParamDefault("merge", "false") == "true" {
+		c.Session.Set("bind", "merge")
+	} else {
+		c.Session.Set("bind", "bind")
+	}
+	utils.JSON(c.Ctx, GetSessionRes{
+		URL: loginURL,
+	})
 	return iris.StatusOK
 }
 
 // PostWechatRes 微信登陆数据
 type PostWechatRes struct {
 	New bool
+	services.TokenPair
 }
 
 // PostWechatReq 温馨登陆请求
@@ -71,10 +195,14 @@ func (c *SessionController) PostWechat() int {
 	utils.Assert(err == nil && req.Code != "", "invalid_code", 400)
 
 	id, newUser := c.Service.LoginByWechat(req.Code)
+	userID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "", 500)
+	tokens := services.GetServiceManger().Token.IssueTokens(userID, "wechat", c.getRequestMeta())
 	c.JSON(PostWechatRes{
-		New: newUser,
+		New:       newUser,
+		TokenPair: tokens,
 	})
-	c.Session.Set("id", id)
+	c.login(userID, "wechat", tokens.Session)
 	if newUser {
 		c.Session.Set("login", "wechat_new")
 	} else {
@@ -83,43 +211,136 @@ func (c *SessionController) PostWechat() int {
 	return iris.StatusOK
 }
 
+// PostBindWechatReq 绑定微信请求
+type PostBindWechatReq struct {
+	Code  string
+	Merge bool // 若该微信账号已注册则合并两个账号
+}
+
+// PostBindWechat 已登录用户绑定微信账号
+func (c *SessionController) PostBindWechat() int {
+	userID := c.checkLogin()
+	req := PostBindWechatReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.Assert(err == nil && req.Code != "", "invalid_code", 400)
+
+	id := c.Service.BindWechat(userID, req.Code, req.Merge)
+	c.switchUser(userID, id, "wechat")
+	return iris.StatusOK
+}
+
 // GetSessionStatusRes 获取登陆状态返回值
 type GetSessionStatusRes struct {
 	Status string
 }
 
 // GetStatus 获取登陆状态
+// 扫码登陆的状态通过 GET /session/wechat/events 推送，确认后通过 POST /session/wechat/finish 完成登陆
 func (c *SessionController) GetStatus() int {
 
 	status := c.Session.GetString("login")
 	if status == "" {
 		status = "none"
 	}
-	if status == "wechat_qr" {
-		session := c.Session.GetString("qr-code")
-		sessionID, err := primitive.ObjectIDFromHex(session)
-		if err != nil {
-			status = "none"
-			c.Session.Set("login", status)
-		} else {
-			id := c.Service.GetSessionUser(sessionID)
-			if id != primitive.NilObjectID {
-				c.Session.Set("id", id.Hex())
-				status = "wechat_pc"
-				c.Session.Set("login", status)
-			}
-		}
-	}
 	c.JSON(GetSessionStatusRes{
 		Status: status,
 	})
 	return iris.StatusOK
 }
 
-// Delete 退出登陆
+// Delete 退出登陆，同时注销当前登陆会话
 func (c *SessionController) Delete() int {
+	if sessionID := c.getSessionID(); sessionID != "" {
+		services.GetServiceManger().Token.RevokeSession(sessionID)
+	}
 	c.Session.Set("login", "none")
 	c.Session.Delete("id")
+	c.Session.Delete("sid")
+	return iris.StatusOK
+}
+
+// SessionDetail 登陆会话详情
+type SessionDetail struct {
+	models.LoginSession
+	Current bool // 是否为当前会话
+}
+
+// GetList 获取当前用户的全部登陆会话
+func (c *SessionController) GetList() int {
+	id := c.checkUserLogin()
+	current := c.getSessionID()
+	res := []SessionDetail{}
+	for _, session := range services.GetServiceManger().Token.GetSessions(id) {
+		res = append(res, SessionDetail{
+			LoginSession: session,
+			Current:      session.ID == current,
+		})
+	}
+	c.JSON(res)
+	return iris.StatusOK
+}
+
+// DeleteBy 注销某个登陆会话
+func (c *SessionController) DeleteBy(sessionID string) int {
+	id := c.checkUserLogin()
+	services.GetServiceManger().Token.RemoveSession(id, sessionID)
+	return iris.StatusOK
+}
+
+// DeleteAll 退出全部设备的登陆，包括当前会话，个人 API 令牌不受影响
+func (c *SessionController) DeleteAll() int {
+	id := c.checkUserLogin()
+	services.GetServiceManger().Token.RemoveAllSessions(id)
+	c.Session.Set("login", "none")
+	c.Session.Delete("id")
+	c.Session.Delete("sid")
+	return iris.StatusOK
+}
+
+// PostRefreshReq 刷新令牌请求
+type PostRefreshReq struct {
+	RefreshToken string
+}
+
+// PostRefresh 使用刷新令牌换取新的访问令牌和刷新令牌
+func (c *SessionController) PostRefresh() int {
+	req := PostRefreshReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.Assert(err == nil && req.RefreshToken != "", "invalid_token", 400)
+	c.JSON(services.GetServiceManger().Token.RefreshTokens(req.RefreshToken))
+	return iris.StatusOK
+}
+
+// GetToken 获取个人 API 令牌列表
+func (c *SessionController) GetToken() int {
+	id := c.checkUserLogin()
+	c.JSON(services.GetServiceManger().Token.GetAPITokens(id))
+	return iris.StatusOK
+}
+
+// PostTokenReq 创建个人 API 令牌请求
+type PostTokenReq struct {
+	Name   string
+	Scopes []models.APIScope
+	Days   int64 // 有效天数，0 为永不过期
+}
+
+// PostToken 创建个人 API 令牌，令牌只在创建时返回一次
+func (c *SessionController) PostToken() int {
+	id := c.checkUserLogin()
+	req := PostTokenReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	c.JSON(services.GetServiceManger().Token.AddAPIToken(id, req.Name, req.Scopes, req.Days))
+	return iris.StatusOK
+}
+
+// DeleteTokenBy 删除个人 API 令牌
+func (c *SessionController) DeleteTokenBy(tokenID string) int {
+	id := c.checkUserLogin()
+	_id, err := primitive.ObjectIDFromHex(tokenID)
+	utils.AssertErr(err, "invalid_token", 400)
+	services.GetServiceManger().Token.RemoveAPIToken(id, _id)
 	return iris.StatusOK
 }
 
@@ -129,29 +350,85 @@ type GetWeChatImageRes struct {
 }
 
 // GetWechat 获取微信登陆二维码
+// 二维码内容为签名的一次性 nonce，只能用于登陆当前 PC 端会话
 func (c *SessionController) GetWechat() int {
-	sessionID := primitive.NewObjectID()
-	png, err := qrcode.Encode(sessionID.Hex(), qrcode.Medium, 256)
-	c.Session.Set("qr-code", sessionID.Hex())
-	c.Session.Set("login", "wechat_qr")
+	nonce, code := services.GetServiceManger().Token.AddQRLogin(c.Session.ID(),
+		c.Session.GetString("qr-code"), c.getRequestMeta())
+	png, err := qrcode.Encode(code, qrcode.Medium, 256)
 	utils.AssertErr(err, "", 500)
+	c.Session.Set("qr-code", nonce)
+	c.Session.Set("login", "wechat_qr")
 	_, err = c.Ctx.Write(png)
 	utils.AssertErr(err, "", 500)
 	return iris.StatusOK
 }
 
-type PutWechatReq struct {
-	Session string
+// GetWechatEvents 推送扫码登陆状态(Server-Sent Events)
+// 依次推送 pending、scanned，手机确认后推送 confirmed，拒绝或过期时推送 rejected、expired
+// 推送过程中不修改登陆状态，收到 confirmed 后调用 POST /session/wechat/finish 完成登陆
+func (c *SessionController) GetWechatEvents() {
+	nonce := c.Session.GetString("qr-code")
+	utils.Assert(nonce != "", "invalid_code", 400)
+	flusher, ok := c.Ctx.ResponseWriter().Flusher()
+	utils.Assert(ok, "", 500)
+
+	events, cancel := services.GetServiceManger().Token.WatchQRLogin(nonce, c.getRequestMeta().IP)
+	defer cancel()
+
+	c.Ctx.ContentType("text/event-stream")
+	c.Ctx.Header("Cache-Control", "no-cache")
+	c.Ctx.Header("X-Accel-Buffering", "no")
+	closed := c.Ctx.Request().Context().Done()
+	for {
+		select {
+		case state, ok := <-events:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(c.Ctx, "data: %s\n\n", state); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-closed:
+			return
+		}
+	}
 }
 
-// PutWechat 微信扫码登陆
+// PostWechatFinish 完成扫码登陆，收到 confirmed 事件后调用
+func (c *SessionController) PostWechatFinish() int {
+	nonce := c.Session.GetString("qr-code")
+	utils.Assert(nonce != "", "invalid_code", 400)
+	userID := services.GetServiceManger().Token.FinishQRLogin(nonce, c.Session.ID())
+	utils.Assert(!userID.IsZero(), "invalid_code", 403)
+	c.login(userID, "wechat_pc", "")
+	c.Session.Set("login", "wechat_pc")
+	c.Session.Delete("qr-code")
+	return iris.StatusOK
+}
+
+// WechatQRCodeReq 扫码登陆请求
+type WechatQRCodeReq struct {
+	Code    string // 二维码内容
+	Confirm bool   // 是否确认登陆(仅确认时使用)
+}
+
+// PostWechatScan 手机扫码，返回 PC 端的 IP 和浏览器信息供用户确认
+func (c *SessionController) PostWechatScan() int {
+	userID := c.checkUserLogin()
+	req := WechatQRCodeReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.Assert(err == nil && req.Code != "", "invalid_code", 400)
+	c.JSON(services.GetServiceManger().Token.ScanQRLogin(userID, req.Code))
+	return iris.StatusOK
+}
+
+// PutWechat 手机确认或拒绝扫码登陆，必须先扫码
 func (c *SessionController) PutWechat() int {
-	userID := c.checkLogin()
-	req := PutWechatReq{}
+	userID := c.checkUserLogin()
+	req := WechatQRCodeReq{}
 	err := c.Ctx.ReadJSON(&req)
-	utils.AssertErr(err, "invalid_value", 400)
-	sessionID, err := primitive.ObjectIDFromHex(req.Session)
-	utils.AssertErr(err, "invalid_session", 400)
-	c.Service.LoginByWechatOnPC(userID, sessionID)
+	utils.Assert(err == nil && req.Code != "", "invalid_code", 400)
+	services.GetServiceManger().Token.ConfirmQRLogin(userID, req.Code, req.Confirm)
 	return iris.StatusOK
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/TimeForCoin/Server/app/utils"
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/mvc"
+	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -42,9 +43,16 @@ type AddTaskReq struct {
 	MaxPlayer    int64    `json:"max_player"`
 	AutoAccept   bool     `json:"auto_accept"`
 	Publish      bool     `json:"publish"`
+	PublishAt    int64    `json:"publish_at"` // 定时发布时间，-1 为取消定时
+	TopTime      int64    `json:"top_time"`   // 置顶截止时间(仅修改任务)
+	Identities   []string `json:"identities"` // 限定参与者的认证身份，修改时传空数组取消限制
+	Schools      []string `json:"schools"`    // 限定参与者的认证学校，修改时传空数组取消限制
+	Campuses     []string `json:"campuses"`   // 所属校区，发布时为空则为自己认证的校区
+	CrossCampus  bool     `json:"cross_campus"`
 }
 
-func validTask(req AddTaskReq, new bool) {
+// validTask 检查任务请求，并过滤标题和内容中的敏感词，返回是否需要人工审核
+func validTask(req *AddTaskReq, new bool) (review bool) {
 	if req.Type != "" || new {
 		utils.Assert(models.TaskType(req.Type) == models.TaskTypeInfo ||
 			models.TaskType(req.Type) == models.TaskTypeQuestionnaire ||
@@ -76,6 +84,14 @@ func validTask(req AddTaskReq, new bool) {
 		utils.Assert(len(t) < 32, "tag_too_long", 403)
 	}
 
+	for _, i := range req.Identities {
+		utils.Assert(utils.IsIdentity(i), "invalid_identity", 400)
+	}
+
+	for _, school := range req.Schools {
+		utils.Assert(school != "" && len(school) < 64, "invalid_school", 400)
+	}
+
 	for _, file := range req.Images {
 		_, err := primitive.ObjectIDFromHex(file)
 		utils.AssertErr(err, "invalid_file", 400)
@@ -85,6 +101,37 @@ func validTask(req AddTaskReq, new bool) {
 		_, err := primitive.ObjectIDFromHex(attachment)
 		utils.AssertErr(err, "invalid_file", 400)
 	}
+
+	var titleReview, contentReview bool
+	req.Title, titleReview = services.GetServiceManger().Filter.CheckContent(req.Title)
+	req.Content, contentReview = services.GetServiceManger().Filter.CheckContent(req.Content)
+	return titleReview || contentReview
+}
+
+// makeIdentities 转换认证身份列表，保留 nil 以区分未修改
+func makeIdentities(identities []string) []models.UserIdentity {
+	if identities == nil {
+		return nil
+	}
+	res := []models.UserIdentity{}
+	for _, i := range identities {
+		res = append(res, models.UserIdentity(i))
+	}
+	return res
+}
+
+// makeCampuses 转换校区 ID 列表，保留 nil 以区分未修改
+func makeCampuses(campuses []string) []primitive.ObjectID {
+	if campuses == nil {
+		return nil
+	}
+	res := []primitive.ObjectID{}
+	for _, c := range campuses {
+		id, err := primitive.ObjectIDFromHex(c)
+		utils.AssertErr(err, "invalid_campus", 400)
+		res = append(res, id)
+	}
+	return res
 }
 
 // Post 添加任务
@@ -93,7 +140,7 @@ func (c *TaskController) Post() int {
 	req := AddTaskReq{}
 	err := c.Ctx.ReadJSON(&req)
 	utils.Assert(err == nil, "invalid_value", 400)
-	validTask(req, true)
+	review := validTask(&req, true)
 
 	taskType := models.TaskType(req.Type)
 	taskReward := models.RewardType(req.Reward)
@@ -122,8 +169,16 @@ func (c *TaskController) Post() int {
 		EndDate:      req.EndDate,
 		MaxPlayer:    req.MaxPlayer,
 		AutoAccept:   req.AutoAccept,
+		PublishAt:    req.PublishAt,
+		Identities:   makeIdentities(req.Identities),
+		Schools:      req.Schools,
+		Campuses:     makeCampuses(req.Campuses),
+		CrossCampus:  req.CrossCampus,
 	}
 	taskID := c.Service.AddTask(id, taskInfo, images, attachments, req.Publish)
+	if review {
+		services.GetServiceManger().Filter.AddReview(id, models.ReportTargetTask, taskID, 0, req.Title+"\n"+req.Content)
+	}
 	c.JSON(struct {
 		ID string `json:"id"`
 	}{
@@ -143,7 +198,7 @@ func (c *TaskController) GetBy(id string) int {
 		biref = true
 	}
 
-	task := c.Service.GetTaskByID(_id, c.Session.GetString("id"), biref)
+	task := c.Service.GetTaskByID(_id, c.getUserID(), biref)
 	c.Service.AddView(_id)
 	c.JSON(task)
 	return iris.StatusOK
@@ -158,7 +213,7 @@ func (c *TaskController) PutBy(id string) int {
 	req := AddTaskReq{}
 	err = c.Ctx.ReadJSON(&req)
 	utils.AssertErr(err, "invalid_value", 400)
-	validTask(req, false)
+	review := validTask(&req, false)
 
 	var images []primitive.ObjectID
 	for _, file := range req.Images {
@@ -185,8 +240,17 @@ func (c *TaskController) PutBy(id string) int {
 		EndDate:      req.EndDate,
 		MaxPlayer:    req.MaxPlayer,
 		AutoAccept:   req.AutoAccept,
+		PublishAt:    req.PublishAt,
+		TopTime:      req.TopTime,
+		Identities:   makeIdentities(req.Identities),
+		Schools:      req.Schools,
+		Campuses:     makeCampuses(req.Campuses),
+		CrossCampus:  req.CrossCampus,
 	}
 	c.Service.SetTaskInfo(userID, taskID, taskInfo, images, attachments)
+	if review {
+		services.GetServiceManger().Filter.AddReview(userID, models.ReportTargetTask, taskID, 0, req.Title+"\n"+req.Content)
+	}
 	return iris.StatusOK
 }
 
@@ -218,6 +282,7 @@ func (c *TaskController) Get() int {
 	reward := c.Ctx.URLParamDefault("reward", "all")
 	keyword := c.Ctx.URLParamDefault("keyword", "")
 	user := c.Ctx.URLParamDefault("user", "")
+	campus := c.Ctx.URLParamDefault("campus", "") // 默认为全部可见任务，也可以指定逗号分隔的校区 ID
 	birefParam := c.Ctx.URLParamDefault("biref", "false")
 	biref := false
 	if birefParam == "true" {
@@ -241,7 +306,7 @@ func (c *TaskController) Get() int {
 	}
 
 	taskCount, tasksData := c.Service.GetTasks(page, size, sort,
-		taskType, status, reward, keyword, user, c.Session.GetString("id"), biref)
+		taskType, status, reward, keyword, user, campus, c.getUserID(), biref)
 
 	if tasksData == nil {
 		tasksData = []services.TaskDetail{}
@@ -286,21 +351,45 @@ func (c *TaskController) DeleteByLike(id string) int {
 	return iris.StatusOK
 }
 
-// PostByCollect 添加收藏
+// PostByCollect 添加收藏，可通过 folder 参数指定收藏夹
 func (c *TaskController) PostByCollect(id string) int {
 	userID := c.checkLogin()
 	taskID, err := primitive.ObjectIDFromHex(id)
 	utils.AssertErr(err, "invalid_id", 400)
-	c.Service.ChangeCollection(taskID, userID, true)
+	c.Service.ChangeCollection(taskID, userID, true, makeFolderID(c.Ctx.URLParamDefault("folder", "")))
 	return iris.StatusOK
 }
 
+// PutByCollect 将收藏移动到收藏夹，收藏夹为空时移回默认收藏夹
+func (c *TaskController) PutByCollect(id string) int {
+	userID := c.checkLogin()
+	taskID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	req := struct {
+		Folder string
+	}{}
+	err = c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	c.Service.SetCollectionFolder(taskID, userID, makeFolderID(req.Folder))
+	return iris.StatusOK
+}
+
+// makeFolderID 解析收藏夹 ID，为空时表示默认收藏夹
+func makeFolderID(folder string) primitive.ObjectID {
+	if folder == "" {
+		return primitive.NilObjectID
+	}
+	folderID, err := primitive.ObjectIDFromHex(folder)
+	utils.AssertErr(err, "invalid_folder", 400)
+	return folderID
+}
+
 // DeleteByCollect 删除收藏
 func (c *TaskController) DeleteByCollect(id string) int {
 	userID := c.checkLogin()
 	taskID, err := primitive.ObjectIDFromHex(id)
 	utils.AssertErr(err, "invalid_id", 400)
-	c.Service.ChangeCollection(taskID, userID, false)
+	c.Service.ChangeCollection(taskID, userID, false, primitive.NilObjectID)
 	return iris.StatusOK
 }
 
@@ -335,9 +424,9 @@ func (c *TaskController) PostByPlayer(id string) int {
 type TaskStatusReq struct {
 	Status   string `json:"status"`
 	Note     string `json:"note"`
-	Degree   int    `json:"degree"`
+	Degree   int    `json:"degree"` // 完成度 1-5
 	Remark   string `json:"remark"`
-	Score    int    `json:"score"`
+	Score    int    `json:"score"` // 星级 1-5
 	Feedback string `json:"feedback"`
 }
 
@@ -390,6 +479,8 @@ func (c *TaskController) PutByPlayerBy(id, userIDString string) int {
 			status == models.PlayerFinish || status == models.PlayerGiveUp ||
 			status == models.PlayerFailure, "invalid_status", 403)
 	}
+	utils.Assert(req.Degree >= 0 && req.Degree <= 5, "invalid_degree", 400)
+	utils.Assert(req.Score >= 0 && req.Score <= 5, "invalid_score", 400)
 
 	taskStatusInfo := models.TaskStatusSchema{
 		Status:   status,
@@ -437,6 +528,34 @@ func (c *TaskController) GetByPlayer(id string) int {
 	return iris.StatusOK
 }
 
+// GetByPlayerExport 导出任务参与者表格
+func (c *TaskController) GetByPlayerExport(id string) int {
+	userID := c.checkLogin()
+	taskID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	format := c.Ctx.URLParamDefault("format", "csv")
+	utils.Assert(format == "csv" || format == "xlsx", "invalid_format", 400)
+
+	title, export := c.Service.ExportTaskPlayer(taskID, userID)
+
+	var writer utils.TableWriter
+	c.Ctx.Header("Content-Disposition", "attachment; filename=\"task-"+id+"-players."+format+"\"")
+	if format == "csv" {
+		c.Ctx.ContentType("text/csv; charset=utf-8")
+		writer, err = utils.NewCSVWriter(c.Ctx.ResponseWriter())
+	} else {
+		c.Ctx.ContentType("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		writer, err = utils.NewXLSXWriter(c.Ctx.ResponseWriter(), title)
+	}
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	if err = export(writer); err != nil {
+		// 表格已开始写入，不能再返回错误信息，记录日志后中断
+		log.Error().Err(err).Str("task", id).Msg("Failure to export task players")
+		c.Ctx.StopExecution()
+	}
+	return iris.StatusOK
+}
+
 // GetByWechat 生成活动微信小程序码
 func (c *TaskController) GetByWechat(id string) int {
 	taskID, err := primitive.ObjectIDFromHex(id)
@@ -3,6 +3,7 @@ package controllers
 import (
 	"github.com/TimeForCoin/Server/app/utils"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/TimeForCoin/Server/app/libs"
@@ -45,26 +46,37 @@ type UserListRes struct {
 // Get 搜索用户
 func (c *UserController) Get() int {
 	page, size := c.getPaginationData()
-	key := c.Ctx.URLParamDefault("key", "")
-	utils.Assert(key != "", "invalid_key", 400)
+	search := models.UserSearchFilter{
+		Key:      c.Ctx.URLParamDefault("key", ""),
+		School:   c.Ctx.URLParamDefault("school", ""),
+		Identity: models.UserIdentity(c.Ctx.URLParamDefault("identity", "")),
+		Status:   models.CertificationStatus(c.Ctx.URLParamDefault("status", "")),
+		Gender:   models.UserGender(c.Ctx.URLParamDefault("gender", "")),
+	}
+	var err error
+	search.MinCredit, err = strconv.ParseInt(c.Ctx.URLParamDefault("min_credit", "0"), 10, 64)
+	utils.AssertErr(err, "invalid_min_credit", 400)
+	search.MinLevel, err = strconv.ParseInt(c.Ctx.URLParamDefault("min_level", "0"), 10, 64)
+	utils.AssertErr(err, "invalid_min_level", 400)
+	utils.Assert(search.Key != "" || search.School != "" || search.Identity != "" || search.Status != "" ||
+		search.Gender != "" || search.MinCredit > 0 || search.MinLevel > 0, "invalid_key", 400)
+	sort := models.UserSearchSort(c.Ctx.URLParamDefault("sort", string(models.UserSortRelevance)))
 
-	res := c.Service.SearchUser(key, page, size)
+	viewerID, _ := primitive.ObjectIDFromHex(c.getUserID())
+	count, res := c.Service.SearchUser(search, sort, viewerID, page, size)
 
-	// noinspection GoPreferNilSlice
 	for i := range res {
-		sessionUserID := c.Session.GetString("id")
-		if sessionUserID != "" {
-			sessionUser, err := primitive.ObjectIDFromHex(sessionUserID)
-			utils.AssertErr(err, "", 500)
-			res[i].Data.Follower = c.Service.IsFollower(sessionUser, res[i].UserID)
-			res[i].Data.Following = c.Service.IsFollowing(sessionUser, res[i].UserID)
+		if !viewerID.IsZero() {
+			res[i].Data.Follower = c.Service.IsFollower(viewerID, res[i].UserID)
+			res[i].Data.Following = c.Service.IsFollowing(viewerID, res[i].UserID)
 		}
 	}
 
 	c.JSON(UserListRes{
 		Pagination: PaginationRes{
-			Page: page,
-			Size: size,
+			Page:  page,
+			Size:  size,
+			Total: count,
 		},
 		Data: res,
 	})
@@ -81,8 +93,9 @@ func (c *UserController) GetInfoBy(userID string) int {
 		id, err = primitive.ObjectIDFromHex(userID)
 		utils.AssertErr(err, "invalid_session", 401)
 	}
-	res := c.Service.GetUser(id, userID == "me")
-	sessionUserID := c.Session.GetString("id")
+	viewerID, _ := primitive.ObjectIDFromHex(c.getUserID())
+	res := c.Service.GetUser(id, viewerID)
+	sessionUserID := c.getUserID()
 	if userID != "me" && sessionUserID != "" {
 		sessionUser, err := primitive.ObjectIDFromHex(sessionUserID)
 		utils.AssertErr(err, "", 500)
@@ -94,10 +107,53 @@ func (c *UserController) GetInfoBy(userID string) int {
 	return iris.StatusOK
 }
 
+// GetMeExport 导出个人数据
+func (c *UserController) GetMeExport() int {
+	id := c.checkLogin()
+	export := c.Service.ExportUserData(id)
+	c.Ctx.ContentType("application/zip")
+	c.Ctx.Header("Content-Disposition", "attachment; filename=\"user-"+id.Hex()+"-data.zip\"")
+	export(c.Ctx.ResponseWriter())
+	return iris.StatusOK
+}
+
+// DeleteMe 注销账号
+func (c *UserController) DeleteMe() int {
+	id := c.checkUserLogin()
+	c.Service.DeleteUser(id)
+	c.Session.Set("login", "none")
+	c.Session.Delete("id")
+	c.Session.Delete("sid")
+	return iris.StatusOK
+}
+
 // PostAttend 用户签到
 func (c *UserController) PostAttend() int {
 	id := c.checkLogin()
-	c.Service.UserAttend(id)
+	c.JSON(c.Service.UserAttend(id))
+	return iris.StatusOK
+}
+
+// GetAttend 获取签到日历
+func (c *UserController) GetAttend() int {
+	id := c.checkLogin()
+	month := c.Ctx.URLParamDefault("month", "")
+	c.JSON(c.Service.GetAttendanceCalendar(id, month))
+	return iris.StatusOK
+}
+
+// MakeUpAttendReq 补签请求
+type MakeUpAttendReq struct {
+	Date string `json:"date"`
+}
+
+// PostAttendMakeup 补签
+func (c *UserController) PostAttendMakeup() int {
+	id := c.checkLogin()
+	req := MakeUpAttendReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.Assert(err == nil && req.Date != "", "invalid_value", 400)
+	c.JSON(c.Service.MakeUpAttend(id, req.Date))
 	return iris.StatusOK
 }
 
@@ -114,7 +170,7 @@ type PutUserInfoReq struct {
 
 // PutInfo 修改用户信息
 func (c *UserController) PutInfo() int {
-	id, err := primitive.ObjectIDFromHex(c.Session.GetString("id"))
+	id, err := primitive.ObjectIDFromHex(c.getUserID())
 	utils.Assert(err == nil, "invalid_session", 401)
 	// 解析
 	req := PutUserInfoReq{}
@@ -166,6 +222,24 @@ func (c *UserController) PutInfo() int {
 	return iris.StatusOK
 }
 
+// GetPrivacy 获取个人信息隐私设置
+func (c *UserController) GetPrivacy() int {
+	id := c.checkLogin()
+	c.JSON(c.Service.GetPrivacy(id))
+	return iris.StatusOK
+}
+
+// PutPrivacy 修改个人信息隐私设置
+// 可见范围: public 所有人, followers 关注者, partners 任务伙伴(联系方式仅任务进行中可见), private 仅自己
+func (c *UserController) PutPrivacy() int {
+	id := c.checkLogin()
+	req := models.UserPrivacySchema{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	c.Service.SetPrivacy(id, req)
+	return iris.StatusOK
+}
+
 // PutUserTypeReq 修改用户类型请求
 type PutUserTypeReq struct {
 	Type string `json:"type"`
@@ -190,11 +264,11 @@ func (c *UserController) PutTypeByID(userID string) int {
 	utils.Assert(err == nil, "invalid_value", 400)
 	utils.Assert(utils.IsUserType(req.Type), "invalid_type", 400)
 	utils.Assert(req.Type != string(models.UserTypeRoot), "not_allow_type", 403)
-	c.Service.SetUserType(id, opID, models.UserType(req.Type))
+	c.Service.SetUserType(id, opID, models.UserType(req.Type), c.getRequestMeta())
 	return iris.StatusOK
 }
 
-// GetCollectBy 获取用户收藏
+// GetCollectBy 获取用户收藏（按收藏时间倒序）
 func (c *UserController) GetCollectBy(userIDString string) int {
 	page, size := c.getPaginationData()
 	utils.Assert(userIDString != "", "string")
@@ -207,12 +281,12 @@ func (c *UserController) GetCollectBy(userIDString string) int {
 		utils.AssertErr(err, "invalid_user", 403)
 	}
 
-	sort := c.Ctx.URLParamDefault("sort", "new")
+	folder := c.Ctx.URLParamDefault("folder", "all")
 	taskType := c.Ctx.URLParamDefault("type", "all")
 	status := c.Ctx.URLParamDefault("status", "wait")
 	reward := c.Ctx.URLParamDefault("reward", "all")
 
-	taskCount, tasksData := c.Service.GetUserCollections(userID, page, size, sort,
+	taskCount, tasksData := c.Service.GetUserCollections(userID, page, size, folder,
 		taskType, status, reward)
 
 	res := TasksListRes{
@@ -227,6 +301,56 @@ func (c *UserController) GetCollectBy(userIDString string) int {
 	return iris.StatusOK
 }
 
+// GetFolder 获取自己的收藏夹
+func (c *UserController) GetFolder() int {
+	id := c.checkLogin()
+	c.JSON(c.Service.GetCollectFolders(id))
+	return iris.StatusOK
+}
+
+// FolderReq 添加/修改收藏夹请求
+type FolderReq struct {
+	Name string
+}
+
+// PostFolder 添加收藏夹
+func (c *UserController) PostFolder() int {
+	id := c.checkLogin()
+	req := FolderReq{}
+	err := c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	utils.Assert(req.Name != "" && len(req.Name) < 64, "invalid_name", 400)
+	folderID := c.Service.AddCollectFolder(id, req.Name)
+	c.JSON(struct {
+		ID string `json:"id"`
+	}{
+		ID: folderID.Hex(),
+	})
+	return iris.StatusOK
+}
+
+// PutFolderBy 修改收藏夹名称
+func (c *UserController) PutFolderBy(folder string) int {
+	id := c.checkLogin()
+	folderID, err := primitive.ObjectIDFromHex(folder)
+	utils.AssertErr(err, "invalid_id", 400)
+	req := FolderReq{}
+	err = c.Ctx.ReadJSON(&req)
+	utils.AssertErr(err, "invalid_value", 400)
+	utils.Assert(req.Name != "" && len(req.Name) < 64, "invalid_name", 400)
+	c.Service.SetCollectFolder(id, folderID, req.Name)
+	return iris.StatusOK
+}
+
+// DeleteFolderBy 删除收藏夹，其中的收藏移回默认收藏夹
+func (c *UserController) DeleteFolderBy(folder string) int {
+	id := c.checkLogin()
+	folderID, err := primitive.ObjectIDFromHex(folder)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.RemoveCollectFolder(id, folderID)
+	return iris.StatusOK
+}
+
 // TasksStatusListRes 用户参与任务数据
 type TasksStatusListRes struct {
 	Pagination PaginationRes
@@ -290,12 +414,18 @@ func (c *UserController) DeleteHistory() int {
 	return iris.StatusOK
 }
 
-// FollowListRes 关注/粉丝列表数据
+// FollowListRes 用户列表数据
 type FollowListRes struct {
 	Pagination PaginationRes
 	Data       []models.UserBaseInfo
 }
 
+// FollowUserListRes 关注/粉丝列表数据
+type FollowUserListRes struct {
+	Pagination PaginationRes
+	Data       []services.FollowUser
+}
+
 // GetFollowerBy 获取用户粉丝列表
 func (c *UserController) GetFollowerBy(id string) int {
 	var userID primitive.ObjectID
@@ -309,11 +439,8 @@ func (c *UserController) GetFollowerBy(id string) int {
 	page, size := c.getPaginationData()
 
 	followers, total := c.Service.GetFollower(userID, page, size)
-	if followers == nil {
-		followers = []models.UserBaseInfo{}
-	}
 
-	c.JSON(FollowListRes{
+	c.JSON(FollowUserListRes{
 		Pagination: PaginationRes{
 			Page:  page,
 			Size:  size,
@@ -337,11 +464,8 @@ func (c *UserController) GetFollowingBy(id string) int {
 	page, size := c.getPaginationData()
 
 	followings, total := c.Service.GetFollowing(userID, page, size)
-	if followings == nil {
-		followings = []models.UserBaseInfo{}
-	}
 
-	c.JSON(FollowListRes{
+	c.JSON(FollowUserListRes{
 		Pagination: PaginationRes{
 			Page:  page,
 			Size:  size,
@@ -353,6 +477,39 @@ func (c *UserController) GetFollowingBy(id string) int {
 	return iris.StatusOK
 }
 
+// GetMutualBy 获取与用户互相关注的用户列表
+func (c *UserController) GetMutualBy(id string) int {
+	var userID primitive.ObjectID
+	if id == "me" {
+		userID = c.checkLogin()
+	} else {
+		var err error
+		userID, err = primitive.ObjectIDFromHex(id)
+		utils.AssertErr(err, "invalid_id", 400)
+	}
+	page, size := c.getPaginationData()
+
+	users, total := c.Service.GetMutualFollow(userID, page, size)
+
+	c.JSON(FollowUserListRes{
+		Pagination: PaginationRes{
+			Page:  page,
+			Size:  size,
+			Total: total,
+		},
+		Data: users,
+	})
+	return iris.StatusOK
+}
+
+// GetSuggestion 获取推荐关注的用户
+func (c *UserController) GetSuggestion() int {
+	userID := c.checkLogin()
+	_, size := c.getPaginationData()
+	c.JSON(c.Service.GetFollowSuggestions(userID, size))
+	return iris.StatusOK
+}
+
 // PostFollowingBy 添加关注
 func (c *UserController) PostFollowingBy(id string) int {
 	userID := c.checkLogin()
@@ -370,3 +527,42 @@ func (c *UserController) DeleteFollowingBy(id string) int {
 	c.Service.UnFollowUser(userID, followingID)
 	return iris.StatusOK
 }
+
+// GetBlock 获取屏蔽列表
+func (c *UserController) GetBlock() int {
+	userID := c.checkLogin()
+	page, size := c.getPaginationData()
+
+	users, total := c.Service.GetBlockList(userID, page, size)
+	if users == nil {
+		users = []models.UserBaseInfo{}
+	}
+
+	c.JSON(FollowListRes{
+		Pagination: PaginationRes{
+			Page:  page,
+			Size:  size,
+			Total: total,
+		},
+		Data: users,
+	})
+	return iris.StatusOK
+}
+
+// PostBlockBy 屏蔽用户
+func (c *UserController) PostBlockBy(id string) int {
+	userID := c.checkLogin()
+	blockID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.BlockUser(userID, blockID)
+	return iris.StatusOK
+}
+
+// DeleteBlockBy 取消屏蔽
+func (c *UserController) DeleteBlockBy(id string) int {
+	userID := c.checkLogin()
+	blockID, err := primitive.ObjectIDFromHex(id)
+	utils.AssertErr(err, "invalid_id", 400)
+	c.Service.UnBlockUser(userID, blockID)
+	return iris.StatusOK
+}
@@ -1,6 +1,8 @@
 package libs
 
 import (
+	"time"
+
 	"github.com/TimeForCoin/Server/app/utils"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -36,6 +38,18 @@ func (s *EmailService) SendAuthEmail(id primitive.ObjectID, to, code string) err
 	return s.Dialer.DialAndSend(m)
 }
 
+// SendCertificationRemindEmail 发送认证即将过期提醒邮件
+func (s *EmailService) SendCertificationRemindEmail(to, identity string, expireTime int64) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "[闲得一币] 身份认证即将过期")
+	m.SetBody("text/html",
+		"<p>你的「"+identity+"」身份认证将于 "+time.Unix(expireTime, 0).Format("2006-01-02")+
+			" 过期</p><p>过期后部分仅限认证用户参与的任务将无法领取，请及时登录闲得一币重新认证</p>")
+	return s.Dialer.DialAndSend(m)
+}
+
 // GetEmail 获取全局邮件服务实例
 func GetEmail() *EmailService {
 	if emailService == nil {
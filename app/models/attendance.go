@@ -0,0 +1,170 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AttendanceDateLayout 签到日期格式
+const AttendanceDateLayout = "2006-01-02"
+
+// AttendanceModel 签到记录数据库
+type AttendanceModel struct {
+	Collection *mongo.Collection
+}
+
+// AttendanceSchema 签到记录数据结构
+type AttendanceSchema struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	User   primitive.ObjectID `bson:"user" json:"-"` // 签到用户 [索引]
+	Date   string             `bson:"date"`          // 签到日期 (2006-01-02)
+	Time   int64              `bson:"time"`          // 实际签到时间
+	Reward int64              `bson:"reward"`        // 获得积分
+	MakeUp bool               `bson:"make_up"`       // 是否为补签
+	Cost   int64              `bson:"cost"`          // 补签花费的金币
+}
+
+// AddAttendance 添加签到记录，当天已签到时返回 false
+func (m *AttendanceModel) AddAttendance(attendance AttendanceSchema) (added bool, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	attendance.ID = primitive.NewObjectID()
+	attendance.Time = time.Now().Unix()
+	res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"user": attendance.User, "date": attendance.Date},
+		bson.M{"$setOnInsert": attendance},
+		options.Update().SetUpsert(true))
+	if isDuplicateKey(err) { // 并发签到时由唯一索引保证只添加一次
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+// GetAttendances 获取用户某段日期内的签到记录（按日期先后排列）
+func (m *AttendanceModel) GetAttendances(userID primitive.ObjectID, start, end string) (res []AttendanceSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{
+		"user": userID,
+		"date": bson.M{"$gte": start, "$lte": end},
+	}, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	res = []AttendanceSchema{}
+	for cursor.Next(ctx) {
+		attendance := AttendanceSchema{}
+		if err = cursor.Decode(&attendance); err != nil {
+			return
+		}
+		res = append(res, attendance)
+	}
+	return
+}
+
+// GetAttendanceStreak 获取截至某天(含)的连续签到天数
+func (m *AttendanceModel) GetAttendanceStreak(userID primitive.ObjectID, end string) (streak int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	day, err := time.ParseInLocation(AttendanceDateLayout, end, time.Local)
+	if err != nil {
+		return
+	}
+	cursor, err := m.Collection.Find(ctx, bson.M{
+		"user": userID,
+		"date": bson.M{"$lte": end},
+	}, options.Find().SetSort(bson.M{"date": -1}).SetProjection(bson.M{"date": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		attendance := AttendanceSchema{}
+		if err = cursor.Decode(&attendance); err != nil {
+			return
+		}
+		if attendance.Date != day.Format(AttendanceDateLayout) {
+			break
+		}
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	err = cursor.Err()
+	return
+}
+
+// CountMakeUp 统计用户某段时间内进行的补签次数
+func (m *AttendanceModel) CountMakeUp(userID primitive.ObjectID, startTime, endTime int64) (int64, error) {
+	ctx, over := GetCtx()
+	defer over()
+	return m.Collection.CountDocuments(ctx, bson.M{
+		"user":    userID,
+		"make_up": true,
+		"time":    bson.M{"$gte": startTime, "$lt": endTime},
+	})
+}
+
+// DedupeAttendances 删除同一天重复的签到记录，只保留最早的一条
+func (m *AttendanceModel) DedupeAttendances(ctx context.Context) error {
+	cursor, err := m.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$sort": bson.M{"time": 1}},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"user": "$user", "date": "$date"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		group := struct {
+			IDs []primitive.ObjectID `bson:"ids"`
+		}{}
+		if err = cursor.Decode(&group); err != nil {
+			return err
+		}
+		if _, err = m.Collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// MergeAttendances 将用户 from 的签到记录合并到用户 to，同一天已有签到时保留 to 的记录
+func (m *AttendanceModel) MergeAttendances(from, to primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"user": from})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		attendance := AttendanceSchema{}
+		if err = cursor.Decode(&attendance); err != nil {
+			return err
+		}
+		attendance.ID = primitive.NewObjectID()
+		attendance.User = to
+		if _, err = m.Collection.UpdateOne(ctx, bson.M{"user": to, "date": attendance.Date},
+			bson.M{"$setOnInsert": attendance}, options.Update().SetUpsert(true)); err != nil && !isDuplicateKey(err) {
+			return err
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	_, err = m.Collection.DeleteMany(ctx, bson.M{"user": from})
+	return err
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAttendanceModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testAttendance", testAttendance)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.Attendance.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testAttendance(t *testing.T) {
+	userID := primitive.NewObjectID()
+	now := time.Now()
+	today := now.Format(AttendanceDateLayout)
+	yesterday := now.AddDate(0, 0, -1).Format(AttendanceDateLayout)
+	before := now.AddDate(0, 0, -2).Format(AttendanceDateLayout)
+
+	for _, date := range []string{today, before} {
+		added, err := model.Attendance.AddAttendance(AttendanceSchema{User: userID, Date: date, Reward: 5})
+		if err != nil || !added {
+			t.Error(added, err)
+		}
+	}
+	added, err := model.Attendance.AddAttendance(AttendanceSchema{User: userID, Date: today})
+	if err != nil || added {
+		t.Error("attend twice", err)
+	}
+
+	streak, err := model.Attendance.GetAttendanceStreak(userID, today)
+	if err != nil || streak != 1 {
+		t.Error(streak, err)
+	}
+
+	// 补签连接前后的签到
+	added, err = model.Attendance.AddAttendance(AttendanceSchema{User: userID, Date: yesterday, MakeUp: true, Cost: 10})
+	if err != nil || !added {
+		t.Error(added, err)
+	}
+	streak, err = model.Attendance.GetAttendanceStreak(userID, today)
+	if err != nil || streak != 3 {
+		t.Error(streak, err)
+	}
+	count, err := model.Attendance.CountMakeUp(userID, now.Add(-time.Hour).Unix(), now.Add(time.Hour).Unix())
+	if err != nil || count != 1 {
+		t.Error(count, err)
+	}
+
+	res, err := model.Attendance.GetAttendances(userID, before, today)
+	if err != nil || len(res) != 3 || res[0].Date != before {
+		t.Error(res, err)
+	}
+	t.Log(res)
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditModel 管理操作审计数据库
+// 审计记录只允许追加，不提供修改与删除
+type AuditModel struct {
+	Collection *mongo.Collection
+}
+
+// AuditAction 管理操作类型
+type AuditAction string
+
+// AuditAction 管理操作类型
+const (
+	AuditSetUserType             AuditAction = "set_user_type"             // 修改用户类型
+	AuditUpdateCertification     AuditAction = "update_certification"      // 审核认证
+	AuditAddAutoCertification    AuditAction = "add_auto_certification"    // 添加自动认证后缀
+	AuditRemoveAutoCertification AuditAction = "remove_auto_certification" // 移除自动认证后缀
+	AuditSendSystemMessage       AuditAction = "send_system_message"       // 发送系统消息
+	AuditRemoveComment           AuditAction = "remove_comment"            // 删除他人评论
+	AuditRemoveUselessFiles      AuditAction = "remove_useless_files"      // 清理全部无用文件
+	AuditAddArticle              AuditAction = "add_article"               // 发布公告
+	AuditSetArticle              AuditAction = "set_article"               // 修改公告
+	AuditBanUser                 AuditAction = "ban_user"                  // 封禁用户
+	AuditUnbanUser               AuditAction = "unban_user"                // 解除封禁
+	AuditHandleReport            AuditAction = "handle_report"             // 处理举报
+	AuditSetFilterWord           AuditAction = "set_filter_word"           // 添加/修改敏感词
+	AuditRemoveFilterWord        AuditAction = "remove_filter_word"        // 移除敏感词
+	AuditSetRole                 AuditAction = "set_role"                  // 添加/修改角色
+	AuditRemoveRole              AuditAction = "remove_role"               // 删除角色
+	AuditGrantRole               AuditAction = "grant_role"                // 授予角色
+	AuditRevokeRole              AuditAction = "revoke_role"               // 撤销角色
+	AuditAddInvite               AuditAction = "add_invite"                // 发放认证邀请码
+	AuditRemoveInvite            AuditAction = "remove_invite"             // 删除认证邀请码
+	AuditSetCampus               AuditAction = "set_campus"                // 添加/修改校区
+	AuditRemoveCampus            AuditAction = "remove_campus"             // 删除校区
+	AuditSetCampusAdmin          AuditAction = "set_campus_admin"          // 添加/移除校区管理员
+	AuditSetTaskHidden           AuditAction = "set_task_hidden"           // 屏蔽/恢复校区任务
+)
+
+// AuditTarget 操作对象类型
+type AuditTarget string
+
+// AuditTarget 操作对象类型
+const (
+	AuditTargetUser    AuditTarget = "user"
+	AuditTargetEmail   AuditTarget = "email"
+	AuditTargetMessage AuditTarget = "message"
+	AuditTargetComment AuditTarget = "comment"
+	AuditTargetFile    AuditTarget = "file"
+	AuditTargetArticle AuditTarget = "article"
+	AuditTargetReport  AuditTarget = "report"
+	AuditTargetWord    AuditTarget = "word"
+	AuditTargetRole    AuditTarget = "role"
+	AuditTargetInvite  AuditTarget = "invite"
+	AuditTargetCampus  AuditTarget = "campus"
+	AuditTargetTask    AuditTarget = "task"
+)
+
+// RequestMeta 请求信息
+type RequestMeta struct {
+	IP        string `bson:"ip"`
+	UserAgent string `bson:"user_agent"`
+	Method    string `bson:"method"`
+	Path      string `bson:"path"`
+}
+
+// AuditSchema 管理操作审计记录
+type AuditSchema struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Actor      primitive.ObjectID `bson:"actor" json:"-"`   // 操作者 [索引]
+	Action     AuditAction        `bson:"action"`           // 操作类型
+	TargetType AuditTarget        `bson:"target_type"`      // 操作对象类型
+	Target     string             `bson:"target"`           // 操作对象 ID 或键名 [索引]
+	Before     bson.M             `bson:"before,omitempty"` // 操作前状态
+	After      bson.M             `bson:"after,omitempty"`  // 操作后状态
+	Meta       RequestMeta        `bson:"meta"`             // 请求信息
+	Time       int64              `bson:"time"`             // 操作时间 [索引]
+}
+
+// AuditFilter 审计记录查询条件，零值表示不限制
+type AuditFilter struct {
+	Actor      primitive.ObjectID
+	Actions    []AuditAction
+	TargetType AuditTarget
+	Target     string
+	StartTime  int64
+	EndTime    int64
+}
+
+// AddAudit 追加审计记录
+func (m *AuditModel) AddAudit(audit AuditSchema) error {
+	ctx, over := GetCtx()
+	defer over()
+	audit.ID = primitive.NewObjectID()
+	audit.Time = time.Now().Unix()
+	_, err := m.Collection.InsertOne(ctx, audit)
+	return err
+}
+
+// GetAudits 分页查询审计记录（按时间倒序）
+func (m *AuditModel) GetAudits(filter AuditFilter, skip, limit int64) (res []AuditSchema, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	query := bson.M{}
+	if !filter.Actor.IsZero() {
+		query["actor"] = filter.Actor
+	}
+	if len(filter.Actions) > 0 {
+		query["action"] = bson.M{"$in": filter.Actions}
+	}
+	if filter.TargetType != "" {
+		query["target_type"] = filter.TargetType
+	}
+	if filter.Target != "" {
+		query["target"] = filter.Target
+	}
+	if filter.StartTime != 0 || filter.EndTime != 0 {
+		timeRange := bson.M{}
+		if filter.StartTime != 0 {
+			timeRange["$gte"] = filter.StartTime
+		}
+		if filter.EndTime != 0 {
+			timeRange["$lte"] = filter.EndTime
+		}
+		query["time"] = timeRange
+	}
+
+	count, err = m.Collection.CountDocuments(ctx, query)
+	if err != nil {
+		return
+	}
+	cursor, err := m.Collection.Find(ctx, query,
+		options.Find().SetSort(bson.M{"time": -1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	res = []AuditSchema{}
+	for cursor.Next(ctx) {
+		audit := AuditSchema{}
+		if err = cursor.Decode(&audit); err != nil {
+			return
+		}
+		res = append(res, audit)
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testAudit", testAudit)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.Audit.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testAudit(t *testing.T) {
+	adminID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	meta := RequestMeta{IP: "127.0.0.1", UserAgent: "test", Method: "PUT", Path: "/users/type"}
+
+	err := model.Audit.AddAudit(AuditSchema{
+		Actor:      adminID,
+		Action:     AuditSetUserType,
+		TargetType: AuditTargetUser,
+		Target:     userID.Hex(),
+		Before:     bson.M{"type": UserTypeNormal},
+		After:      bson.M{"type": UserTypeAdmin},
+		Meta:       meta,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	err = model.Audit.AddAudit(AuditSchema{
+		Actor:      primitive.NewObjectID(),
+		Action:     AuditAddAutoCertification,
+		TargetType: AuditTargetEmail,
+		Target:     "example.edu.cn",
+		After:      bson.M{"data": "学校"},
+		Meta:       meta,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	audits, count, err := model.Audit.GetAudits(AuditFilter{Actor: adminID}, 0, 10)
+	if err != nil || count != 1 || len(audits) != 1 || audits[0].After["type"] != string(UserTypeAdmin) {
+		t.Error(audits, count, err)
+	}
+	audits, count, err = model.Audit.GetAudits(AuditFilter{
+		Actions: []AuditAction{AuditSetUserType, AuditAddAutoCertification},
+	}, 0, 10)
+	if err != nil || count != 2 || len(audits) != 2 {
+		t.Error(audits, count, err)
+	}
+	audits, count, err = model.Audit.GetAudits(AuditFilter{
+		TargetType: AuditTargetEmail,
+		EndTime:    time.Now().Add(-time.Hour).Unix(),
+	}, 0, 10)
+	if err != nil || count != 0 {
+		t.Error(audits, count, err)
+	}
+	t.Log(audits)
+}
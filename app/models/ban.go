@@ -0,0 +1,171 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BanModel 封禁记录数据库
+type BanModel struct {
+	Collection *mongo.Collection
+}
+
+// BanStatus 封禁状态
+type BanStatus string
+
+// BanStatus 封禁状态
+const (
+	BanStatusActive  BanStatus = "active"  // 封禁中
+	BanStatusExpired BanStatus = "expired" // 已到期
+	BanStatusLifted  BanStatus = "lifted"  // 已被管理员解除
+)
+
+// BanSchema 封禁记录数据结构
+type BanSchema struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`       // 封禁记录 ID
+	User      primitive.ObjectID `bson:"user" json:"-"`                 // 被封禁用户 [索引]
+	Admin     primitive.ObjectID `bson:"admin,omitempty" json:"-"`      // 操作管理员 (系统自动封禁为空)
+	Reason    string             `bson:"reason"`                        // 封禁原因
+	StartTime int64              `bson:"start_time"`                    // 开始时间
+	EndTime   int64              `bson:"end_time"`                      // 结束时间，0 为永久封禁
+	Status    BanStatus          `bson:"status"`                        // 封禁状态
+	PrevType  UserType           `bson:"prev_type,omitempty" json:"-"`  // 封禁前的用户类型，解封时恢复
+	LiftAdmin primitive.ObjectID `bson:"lift_admin,omitempty" json:"-"` // 解除封禁的管理员
+	LiftTime  int64              `bson:"lift_time,omitempty"`           // 解除/到期时间
+}
+
+// AddBan 添加封禁记录
+func (m *BanModel) AddBan(ban BanSchema) (primitive.ObjectID, error) {
+	ctx, over := GetCtx()
+	defer over()
+	ban.ID = primitive.NewObjectID()
+	ban.Status = BanStatusActive
+	if ban.StartTime == 0 {
+		ban.StartTime = time.Now().Unix()
+	}
+	_, err := m.Collection.InsertOne(ctx, ban)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return ban.ID, nil
+}
+
+// GetActiveBan 获取用户当前生效的封禁记录（结束时间最晚的一条）
+func (m *BanModel) GetActiveBan(userID primitive.ObjectID) (ban BanSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{
+		"user":   userID,
+		"status": BanStatusActive,
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	found := false
+	for cursor.Next(ctx) {
+		res := BanSchema{}
+		if err = cursor.Decode(&res); err != nil {
+			return
+		}
+		if !found || res.EndTime == 0 || (ban.EndTime != 0 && res.EndTime > ban.EndTime) {
+			ban = res
+			found = true
+		}
+	}
+	if !found {
+		err = ErrNotExist
+	}
+	return
+}
+
+// GetBansByUser 分页获取用户的封禁记录（按时间倒序）
+func (m *BanModel) GetBansByUser(userID primitive.ObjectID, skip, limit int64) (bans []BanSchema, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	filter := bson.M{"user": userID}
+	count, err = m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return
+	}
+	cursor, err := m.Collection.Find(ctx, filter,
+		options.Find().SetSort(bson.M{"start_time": -1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	bans = []BanSchema{}
+	for cursor.Next(ctx) {
+		ban := BanSchema{}
+		if err = cursor.Decode(&ban); err != nil {
+			return
+		}
+		bans = append(bans, ban)
+	}
+	return
+}
+
+// LiftBans 解除用户所有生效中的封禁
+func (m *BanModel) LiftBans(userID, admin primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateMany(ctx, bson.M{
+		"user":   userID,
+		"status": BanStatusActive,
+	}, bson.M{"$set": bson.M{
+		"status":     BanStatusLifted,
+		"lift_admin": admin,
+		"lift_time":  time.Now().Unix(),
+	}})
+	if err != nil {
+		return err
+	} else if res.ModifiedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// GetExpiredBans 获取已到结束时间但仍为生效状态的封禁记录
+func (m *BanModel) GetExpiredBans(now int64) (bans []BanSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{
+		"status":   BanStatusActive,
+		"end_time": bson.M{"$gt": 0, "$lte": now},
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		ban := BanSchema{}
+		if err = cursor.Decode(&ban); err != nil {
+			return
+		}
+		bans = append(bans, ban)
+	}
+	return
+}
+
+// ExpireBan 将封禁记录标记为已到期
+func (m *BanModel) ExpireBan(id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": BanStatusActive,
+	}, bson.M{"$set": bson.M{
+		"status":    BanStatusExpired,
+		"lift_time": time.Now().Unix(),
+	}})
+	if err != nil {
+		return err
+	} else if res.ModifiedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBanModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testBan", testBan)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.Ban.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testBan(t *testing.T) {
+	userID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+	now := time.Now().Unix()
+
+	if _, err := model.Ban.GetActiveBan(userID); err != ErrNotExist {
+		t.Error(err)
+	}
+
+	expiredID, err := model.Ban.AddBan(BanSchema{User: userID, Admin: adminID, Reason: "spam", EndTime: now - 10})
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = model.Ban.AddBan(BanSchema{User: userID, Reason: "abuse", EndTime: now + 3600, PrevType: UserTypeAdmin})
+	if err != nil {
+		t.Error(err)
+	}
+
+	ban, err := model.Ban.GetActiveBan(userID)
+	if err != nil || ban.Reason != "abuse" || ban.PrevType != UserTypeAdmin {
+		t.Error(ban, err)
+	}
+
+	bans, err := model.Ban.GetExpiredBans(now)
+	if err != nil || len(bans) != 1 || bans[0].ID != expiredID {
+		t.Error(bans, err)
+	}
+	if err = model.Ban.ExpireBan(expiredID); err != nil {
+		t.Error(err)
+	}
+	if err = model.Ban.ExpireBan(expiredID); err != ErrNotExist {
+		t.Error("expire twice", err)
+	}
+
+	if err = model.Ban.LiftBans(userID, adminID); err != nil {
+		t.Error(err)
+	}
+	if _, err = model.Ban.GetActiveBan(userID); err != ErrNotExist {
+		t.Error(err)
+	}
+
+	bans, count, err := model.Ban.GetBansByUser(userID, 0, 10)
+	if err != nil || count != 2 || len(bans) != 2 {
+		t.Error(bans, count, err)
+	}
+	t.Log(bans)
+}
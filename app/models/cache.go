@@ -12,9 +12,8 @@ import (
 // 快速缓存
 
 // - 用户基本信息(头像、昵称)
-// - 用户点赞
-// - 用户收藏
-// - 用户关系
+// - 用户屏蔽关系
+// - 用户权限
 
 // CacheModel 缓存数据库
 type CacheModel struct {
@@ -26,12 +25,9 @@ type DataKind string
 
 // DataKind 缓存数据类型
 const (
-	KindOfLikeTask    DataKind = "like-task-"
-	KindOfLikeComment DataKind = "like-comment-"
-	KindOfCollectTask DataKind = "collect-task-"
-	KindOfBaseInfo    DataKind = "info-"
-	KindOfFollower    DataKind = "follower-"
-	KindOfFollowing   DataKind = "following-"
+	KindOfBaseInfo   DataKind = "info-"
+	KindOfBlock      DataKind = "block-"
+	KindOfPermission DataKind = "permission-"
 )
 
 // WillUpdate 更新缓存数据
@@ -39,9 +35,9 @@ func (c *CacheModel) WillUpdate(userID primitive.ObjectID, kind DataKind) error
 	return c.Redis.Del(string(kind) + userID.Hex()).Err()
 }
 
-// IsLikeTask 用户是否点赞任务
-func (c *CacheModel) IsLikeTask(userID, taskID primitive.ObjectID) bool {
-	setName := string(KindOfLikeTask) + userID.Hex()
+// IsBlockUser 用户是否已屏蔽某人
+func (c *CacheModel) IsBlockUser(userID, otherID primitive.ObjectID) bool {
+	setName := string(KindOfBlock) + userID.Hex()
 	exist, err := c.Redis.Exists(setName).Result()
 	if err != nil {
 		return false
@@ -49,114 +45,10 @@ func (c *CacheModel) IsLikeTask(userID, taskID primitive.ObjectID) bool {
 	// 不存在记录
 	if exist == 0 {
 		// 从数据库读取
-		set := GetModel().Set.GetSets(userID, SetOfLikeTask)
-		if len(set.LikeTaskID) > 0 {
+		set := GetModel().Set.GetSets(userID, SetOfBlockUser)
+		if len(set.BlockUserID) > 0 {
 			var setID []string
-			for _, id := range set.LikeTaskID {
-				setID = append(setID, id.Hex())
-			}
-			err = c.Redis.SAdd(setName, setID).Err()
-			if err != nil {
-				return false
-			}
-		}
-	}
-	val, err := c.Redis.SIsMember(setName, taskID.Hex()).Result()
-	return val
-}
-
-// IsLikeComment 用户是否点赞评论
-func (c *CacheModel) IsLikeComment(userID, commentID primitive.ObjectID) bool {
-	setName := string(KindOfLikeComment) + userID.Hex()
-	exist, err := c.Redis.Exists(setName).Result()
-	if err != nil {
-		return false
-	}
-	// 不存在记录
-	if exist == 0 {
-		// 从数据库读取
-		set := GetModel().Set.GetSets(userID, SetOfLikeComment)
-		if len(set.LikeCommentID) > 0 {
-			var setID []string
-			for _, id := range set.LikeCommentID {
-				setID = append(setID, id.Hex())
-			}
-			err = c.Redis.SAdd(setName, setID).Err()
-			if err != nil {
-				return false
-			}
-		}
-	}
-	val, err := c.Redis.SIsMember(setName, commentID.Hex()).Result()
-	return val
-}
-
-// IsCollectTask 用户是否收藏任务
-func (c *CacheModel) IsCollectTask(userID, taskID primitive.ObjectID) bool {
-	setName := string(KindOfCollectTask) + userID.Hex()
-	exist, err := c.Redis.Exists(setName).Result()
-	if err != nil {
-		return false
-	}
-	// 不存在记录
-	if exist == 0 {
-		// 从数据库读取
-		set := GetModel().Set.GetSets(userID, SetOfCollectTask)
-		if len(set.CollectTaskID) > 0 {
-			var setID []string
-			for _, id := range set.CollectTaskID {
-				setID = append(setID, id.Hex())
-			}
-			err = c.Redis.SAdd(setName, setID).Err()
-			if err != nil {
-				return false
-			}
-		}
-	}
-	val, err := c.Redis.SIsMember(setName, taskID.Hex()).Result()
-	return val
-}
-
-// IsFollowerUser 用户是否被某人关注
-func (c *CacheModel) IsFollowerUser(userID, otherID primitive.ObjectID) bool {
-	setName := string(KindOfFollower) + userID.Hex()
-	exist, err := c.Redis.Exists(setName).Result()
-	if err != nil {
-		return false
-	}
-	// 不存在记录
-	if exist == 0 {
-		// 从数据库读取
-		set := GetModel().Set.GetSets(userID, SetOfFollowerUser)
-		if len(set.FollowerUserID) > 0 {
-			var setID []string
-			for _, id := range set.FollowerUserID {
-				setID = append(setID, id.Hex())
-			}
-			err = c.Redis.SAdd(setName, setID).Err()
-			if err != nil {
-				return false
-			}
-		}
-	}
-	val, err := c.Redis.SIsMember(setName, otherID.Hex()).Result()
-	return val
-}
-
-// IsFollowingUser 用户是否已关注某人
-func (c *CacheModel) IsFollowingUser(userID, otherID primitive.ObjectID) bool {
-	setName := string(KindOfFollowing) + userID.Hex()
-	exist, err := c.Redis.Exists(setName).Result()
-	if err != nil {
-		return false
-	}
-	// 不存在记录
-	if exist == 0 {
-		// 从数据库读取
-		set := GetModel().Set.GetSets(userID, SetOfFollowingUser)
-		if len(set.FollowingUserID) > 0 {
-			var setID []string
-			for _, id := range set.FollowingUserID {
+			for _, id := range set.BlockUserID {
 				setID = append(setID, id.Hex())
 			}
 			err = c.Redis.SAdd(setName, setID).Err()
@@ -176,6 +68,19 @@ type UserBaseInfo struct {
 	Avatar   string
 	Gender   UserGender
 	Type     UserType
+	Level    int64
+}
+
+// newUserBaseInfo 从用户数据生成基本信息
+func newUserBaseInfo(user UserSchema) UserBaseInfo {
+	return UserBaseInfo{
+		ID:       user.ID.Hex(),
+		Nickname: user.Info.Nickname,
+		Avatar:   user.Info.Avatar,
+		Gender:   user.Info.Gender,
+		Type:     user.Data.Type,
+		Level:    user.Data.Level,
+	}
 }
 
 // GetUserBaseInfo 获取用户基本信息
@@ -189,11 +94,7 @@ func (c *CacheModel) GetUserBaseInfo(id primitive.ObjectID) (UserBaseInfo, error
 		if err != nil {
 			return baseInfo, err
 		}
-		baseInfo.ID = user.ID.Hex()
-		baseInfo.Nickname = user.Info.Nickname
-		baseInfo.Avatar = user.Info.Avatar
-		baseInfo.Gender = user.Info.Gender
-		baseInfo.Type = user.Data.Type
+		baseInfo = newUserBaseInfo(user)
 		str, err := jsoniter.Marshal(baseInfo)
 		if err != nil {
 			return baseInfo, err
@@ -204,6 +105,84 @@ func (c *CacheModel) GetUserBaseInfo(id primitive.ObjectID) (UserBaseInfo, error
 	return baseInfo, err
 }
 
+// GetUsersBaseInfo 批量获取用户基本信息，不存在的用户不在结果中
+func (c *CacheModel) GetUsersBaseInfo(ids []primitive.ObjectID) (map[primitive.ObjectID]UserBaseInfo, error) {
+	res := map[primitive.ObjectID]UserBaseInfo{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = "info-" + id.Hex()
+	}
+	vals, err := c.Redis.MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var missing []primitive.ObjectID
+	for i, val := range vals {
+		baseInfo := UserBaseInfo{}
+		if str, ok := val.(string); !ok || jsoniter.UnmarshalFromString(str, &baseInfo) != nil {
+			missing = append(missing, ids[i])
+			continue
+		}
+		res[ids[i]] = baseInfo
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+	// 从数据库读取缺少的记录
+	users, err := GetModel().User.GetUsersByIDs(missing)
+	if err != nil {
+		return nil, err
+	}
+	pipe := c.Redis.Pipeline()
+	for _, user := range users {
+		baseInfo := newUserBaseInfo(user)
+		res[user.ID] = baseInfo
+		str, err := jsoniter.Marshal(baseInfo)
+		if err != nil {
+			return nil, err
+		}
+		pipe.Set("info-"+user.ID.Hex(), str, time.Hour*24)
+	}
+	if len(users) > 0 {
+		_, err = pipe.Exec()
+	}
+	return res, err
+}
+
+// GetUserPermissions 获取用户拥有的权限
+// 管理员拥有全部权限，普通用户拥有被授予角色的权限，封禁用户没有任何权限
+func (c *CacheModel) GetUserPermissions(id primitive.ObjectID) ([]Permission, error) {
+	permissions := []Permission{}
+	val, err := c.Redis.Get(string(KindOfPermission) + id.Hex()).Result()
+	// 不存在记录
+	if err != nil {
+		// 从数据库读取
+		user, err := GetModel().User.GetUserByID(id)
+		if err != nil {
+			return permissions, err
+		}
+		switch user.Data.Type {
+		case UserTypeAdmin, UserTypeRoot:
+			permissions = AllPermissions
+		case UserTypeNormal:
+			permissions, err = GetModel().Role.GetPermissionsByRoles(user.Roles)
+			if err != nil {
+				return permissions, err
+			}
+		}
+		str, err := jsoniter.Marshal(permissions)
+		if err != nil {
+			return permissions, err
+		}
+		return permissions, c.Redis.Set(string(KindOfPermission)+id.Hex(), str, time.Hour*24).Err()
+	}
+	err = jsoniter.Unmarshal([]byte(val), &permissions)
+	return permissions, err
+}
+
 // SetCertification 设置认证
 func (c *CacheModel) SetCertification(userID primitive.ObjectID, code string) error {
 	return c.Redis.Set("certification-"+userID.Hex(), code, time.Minute*30).Err()
@@ -222,27 +201,3 @@ func (c *CacheModel) CheckCertification(userID primitive.ObjectID, email, code s
 	err = c.Redis.Del("certification-" + userID.Hex()).Err()
 	return true, true
 }
-
-// SetSessionUser 设置会话用户ID
-func (c *CacheModel) SetSessionUser(session string, userID primitive.ObjectID) error {
-	_, err := c.Redis.Set("login-"+session, userID.Hex(), time.Minute*10).Result()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// GetSessionUser 获取用户ID
-func (c *CacheModel) GetSessionUser(session string) (primitive.ObjectID, error) {
-	user, err := c.Redis.Get("login-" + session).Result()
-	if err != nil {
-		return primitive.NilObjectID, err
-	} else if user == "" {
-		return primitive.NilObjectID, ErrNotExist
-	}
-	userID, err := primitive.ObjectIDFromHex(user)
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
-	return userID, nil
-}
@@ -10,49 +10,11 @@ func TestCache(t *testing.T) {
 	t.Run("InitRedis", testInitRedis)
 	t.Run("InitDB", testInitDB)
 	t.Run("GetUserBaseInfo", testGetUserBaseInfo)
-	t.Run("testAddLike", testAddLike)
+	t.Run("GetUsersBaseInfo", testGetUsersBaseInfo)
 	t.Run("DisconnectRedis", testDisconnectRedis)
 	t.Run("DisconnectDB", testDisconnectDB)
 }
 
-func testAddLike(t *testing.T) {
-	userID := primitive.NewObjectID()
-	taskID := primitive.NewObjectID()
-	err := redisInst.Cache.WillUpdate(userID, KindOfLikeTask)
-	if err != nil {
-		t.Error(err)
-	}
-
-	err = model.Set.AddToSet(userID, taskID, SetOfLikeTask)
-	if err != nil {
-		t.Error(err)
-	}
-	if redisInst.Cache.IsLikeTask(userID, primitive.NewObjectID()) == true {
-		t.Error()
-	}
-	if redisInst.Cache.IsLikeTask(userID, taskID) == false {
-		t.Error()
-	}
-	taskID = primitive.NewObjectID()
-	err = model.Set.AddToSet(userID, taskID, SetOfLikeTask)
-	if err != nil {
-		t.Error(err)
-	}
-	if redisInst.Cache.IsLikeTask(userID, taskID) == true {
-		t.Error()
-	}
-
-	err = redisInst.Cache.WillUpdate(userID, KindOfLikeTask)
-	if err != nil {
-		t.Error(err)
-	}
-
-	if redisInst.Cache.IsLikeTask(userID, taskID) == false {
-		t.Error()
-	}
-
-}
-
 func testGetUserBaseInfo(t *testing.T) {
 	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
 	if err != nil {
@@ -111,3 +73,29 @@ func testGetUserBaseInfo(t *testing.T) {
 	}
 
 }
+
+func testGetUsersBaseInfo(t *testing.T) {
+	cached, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	uncached, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err = redisInst.Cache.GetUserBaseInfo(cached); err != nil {
+		t.Error(err)
+	}
+	if err = redisInst.Cache.WillUpdate(uncached, KindOfBaseInfo); err != nil {
+		t.Error(err)
+	}
+
+	// 缓存和数据库中的用户都能获取，不存在的用户不返回
+	infos, err := redisInst.Cache.GetUsersBaseInfo([]primitive.ObjectID{cached, uncached, primitive.NewObjectID()})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(infos) != 2 || infos[cached].ID != cached.Hex() || infos[uncached].ID != uncached.Hex() {
+		t.Error("wrong base info", infos)
+	}
+}
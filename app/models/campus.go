@@ -0,0 +1,164 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CampusModel 校区数据库
+type CampusModel struct {
+	Collection *mongo.Collection
+}
+
+// CampusSchema 校区数据结构
+type CampusSchema struct {
+	ID      primitive.ObjectID   `bson:"_id,omitempty" json:"id"` // 校区 ID
+	Name    string               `bson:"name"`                    // 校区名称，与认证内容(学校/单位)对应 [索引]
+	Domains []string             `bson:"domains"`                 // 属于该校区的认证邮箱后缀 [索引]
+	Admins  []primitive.ObjectID `bson:"admins" json:"-"`         // 校区管理员
+	Time    int64                `bson:"time"`                    // 创建时间
+}
+
+// AddCampus 添加校区
+func (m *CampusModel) AddCampus(campus CampusSchema) (primitive.ObjectID, error) {
+	ctx, over := GetCtx()
+	defer over()
+	campus.ID = primitive.NewObjectID()
+	campus.Time = time.Now().Unix()
+	if campus.Domains == nil {
+		campus.Domains = []string{}
+	}
+	campus.Admins = []primitive.ObjectID{}
+	_, err := m.Collection.InsertOne(ctx, campus)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return campus.ID, nil
+}
+
+// GetCampuses 获取全部校区
+func (m *CampusModel) GetCampuses() (campuses []CampusSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	campuses = []CampusSchema{}
+	for cursor.Next(ctx) {
+		campus := CampusSchema{}
+		if err = cursor.Decode(&campus); err != nil {
+			return
+		}
+		campuses = append(campuses, campus)
+	}
+	return
+}
+
+// GetCampusByID 获取校区
+func (m *CampusModel) GetCampusByID(id primitive.ObjectID) (campus CampusSchema, err error) {
+	return m.findCampus(bson.M{"_id": id})
+}
+
+// GetCampusByName 根据名称获取校区
+func (m *CampusModel) GetCampusByName(name string) (campus CampusSchema, err error) {
+	return m.findCampus(bson.M{"name": name})
+}
+
+// GetCampusByDomain 根据认证邮箱后缀获取校区
+func (m *CampusModel) GetCampusByDomain(domain string) (campus CampusSchema, err error) {
+	return m.findCampus(bson.M{"domains": domain})
+}
+
+// MatchCampus 根据认证邮箱后缀或认证内容(学校/单位)匹配校区，匹配失败返回空 ID
+func (m *CampusModel) MatchCampus(email, data string) primitive.ObjectID {
+	if emailParts := strings.Split(email, "@"); len(emailParts) == 2 {
+		if campus, err := m.GetCampusByDomain(emailParts[1]); err == nil {
+			return campus.ID
+		}
+	}
+	if data != "" {
+		if campus, err := m.GetCampusByName(data); err == nil {
+			return campus.ID
+		}
+	}
+	return primitive.NilObjectID
+}
+
+// findCampus 获取符合条件的校区
+func (m *CampusModel) findCampus(filter bson.M) (campus CampusSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	err = m.Collection.FindOne(ctx, filter).Decode(&campus)
+	if err == mongo.ErrNoDocuments {
+		err = ErrNotExist
+	}
+	return
+}
+
+// SetCampus 修改校区名称和邮箱后缀，为空则不修改
+func (m *CampusModel) SetCampus(id primitive.ObjectID, name string, domains []string) error {
+	ctx, over := GetCtx()
+	defer over()
+	update := bson.M{}
+	if name != "" {
+		update["name"] = name
+	}
+	if domains != nil {
+		update["domains"] = domains
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// RemoveCampus 删除校区
+func (m *CampusModel) RemoveCampus(id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	} else if res.DeletedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// AddCampusAdmin 添加校区管理员
+func (m *CampusModel) AddCampusAdmin(id, userID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"admins": userID}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// RemoveCampusAdmin 移除校区管理员
+func (m *CampusModel) RemoveCampusAdmin(id, userID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id, "admins": userID}, bson.M{"$pull": bson.M{"admins": userID}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCampusModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testCampus", testCampus)
+	t.Run("testCertificationCampus", testCertificationCampus)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.Campus.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = model.User.Collection.Drop(ctx); err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testCampus(t *testing.T) {
+	adminID := primitive.NewObjectID()
+
+	id, err := model.Campus.AddCampus(CampusSchema{Name: "大山中学", Domains: []string{"em.com"}})
+	if err != nil {
+		t.Error(err)
+	}
+	campus, err := model.Campus.GetCampusByDomain("em.com")
+	if err != nil || campus.ID != id {
+		t.Error(campus, err)
+	}
+	if _, err = model.Campus.GetCampusByDomain("none.com"); err != ErrNotExist {
+		t.Error(err)
+	}
+
+	if err = model.Campus.SetCampus(id, "", []string{"em.com", "mail.em.com"}); err != nil {
+		t.Error(err)
+	}
+	campus, err = model.Campus.GetCampusByName("大山中学")
+	if err != nil || len(campus.Domains) != 2 {
+		t.Error(campus, err)
+	}
+
+	if err = model.Campus.AddCampusAdmin(id, adminID); err != nil {
+		t.Error(err)
+	}
+	campus, err = model.Campus.GetCampusByID(id)
+	if err != nil || len(campus.Admins) != 1 || campus.Admins[0] != adminID {
+		t.Error(campus, err)
+	}
+	if err = model.Campus.RemoveCampusAdmin(id, adminID); err != nil {
+		t.Error(err)
+	}
+	if err = model.Campus.RemoveCampusAdmin(id, adminID); err != ErrNotExist {
+		t.Error("remove twice", err)
+	}
+
+	campuses, err := model.Campus.GetCampuses()
+	if err != nil || len(campuses) != 1 {
+		t.Error(campuses, err)
+	}
+	// 按邮箱后缀或名称匹配
+	if model.Campus.MatchCampus("student@mail.em.com", "") != id || model.Campus.MatchCampus("", "大山中学") != id ||
+		!model.Campus.MatchCampus("student@none.com", "小山中学").IsZero() {
+		t.Error("wrong match campus")
+	}
+	if err = model.Campus.RemoveCampus(id); err != nil {
+		t.Error(err)
+	}
+}
+
+func testCertificationCampus(t *testing.T) {
+	id, err := model.Campus.AddCampus(CampusSchema{Name: "小山中学", Domains: []string{"xs.com"}})
+	if err != nil {
+		t.Error(err)
+	}
+	userID, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	// 校区功能上线前通过的认证
+	err = model.User.SetUserCertification(userID, UserCertificationSchema{
+		Identity: IdentityStudent,
+		Method:   CertificationByEmail,
+		Status:   CertificationTrue,
+		Email:    "student@xs.com",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	ctx, over := GetCtx()
+	defer over()
+	if err = model.User.MigrateCertificationCampus(ctx, model.Campus); err != nil {
+		t.Error(err)
+	}
+	user, err := model.User.GetUserByID(userID)
+	if err != nil || len(user.Certifications) != 1 || user.Certifications[0].Campus != id {
+		t.Error("campus not matched", user.Certifications, err)
+	}
+
+	if err = model.User.RemoveCertificationCampus(id); err != nil {
+		t.Error(err)
+	}
+	user, err = model.User.GetUserByID(userID)
+	if err != nil || !user.Certifications[0].Campus.IsZero() {
+		t.Error("campus remains", user.Certifications, err)
+	}
+}
@@ -30,10 +30,10 @@ type CommentSchema struct {
 }
 
 // AddComment 添加评论
-func (m *CommentModel) AddComment(contentID, contentOwn, userID primitive.ObjectID, content string, isReply bool) error {
+func (m *CommentModel) AddComment(contentID, contentOwn, userID primitive.ObjectID, content string, isReply bool) (primitive.ObjectID, error) {
 	ctx, finish := GetCtx()
 	defer finish()
-	_, err := m.Collection.InsertOne(ctx, &CommentSchema{
+	res, err := m.Collection.InsertOne(ctx, &CommentSchema{
 		ContentID:  contentID,
 		ContentOwn: contentOwn,
 		UserID:     userID,
@@ -42,14 +42,22 @@ func (m *CommentModel) AddComment(contentID, contentOwn, userID primitive.Object
 		IsDelete:   false,
 		IsReply:    isReply,
 	})
-	return err
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+	return res.InsertedID.(primitive.ObjectID), nil
 }
 
-// GetCommentsByContent 分页获取评论
-func (m *CommentModel) GetCommentsByContent(contentID primitive.ObjectID, page, size int64, sort bson.M) (res []CommentSchema, err error) {
+// GetCommentsByContent 分页获取评论，可排除指定用户的评论
+func (m *CommentModel) GetCommentsByContent(contentID primitive.ObjectID, page, size int64, sort bson.M,
+	excludeUsers ...primitive.ObjectID) (res []CommentSchema, err error) {
 	ctx, finish := GetCtx()
 	defer finish()
-	cur, err := m.Collection.Find(ctx, bson.M{"content_id": contentID},
+	filter := bson.M{"content_id": contentID}
+	if len(excludeUsers) > 0 {
+		filter["user_id"] = bson.M{"$nin": excludeUsers}
+	}
+	cur, err := m.Collection.Find(ctx, filter,
 		options.Find().SetSkip((page-1)*size).SetLimit(size).SetSort(sort))
 	if err != nil {
 		return
@@ -102,3 +110,35 @@ func (m *CommentModel) InsertCount(commentID primitive.ObjectID, name ContentCou
 	}
 	return nil
 }
+
+// GetCommentsByUser 获取用户发表的所有评论
+func (m *CommentModel) GetCommentsByUser(userID primitive.ObjectID) (res []CommentSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cur, err := m.Collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"time": 1}))
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	res = []CommentSchema{}
+	for cur.Next(ctx) {
+		comment := CommentSchema{}
+		if err = cur.Decode(&comment); err != nil {
+			return
+		}
+		res = append(res, comment)
+	}
+	return
+}
+
+// TransferUser 将用户的评论及被评论内容的所属用户转移到另一用户
+func (m *CommentModel) TransferUser(from, to primitive.ObjectID) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	for _, field := range []string{"user_id", "content_own"} {
+		if _, err := m.Collection.UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
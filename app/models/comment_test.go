@@ -31,7 +31,7 @@ func testAddComment(t *testing.T) {
 	contentID := primitive.NewObjectID()
 	userID := primitive.NewObjectID()
 	contentOwn := primitive.NewObjectID()
-	err = model.Comment.AddComment(contentID, contentOwn, userID, "Hello, world", false)
+	_, err = model.Comment.AddComment(contentID, contentOwn, userID, "Hello, world", false)
 	if err != nil {
 		t.Error(err)
 	}
@@ -42,7 +42,7 @@ func testAddComment(t *testing.T) {
 	}
 	t.Log(res)
 
-	err = model.Comment.AddComment(contentID, contentOwn, userID, "Hello, world", false)
+	_, err = model.Comment.AddComment(contentID, contentOwn, userID, "Hello, world", false)
 	if err != nil {
 		t.Error(err)
 	}
@@ -52,4 +52,9 @@ func testAddComment(t *testing.T) {
 		t.Error(err)
 	}
 	t.Log(res)
+	// 排除屏蔽用户的评论
+	res, err = model.Comment.GetCommentsByContent(contentID, 1, 10, bson.M{"time": 1}, userID)
+	if err != nil || len(res) != 0 {
+		t.Error(res, err)
+	}
 }
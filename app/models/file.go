@@ -43,10 +43,11 @@ type FileSchema struct {
 	Name        string             // 文件名
 	Description string             // 文件描述
 	Size        int64              // 文件大小
-	Public      bool               `json:"-"`   // 公开，非公开文件需要验证权限
-	URL         string             `json:"url"` // 下载链接
-	COSName     string             `json:"-"`   // 对象存储名字
-	Hash        string             `json:"-"`   // 文件哈希值
+	Public      bool               `json:"-"`                              // 公开，非公开文件需要验证权限
+	URL         string             `json:"url"`                            // 下载链接
+	COSName     string             `json:"-"`                              // 对象存储名字
+	Hash        string             `json:"-"`                              // 文件哈希值
+	ExpireTime  int64              `bson:"expire_time,omitempty" json:"-"` // 计划删除时间(用户注销后)
 }
 
 // AddFile 添加文件
@@ -201,3 +202,40 @@ func (m *FileModel) GetFileByHash(hash string) (file FileSchema, err error) {
 	err = m.Collection.FindOne(ctx, bson.M{"hash": hash}).Decode(&file)
 	return
 }
+
+// TransferOwner 将用户的文件转移到另一用户
+func (m *FileModel) TransferOwner(from, to primitive.ObjectID) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"owner_id": from, "owner": FileForUser},
+		bson.M{"$set": bson.M{"owner_id": to}})
+	return err
+}
+
+// SetUserFilesExpire 设置用户私有文件的计划删除时间
+func (m *FileModel) SetUserFilesExpire(userID primitive.ObjectID, expireTime int64) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"owner_id": userID, "owner": FileForUser, "public": false},
+		bson.M{"$set": bson.M{"expire_time": expireTime}})
+	return err
+}
+
+// GetExpiredFiles 获取已到计划删除时间的文件
+func (m *FileModel) GetExpiredFiles(now int64) (files []FileSchema, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	cursor, err := m.Collection.Find(ctx, bson.M{"expire_time": bson.M{"$gt": 0, "$lte": now}})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		file := FileSchema{}
+		if err = cursor.Decode(&file); err != nil {
+			return
+		}
+		files = append(files, file)
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FolderModel 收藏夹数据库
+type FolderModel struct {
+	Collection *mongo.Collection
+}
+
+// FolderSchema 收藏夹数据结构
+type FolderSchema struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id"` // 收藏夹 ID
+	User primitive.ObjectID `bson:"user" json:"-"`           // 所属用户 [索引]
+	Name string             `bson:"name"`                    // 收藏夹名称
+	Time int64              `bson:"time"`                    // 创建时间
+}
+
+// AddFolder 添加收藏夹
+func (m *FolderModel) AddFolder(userID primitive.ObjectID, name string) (primitive.ObjectID, error) {
+	ctx, over := GetCtx()
+	defer over()
+	folder := FolderSchema{
+		ID:   primitive.NewObjectID(),
+		User: userID,
+		Name: name,
+		Time: time.Now().Unix(),
+	}
+	_, err := m.Collection.InsertOne(ctx, folder)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return folder.ID, nil
+}
+
+// GetFolders 获取用户的全部收藏夹（按创建时间排序）
+func (m *FolderModel) GetFolders(userID primitive.ObjectID) (folders []FolderSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"user": userID}, options.Find().SetSort(bson.M{"time": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	folders = []FolderSchema{}
+	for cursor.Next(ctx) {
+		folder := FolderSchema{}
+		if err = cursor.Decode(&folder); err != nil {
+			return
+		}
+		folders = append(folders, folder)
+	}
+	return
+}
+
+// GetFolder 获取用户的收藏夹
+func (m *FolderModel) GetFolder(userID, id primitive.ObjectID) (folder FolderSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	err = m.Collection.FindOne(ctx, bson.M{"_id": id, "user": userID}).Decode(&folder)
+	if err == mongo.ErrNoDocuments {
+		err = ErrNotExist
+	}
+	return
+}
+
+// CountFolders 统计用户的收藏夹数量
+func (m *FolderModel) CountFolders(userID primitive.ObjectID) (int64, error) {
+	ctx, over := GetCtx()
+	defer over()
+	return m.Collection.CountDocuments(ctx, bson.M{"user": userID})
+}
+
+// SetFolderName 修改收藏夹名称
+func (m *FolderModel) SetFolderName(userID, id primitive.ObjectID, name string) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id, "user": userID}, bson.M{"$set": bson.M{"name": name}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// RemoveFolder 删除收藏夹
+func (m *FolderModel) RemoveFolder(userID, id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.DeleteOne(ctx, bson.M{"_id": id, "user": userID})
+	if err != nil {
+		return err
+	} else if res.DeletedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// TransferFolders 将用户 from 的收藏夹转移给用户 to
+func (m *FolderModel) TransferFolders(from, to primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"user": from}, bson.M{"$set": bson.M{"user": to}})
+	return err
+}
@@ -0,0 +1,383 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// suggestionFanOut 计算推荐关注时最多参考的关注人数量
+const suggestionFanOut = 200
+
+// FollowModel 关注关系数据库
+type FollowModel struct {
+	Collection *mongo.Collection
+}
+
+// FollowSchema 关注关系，每条记录表示 User 关注了 Target
+type FollowSchema struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"` // 记录 ID
+	User   primitive.ObjectID `bson:"user"`                   // 关注者 [索引]
+	Target primitive.ObjectID `bson:"target"`                 // 被关注者 [索引]
+	Time   int64              `bson:"time"`                   // 关注时间
+}
+
+// FollowSuggestion 推荐关注的用户
+type FollowSuggestion struct {
+	User   primitive.ObjectID `bson:"_id"`    // 推荐的用户
+	Mutual int64              `bson:"mutual"` // 共同关注人数
+}
+
+// AddFollow 添加关注关系，已关注时返回 ErrExist
+func (m *FollowModel) AddFollow(userID, targetID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"user": userID, "target": targetID},
+		bson.M{"$setOnInsert": bson.M{"time": time.Now().Unix()}}, options.Update().SetUpsert(true))
+	if isDuplicateKey(err) { // 并发关注时由唯一索引保证只添加一次
+		return ErrExist
+	} else if err != nil {
+		return err
+	} else if res.UpsertedCount == 0 {
+		return ErrExist
+	}
+	return nil
+}
+
+// RemoveFollow 移除关注关系
+func (m *FollowModel) RemoveFollow(userID, targetID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.DeleteOne(ctx, bson.M{"user": userID, "target": targetID})
+	if err != nil {
+		return err
+	} else if res.DeletedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// IsFollowing 用户是否已关注某人
+func (m *FollowModel) IsFollowing(userID, targetID primitive.ObjectID) (bool, error) {
+	ctx, over := GetCtx()
+	defer over()
+	count, err := m.Collection.CountDocuments(ctx, bson.M{"user": userID, "target": targetID},
+		options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// GetFollowing 分页获取用户的关注（按关注时间倒序）
+func (m *FollowModel) GetFollowing(userID primitive.ObjectID, skip, limit int64) ([]FollowSchema, int64, error) {
+	return m.findFollows(bson.M{"user": userID}, skip, limit)
+}
+
+// GetFollowers 分页获取用户的粉丝（按关注时间倒序）
+func (m *FollowModel) GetFollowers(targetID primitive.ObjectID, skip, limit int64) ([]FollowSchema, int64, error) {
+	return m.findFollows(bson.M{"target": targetID}, skip, limit)
+}
+
+// findFollows 分页获取符合条件的关注关系
+func (m *FollowModel) findFollows(filter bson.M, skip, limit int64) (res []FollowSchema, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	count, err = m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return
+	}
+	cursor, err := m.Collection.Find(ctx, filter,
+		options.Find().SetSort(bson.M{"time": -1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	res = []FollowSchema{}
+	for cursor.Next(ctx) {
+		follow := FollowSchema{}
+		if err = cursor.Decode(&follow); err != nil {
+			return
+		}
+		res = append(res, follow)
+	}
+	return
+}
+
+// GetFollowerIDs 获取用户全部粉丝的 ID
+func (m *FollowModel) GetFollowerIDs(targetID primitive.ObjectID) (res []primitive.ObjectID, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"target": targetID}, options.Find().SetProjection(bson.M{"user": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		follow := FollowSchema{}
+		if err = cursor.Decode(&follow); err != nil {
+			return
+		}
+		res = append(res, follow.User)
+	}
+	return
+}
+
+// GetMutual 分页获取与用户互相关注的用户（按关注时间倒序）
+func (m *FollowModel) GetMutual(userID primitive.ObjectID, skip, limit int64) (res []FollowSchema, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"user": userID}},
+		bson.M{"$lookup": bson.M{
+			"from": m.Collection.Name(),
+			"let":  bson.M{"target": "$target"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$user", "$$target"}},
+					bson.M{"$eq": bson.A{"$target", userID}},
+				}}}},
+				bson.M{"$limit": 1},
+			},
+			"as": "back",
+		}},
+		bson.M{"$match": bson.M{"back.0": bson.M{"$exists": true}}},
+		bson.M{"$project": bson.M{"back": 0}},
+		bson.M{"$sort": bson.M{"time": -1}},
+		bson.M{"$facet": bson.M{
+			"data":  bson.A{bson.M{"$skip": skip}, bson.M{"$limit": limit}},
+			"count": bson.A{bson.M{"$count": "count"}},
+		}},
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	res = []FollowSchema{}
+	if cursor.Next(ctx) {
+		result := struct {
+			Data  []FollowSchema `bson:"data"`
+			Count []struct {
+				Count int64 `bson:"count"`
+			} `bson:"count"`
+		}{}
+		if err = cursor.Decode(&result); err != nil {
+			return
+		}
+		if result.Data != nil {
+			res = result.Data
+		}
+		if len(result.Count) > 0 {
+			count = result.Count[0].Count
+		}
+	}
+	return
+}
+
+// GetSuggestions 获取推荐关注的用户（关注的人也关注的用户），按共同关注人数排序，排除自己和已关注的用户
+func (m *FollowModel) GetSuggestions(userID primitive.ObjectID, exclude []primitive.ObjectID, limit int64) (res []FollowSuggestion, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"user": userID}},
+		bson.M{"$sort": bson.M{"time": -1}},
+		bson.M{"$limit": suggestionFanOut},
+		bson.M{"$lookup": bson.M{
+			"from":         m.Collection.Name(),
+			"localField":   "target",
+			"foreignField": "user",
+			"as":           "next",
+		}},
+		bson.M{"$unwind": "$next"},
+		bson.M{"$group": bson.M{"_id": "$next.target", "mutual": bson.M{"$sum": 1}}},
+		bson.M{"$match": bson.M{"_id": bson.M{"$nin": append([]primitive.ObjectID{userID}, exclude...)}}},
+		bson.M{"$lookup": bson.M{
+			"from": m.Collection.Name(),
+			"let":  bson.M{"target": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$user", userID}},
+					bson.M{"$eq": bson.A{"$target", "$$target"}},
+				}}}},
+				bson.M{"$limit": 1},
+			},
+			"as": "followed",
+		}},
+		bson.M{"$match": bson.M{"followed.0": bson.M{"$exists": false}}},
+		bson.M{"$sort": bson.D{{Key: "mutual", Value: -1}, {Key: "_id", Value: -1}}},
+		bson.M{"$limit": limit},
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	res = []FollowSuggestion{}
+	for cursor.Next(ctx) {
+		suggestion := FollowSuggestion{}
+		if err = cursor.Decode(&suggestion); err != nil {
+			return
+		}
+		res = append(res, suggestion)
+	}
+	return
+}
+
+// CountFollows 统计用户的关注数和粉丝数
+func (m *FollowModel) CountFollows(userID primitive.ObjectID) (following, follower int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	following, err = m.Collection.CountDocuments(ctx, bson.M{"user": userID})
+	if err != nil {
+		return
+	}
+	follower, err = m.Collection.CountDocuments(ctx, bson.M{"target": userID})
+	return
+}
+
+// MergeFollows 将用户 from 的关注关系合并到用户 to，返回关系中涉及的其他用户
+func (m *FollowModel) MergeFollows(from, to primitive.ObjectID) (related []primitive.ObjectID, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	filter := bson.M{"$or": bson.A{bson.M{"user": from}, bson.M{"target": from}}}
+	cursor, err := m.Collection.Find(ctx, filter)
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		follow := FollowSchema{}
+		if err = cursor.Decode(&follow); err != nil {
+			return
+		}
+		other := follow.Target
+		if follow.User == from {
+			follow.User = to
+		} else {
+			other = follow.User
+			follow.Target = to
+		}
+		// 合并后不能关注自己
+		if follow.User == follow.Target {
+			continue
+		}
+		related = append(related, other)
+		if _, err = m.Collection.UpdateOne(ctx, bson.M{"user": follow.User, "target": follow.Target},
+			bson.M{"$min": bson.M{"time": follow.Time}}, options.Update().SetUpsert(true)); err != nil {
+			return
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return
+	}
+	_, err = m.Collection.DeleteMany(ctx, filter)
+	return
+}
+
+// recountFollows 重新统计用户的关注数和粉丝数
+func (m *FollowModel) recountFollows(ctx context.Context, users *UserModel, ids []primitive.ObjectID) error {
+	counted := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		if counted[id] {
+			continue
+		}
+		counted[id] = true
+		following, err := m.Collection.CountDocuments(ctx, bson.M{"user": id})
+		if err != nil {
+			return err
+		}
+		follower, err := m.Collection.CountDocuments(ctx, bson.M{"target": id})
+		if err != nil {
+			return err
+		}
+		if _, err = users.Collection.UpdateOne(ctx, bson.M{"_id": id},
+			bson.M{"$set": bson.M{"data.following_count": following, "data.follower_count": follower}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DedupeFollows 删除重复的关注关系(只保留最早的一条)，并重新统计相关用户的关注数和粉丝数
+func (m *FollowModel) DedupeFollows(ctx context.Context, users *UserModel) error {
+	cursor, err := m.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$sort": bson.M{"time": 1}},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"user": "$user", "target": "$target"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	var related []primitive.ObjectID
+	for cursor.Next(ctx) {
+		group := struct {
+			ID  FollowSchema         `bson:"_id"`
+			IDs []primitive.ObjectID `bson:"ids"`
+		}{}
+		if err = cursor.Decode(&group); err != nil {
+			return err
+		}
+		if _, err = m.Collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}}); err != nil {
+			return err
+		}
+		related = append(related, group.ID.User, group.ID.Target)
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	return m.recountFollows(ctx, users, related)
+}
+
+// MigrateFollowSets 将集合数据库中的关注/粉丝数组迁移为关注关系记录，并重新统计相关用户的关注数和粉丝数
+func (m *FollowModel) MigrateFollowSets(ctx context.Context, sets *SetModel, users *UserModel) error {
+	filter := bson.M{"$or": bson.A{
+		bson.M{string(SetOfFollowingUser): bson.M{"$exists": true}},
+		bson.M{string(SetOfFollowerUser): bson.M{"$exists": true}},
+	}}
+	cursor, err := sets.Collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	now := time.Now().Unix()
+	var related []primitive.ObjectID
+	for cursor.Next(ctx) {
+		set := SetSchemas{}
+		if err = cursor.Decode(&set); err != nil {
+			return err
+		}
+		var follows []FollowSchema
+		for _, id := range set.FollowingUserID {
+			follows = append(follows, FollowSchema{User: set.UserID, Target: id})
+		}
+		for _, id := range set.FollowerUserID {
+			follows = append(follows, FollowSchema{User: id, Target: set.UserID})
+		}
+		for _, follow := range follows {
+			if follow.User == follow.Target {
+				continue
+			}
+			related = append(related, follow.User, follow.Target)
+			if _, err = m.Collection.UpdateOne(ctx, bson.M{"user": follow.User, "target": follow.Target},
+				bson.M{"$setOnInsert": bson.M{"time": now}}, options.Update().SetUpsert(true)); err != nil {
+				return err
+			}
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	_, err = sets.Collection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{
+		string(SetOfFollowingUser): "",
+		string(SetOfFollowerUser):  "",
+	}})
+	if err != nil {
+		return err
+	}
+	return m.recountFollows(ctx, users, related)
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFollowModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testFollow", testFollow)
+	t.Run("testMergeFollows", testMergeFollows)
+	t.Run("testConcurrentFollow", testConcurrentFollow)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.Follow.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testFollow(t *testing.T) {
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	for _, follow := range [][2]primitive.ObjectID{{a, b}, {b, a}, {b, c}} {
+		if err := model.Follow.AddFollow(follow[0], follow[1]); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := model.Follow.AddFollow(a, b); err != ErrExist {
+		t.Error("follow twice")
+	}
+	following, err := model.Follow.IsFollowing(a, b)
+	if err != nil || !following {
+		t.Error("not following", err)
+	}
+
+	follows, count, err := model.Follow.GetFollowers(b, 0, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 || len(follows) != 1 || follows[0].User != a || follows[0].Time == 0 {
+		t.Error("wrong followers", count, follows)
+	}
+
+	// 互相关注
+	follows, count, err = model.Follow.GetMutual(a, 0, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 || len(follows) != 1 || follows[0].Target != b {
+		t.Error("wrong mutual", count, follows)
+	}
+
+	// 关注的人也关注的用户
+	suggestions, err := model.Follow.GetSuggestions(a, nil, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(suggestions) != 1 || suggestions[0].User != c || suggestions[0].Mutual != 1 {
+		t.Error("wrong suggestions", suggestions)
+	}
+
+	if err = model.Follow.RemoveFollow(b, a); err != nil {
+		t.Error(err)
+	}
+	_, count, err = model.Follow.GetMutual(a, 0, 10)
+	if err != nil || count != 0 {
+		t.Error("wrong mutual after unfollow", count, err)
+	}
+}
+
+func testMergeFollows(t *testing.T) {
+	from, to, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	for _, follow := range [][2]primitive.ObjectID{{from, other}, {to, other}, {from, to}, {other, from}} {
+		if err := model.Follow.AddFollow(follow[0], follow[1]); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := model.Follow.MergeFollows(from, to); err != nil {
+		t.Error(err)
+	}
+	following, follower, err := model.Follow.CountFollows(to)
+	if err != nil {
+		t.Error(err)
+	}
+	// 重复关注合并为一条，不会关注自己
+	if following != 1 || follower != 1 {
+		t.Error("wrong count", following, follower)
+	}
+	following, follower, err = model.Follow.CountFollows(from)
+	if err != nil || following != 0 || follower != 0 {
+		t.Error("follows of merged user remain", following, follower, err)
+	}
+}
+
+func testConcurrentFollow(t *testing.T) {
+	user, target := primitive.NewObjectID(), primitive.NewObjectID()
+
+	// 并发关注只能成功一次
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- model.Follow.AddFollow(user, target)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	success := 0
+	for err := range errs {
+		if err == nil {
+			success++
+		} else if err != ErrExist {
+			t.Error(err)
+		}
+	}
+	following, _, err := model.Follow.CountFollows(user)
+	if success != 1 || following != 1 || err != nil {
+		t.Error("wrong concurrent follow", success, following, err)
+	}
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InviteModel 认证邀请码数据库
+type InviteModel struct {
+	Collection *mongo.Collection
+}
+
+// InviteSchema 认证邀请码数据结构
+type InviteSchema struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"` // 邀请码 ID
+	Code       string             `bson:"code"`                    // 邀请码 [索引]
+	Identity   UserIdentity       `bson:"identity"`                // 认证身份
+	Data       string             `bson:"data"`                    // 认证内容(学校/单位)
+	Creator    primitive.ObjectID `bson:"creator" json:"-"`        // 创建的管理员
+	MaxUse     int64              `bson:"max_use"`                 // 可使用次数，0 为不限制
+	UseCount   int64              `bson:"use_count"`               // 已使用次数
+	ExpireTime int64              `bson:"expire_time"`             // 过期时间，0 为永不过期
+	Time       int64              `bson:"time"`                    // 创建时间
+}
+
+// AddInvite 添加邀请码，邀请码已存在时返回 ErrNotExist
+func (m *InviteModel) AddInvite(invite InviteSchema) (primitive.ObjectID, error) {
+	ctx, over := GetCtx()
+	defer over()
+	invite.ID = primitive.NewObjectID()
+	invite.UseCount = 0
+	invite.Time = time.Now().Unix()
+	_, err := m.Collection.InsertOne(ctx, invite)
+	if isDuplicateKey(err) {
+		return primitive.NilObjectID, ErrNotExist
+	} else if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return invite.ID, nil
+}
+
+// GetInvites 分页获取邀请码（按创建时间倒序）
+func (m *InviteModel) GetInvites(skip, limit int64) (invites []InviteSchema, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	count, err = m.Collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return
+	}
+	cursor, err := m.Collection.Find(ctx, bson.M{},
+		options.Find().SetSort(bson.M{"time": -1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	invites = []InviteSchema{}
+	for cursor.Next(ctx) {
+		invite := InviteSchema{}
+		if err = cursor.Decode(&invite); err != nil {
+			return
+		}
+		invites = append(invites, invite)
+	}
+	return
+}
+
+// GetInviteByCode 根据邀请码获取邀请码信息
+func (m *InviteModel) GetInviteByCode(code string) (invite InviteSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	err = m.Collection.FindOne(ctx, bson.M{"code": code}).Decode(&invite)
+	if err == mongo.ErrNoDocuments {
+		err = ErrNotExist
+	}
+	return
+}
+
+// UseInvite 使用邀请码，邀请码不存在、已过期或已用完时返回 ErrNotExist
+func (m *InviteModel) UseInvite(code string) (invite InviteSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	err = m.Collection.FindOneAndUpdate(ctx, bson.M{
+		"code": code,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"expire_time": 0}, bson.M{"expire_time": bson.M{"$gt": time.Now().Unix()}}}},
+			bson.M{"$or": bson.A{bson.M{"max_use": 0}, bson.M{"$expr": bson.M{"$lt": bson.A{"$use_count", "$max_use"}}}}},
+		},
+	}, bson.M{"$inc": bson.M{"use_count": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&invite)
+	if err == mongo.ErrNoDocuments {
+		err = ErrNotExist
+	}
+	return
+}
+
+// RemoveInvite 删除邀请码
+func (m *InviteModel) RemoveInvite(id primitive.ObjectID) (invite InviteSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	err = m.Collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&invite)
+	if err == mongo.ErrNoDocuments {
+		err = ErrNotExist
+	}
+	return
+}
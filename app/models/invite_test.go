@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestInviteModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testInvite", testInvite)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.Invite.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testInvite(t *testing.T) {
+	adminID := primitive.NewObjectID()
+
+	_, err := model.Invite.AddInvite(InviteSchema{
+		Code:     "once",
+		Identity: IdentityTeacher,
+		Data:     "大山中学",
+		Creator:  adminID,
+		MaxUse:   1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = model.Invite.AddInvite(InviteSchema{
+		Code:       "expired",
+		Identity:   IdentityAlumni,
+		Creator:    adminID,
+		ExpireTime: time.Now().Unix() - 10,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	invite, err := model.Invite.GetInviteByCode("once")
+	if err != nil || invite.Data != "大山中学" || invite.UseCount != 0 {
+		t.Error(invite, err)
+	}
+	invite, err = model.Invite.UseInvite("once")
+	if err != nil || invite.Identity != IdentityTeacher || invite.UseCount != 1 {
+		t.Error(invite, err)
+	}
+	if _, err = model.Invite.UseInvite("once"); err != ErrNotExist {
+		t.Error("use twice", err)
+	}
+	if _, err = model.Invite.UseInvite("expired"); err != ErrNotExist {
+		t.Error("use expired", err)
+	}
+
+	invites, count, err := model.Invite.GetInvites(0, 10)
+	if err != nil || count != 2 || len(invites) != 2 {
+		t.Error(invites, count, err)
+	}
+	if _, err = model.Invite.RemoveInvite(invite.ID); err != nil {
+		t.Error(err)
+	}
+	if _, err = model.Invite.RemoveInvite(invite.ID); err != ErrNotExist {
+		t.Error("remove twice", err)
+	}
+}
@@ -122,3 +122,11 @@ func (m *LogModel) GetLog(userID primitive.ObjectID, logTypes []LogType,
 
 	return
 }
+
+// TransferUser 将用户的日志转移到另一用户
+func (m *LogModel) TransferUser(from, to primitive.ObjectID) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"user_id": from}, bson.M{"$set": bson.M{"user_id": to}})
+	return err
+}
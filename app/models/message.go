@@ -115,7 +115,9 @@ func (m *MessageModel) AddMessage(recUser primitive.ObjectID, messageType Messag
 	} else {
 		unread["unread_2"] = 1
 	}
-	data.Time = time.Now().Unix()
+	if data.Time == 0 {
+		data.Time = time.Now().Unix()
+	}
 	var res SessionSchema
 	err := m.Collection.FindOneAndUpdate(ctx, bson.M{
 		"user_1": user1, "user_2": user2, "type": messageType,
@@ -160,3 +162,155 @@ func (m *MessageModel) ReadMessage(sessionID primitive.ObjectID, firstUser bool)
 	}
 	return nil
 }
+
+// GetChatMessage 获取会话中指定用户在指定时间发送的消息
+func (m *MessageModel) GetChatMessage(sessionID, userID primitive.ObjectID, msgTime int64) (res MessageSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	session := SessionSchema{}
+	err = m.Collection.FindOne(ctx, bson.M{
+		"_id":      sessionID,
+		"messages": bson.M{"$elemMatch": bson.M{"user": userID, "time": msgTime}},
+	}, options.FindOne().SetProjection(bson.M{
+		"messages.$": 1,
+	})).Decode(&session)
+	if err != nil {
+		return
+	} else if len(session.Messages) < 1 {
+		return res, ErrNotExist
+	}
+	return session.Messages[0], nil
+}
+
+// HideChatMessage 屏蔽会话中指定用户在指定时间发送的消息
+func (m *MessageModel) HideChatMessage(sessionID, userID primitive.ObjectID, msgTime int64, content string) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": sessionID},
+		bson.M{"$set": bson.M{"messages.$[item].content": content}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"item.user": userID, "item.time": msgTime}},
+		}))
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// TransferUser 将用户的消息会话及发送的消息转移到另一用户
+// 转移后重新排列会话双方(ID 较大的为用户1)及未读数，与已有会话合并，删除两个用户之间的会话
+func (m *MessageModel) TransferUser(from, to primitive.ObjectID) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	cur, err := m.Collection.Find(ctx, bson.M{"$or": []bson.M{{"user_1": from}, {"user_2": from}}})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		session := SessionSchema{}
+		if err = cur.Decode(&session); err != nil {
+			return err
+		}
+		other, unreadFrom, unreadOther := session.User2, session.Unread1, session.Unread2
+		if session.User1 != from {
+			other, unreadFrom, unreadOther = session.User1, session.Unread2, session.Unread1
+		}
+		if other == from || other == to {
+			if _, err = m.Collection.DeleteOne(ctx, bson.M{"_id": session.ID}); err != nil {
+				return err
+			}
+			continue
+		}
+		// 固定顺序
+		user1, user2, unread1, unread2 := to, other, unreadFrom, unreadOther
+		if strings.Compare(user1.Hex(), user2.Hex()) < 0 {
+			user1, user2, unread1, unread2 = other, to, unreadOther, unreadFrom
+		}
+		if session.Messages == nil {
+			session.Messages = []MessageSchema{}
+		}
+		for i := range session.Messages {
+			if session.Messages[i].UserID == from {
+				session.Messages[i].UserID = to
+			}
+		}
+		if session.LastMessage.UserID == from {
+			session.LastMessage.UserID = to
+		}
+
+		exist := SessionSchema{}
+		err = m.Collection.FindOne(ctx, bson.M{"user_1": user1, "user_2": user2, "type": session.Type},
+			options.FindOne().SetProjection(bson.M{"messages": 0})).Decode(&exist)
+		if err == mongo.ErrNoDocuments {
+			_, err = m.Collection.UpdateOne(ctx, bson.M{"_id": session.ID}, bson.M{"$set": bson.M{
+				"user_1":       user1,
+				"user_2":       user2,
+				"unread_1":     unread1,
+				"unread_2":     unread2,
+				"last_message": session.LastMessage,
+				"messages":     session.Messages,
+			}})
+			if err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		// 合并到已有会话
+		update := bson.M{
+			"$push": bson.M{"messages": bson.M{
+				"$each": session.Messages,
+				"$sort": bson.M{"time": -1},
+			}},
+			"$inc": bson.M{"unread_1": unread1, "unread_2": unread2},
+		}
+		if session.LastMessage.Time > exist.LastMessage.Time {
+			update["$set"] = bson.M{"last_message": session.LastMessage}
+		}
+		if _, err = m.Collection.UpdateOne(ctx, bson.M{"_id": exist.ID}, update); err != nil {
+			return err
+		}
+		if _, err = m.Collection.DeleteOne(ctx, bson.M{"_id": session.ID}); err != nil {
+			return err
+		}
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+	// 其他会话中发送的消息(如任务通知)
+	_, err = m.Collection.UpdateMany(ctx, bson.M{"messages.user": from},
+		bson.M{"$set": bson.M{"messages.$[msg].user": to}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"msg.user": from}},
+		}))
+	if err != nil {
+		return err
+	}
+	_, err = m.Collection.UpdateMany(ctx, bson.M{"last_message.user": from}, bson.M{"$set": bson.M{"last_message.user": to}})
+	return err
+}
+
+// GetAllSessionsByUser 获取用户的所有会话及消息内容
+func (m *MessageModel) GetAllSessionsByUser(userID primitive.ObjectID) (res []SessionSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cur, err := m.Collection.Find(ctx, bson.M{"$or": []bson.M{{"user_1": userID}, {"user_2": userID}}},
+		options.Find().SetSort(bson.M{"last_message.time": -1}))
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	res = []SessionSchema{}
+	for cur.Next(ctx) {
+		session := SessionSchema{}
+		if err = cur.Decode(&session); err != nil {
+			return
+		}
+		res = append(res, session)
+	}
+	return
+}
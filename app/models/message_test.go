@@ -1,14 +1,17 @@
 package models
 
 import (
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
 	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestMessageModel(t *testing.T) {
 	t.Run("InitDB", testInitDB)
 
 	t.Run("testMessage", testMessage)
+	t.Run("testTransferUser", testTransferUser)
 
 	ctx, finish := GetCtx()
 	defer finish()
@@ -80,3 +83,39 @@ func testMessage(t *testing.T) {
 	}
 	t.Log(session)
 }
+
+func testTransferUser(t *testing.T) {
+	from, to, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	for _, msg := range []struct {
+		sender, receiver primitive.ObjectID
+	}{{other, from}, {other, from}, {other, to}, {from, to}} {
+		if _, err := GetModel().Message.AddMessage(msg.receiver, MessageTypeChat, MessageSchema{
+			UserID:  msg.sender,
+			Content: "hello",
+		}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := GetModel().Message.TransferUser(from, to); err != nil {
+		t.Error(err)
+	}
+
+	// 与同一用户的会话合并，两个用户之间的会话删除
+	if sessions := GetModel().Message.GetSessionsByUser(from, 1, 10); len(sessions) != 0 {
+		t.Error("sessions of from remain", sessions)
+	}
+	if sessions := GetModel().Message.GetSessionsByUser(to, 1, 10); len(sessions) != 1 {
+		t.Error("wrong sessions", sessions)
+	}
+	session, err := GetModel().Message.GetSessionWithMsgByUserID(to, other, 1, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	unread := session.Unread2
+	if strings.Compare(to.Hex(), other.Hex()) > 0 {
+		unread = session.Unread1
+	}
+	if len(session.Messages) != 3 || unread != 3 {
+		t.Error("wrong merged session", session)
+	}
+}
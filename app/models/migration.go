@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// 数据迁移
+
+// 每个迁移只执行一次，完成后记录在系统数据库中
+// 迁移按顺序执行，新的迁移只能追加在列表末尾，已发布的迁移不能改名
+
+// migrationTimeout 单个迁移的超时时间
+const migrationTimeout = 30 * time.Minute
+
+// migration 数据迁移
+type migration struct {
+	name string                          // 迁移名称
+	run  func(ctx context.Context) error // 迁移操作
+}
+
+// migrations 数据迁移列表
+var migrations = []migration{
+	{name: "certification-list", run: func(ctx context.Context) error {
+		var validDays int64
+		if conf := utils.GetConf(); conf != nil {
+			validDays = conf.Certification.ValidDays
+		}
+		return model.User.MigrateCertification(ctx, model.System, validDays)
+	}},
+	{name: "certification-campus", run: func(ctx context.Context) error {
+		return model.User.MigrateCertificationCampus(ctx, model.Campus)
+	}},
+	{name: "rating-average", run: func(ctx context.Context) error {
+		return model.User.MigrateRatingAverage(ctx)
+	}},
+	{name: "rating-null", run: func(ctx context.Context) error {
+		return model.User.MigrateRatingNull(ctx)
+	}},
+	{name: "experience-level", run: func(ctx context.Context) error {
+		var levels []int64
+		if conf := utils.GetConf(); conf != nil {
+			for _, level := range conf.Levels {
+				levels = append(levels, level.Experience)
+			}
+		}
+		return model.User.MigrateExperience(ctx, model.Log, levels)
+	}},
+	{name: "follow-sets", run: func(ctx context.Context) error {
+		return model.Follow.MigrateFollowSets(ctx, model.Set, model.User)
+	}},
+	{name: "follow-dedupe", run: func(ctx context.Context) error {
+		return model.Follow.DedupeFollows(ctx, model.User)
+	}},
+	{name: "reaction-sets", run: func(ctx context.Context) error {
+		return model.Reaction.MigrateReactionSets(ctx, model.Set)
+	}},
+	{name: "reaction-dedupe", run: func(ctx context.Context) error {
+		return model.Reaction.DedupeReactions(ctx, model.Task, model.Comment)
+	}},
+	{name: "attendance-dedupe", run: func(ctx context.Context) error {
+		return model.Attendance.DedupeAttendances(ctx)
+	}},
+	{name: "unique-indexes", run: upgradeUniqueIndexes},
+	{name: "nickname-key", run: func(ctx context.Context) error {
+		return model.User.MigrateNicknameKey(ctx)
+	}},
+}
+
+// getMigrationCtx 获取数据迁移使用的上下文
+func getMigrationCtx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), migrationTimeout)
+}
+
+// RunMigrations 执行未完成的数据迁移，需要在 InitDB 后调用
+func RunMigrations() error {
+	for _, m := range migrations {
+		done, err := model.System.IsMigrated(m.name)
+		if err != nil {
+			return err
+		} else if done {
+			continue
+		}
+		log.Info().Msg("Run migration " + m.name)
+		ctx, cancel := getMigrationCtx()
+		err = m.run(ctx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		if err = model.System.SetMigrated(m.name, time.Now().Unix()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -20,6 +20,9 @@ var model *Model
 // ErrNotExist 数据不存在
 var ErrNotExist = errors.New("not_exist")
 
+// ErrExist 数据已存在
+var ErrExist = errors.New("exist")
+
 // Model 数据库实例
 type Model struct {
 	client *mongo.Client
@@ -36,6 +39,17 @@ type Model struct {
 	File          *FileModel
 	Set           *SetModel
 	System        *SystemModel
+	Report        *ReportModel
+	Attendance    *AttendanceModel
+	Ban           *BanModel
+	Role          *RoleModel
+	Audit         *AuditModel
+	Invite        *InviteModel
+	Campus        *CampusModel
+	Follow        *FollowModel
+	Reaction      *ReactionModel
+	Folder        *FolderModel
+	APIToken      *APITokenModel
 }
 
 // GetModel 获取 Model 实例
@@ -49,48 +63,134 @@ func GetCtx() (context.Context, context.CancelFunc) {
 }
 
 // createIndexes 检查并创建索引
-func createIndexes(ctx context.Context, name string, indexes []bson.M) error {
-	collection := model.db.Collection(name)
-	collectionIndexes := collection.Indexes()
-	cur, err := collectionIndexes.List(ctx)
-	if err != nil { // 读取索引发生错误
-		return err
+// 唯一索引与旧数据或旧索引冲突时跳过，由数据迁移处理
+func createIndexes(ctx context.Context, name string, indexes []bson.D, unique bool) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+	collectionIndexes := model.db.Collection(name).Indexes()
+	log.Info().Msg("Init index for " + name)
+	for i := range indexes { // 创建索引，已存在的索引不会重复创建
+		_, err := collectionIndexes.CreateOne(ctx, mongo.IndexModel{
+			Keys:    indexes[i],
+			Options: options.Index().SetUnique(unique),
+		})
+		if unique && isIndexConflict(err) {
+			log.Warn().Err(err).Str("collection", name).Str("index", indexName(indexes[i])).
+				Msg("Unique index conflicts with existing data or index, run migrations to upgrade")
+			continue
+		}
+		if err != nil {
+			return err
+		}
 	}
-	if cur == nil { // 指针不存在
-		return errors.New("can't read collection")
+	return nil
+}
+
+// isIndexConflict 是否为同名索引选项不同(85/86)或数据存在重复(11000)导致的索引创建失败
+func isIndexConflict(err error) bool {
+	if cmdErr, ok := err.(mongo.CommandError); ok && (cmdErr.Code == 85 || cmdErr.Code == 86) {
+		return true
 	}
-	if !cur.Next(ctx) { // 索引不存在，创建索引
-		log.Info().Msg("Init index for " + name)
-		for i := range indexes { // 创建唯一索引
-			if _, err := collectionIndexes.CreateOne(ctx, mongo.IndexModel{
-				Keys:    indexes[i],
-				Options: options.Index().SetUnique(false),
-			}); err != nil {
+	return isDuplicateKey(err)
+}
+
+// upgradeUniqueIndexes 将旧版的非唯一索引替换为唯一索引，需要在去除重复数据后执行
+func upgradeUniqueIndexes(ctx context.Context) error {
+	for _, i := range dbIndexes {
+		collectionIndexes := model.db.Collection(i.name).Indexes()
+		for _, keys := range i.unique {
+			index := mongo.IndexModel{
+				Keys:    keys,
+				Options: options.Index().SetUnique(true),
+			}
+			_, err := collectionIndexes.CreateOne(ctx, index)
+			if cmdErr, ok := err.(mongo.CommandError); ok && (cmdErr.Code == 85 || cmdErr.Code == 86) {
+				log.Warn().Str("collection", i.name).Str("index", indexName(keys)).Msg("Replace index with unique index")
+				if _, err = collectionIndexes.DropOne(ctx, indexName(keys)); err != nil {
+					return err
+				}
+				_, err = collectionIndexes.CreateOne(ctx, index)
+			}
+			if err != nil {
 				return err
 			}
 		}
 	}
-	return cur.Close(ctx) // 关闭指针
+	return nil
+}
+
+// indexName 索引默认名称，与驱动生成的名称一致
+func indexName(keys bson.D) string {
+	name := ""
+	for i, key := range keys {
+		if i > 0 {
+			name += "_"
+		}
+		name += fmt.Sprintf("%s_%v", key.Key, key.Value)
+	}
+	return name
+}
+
+// isDuplicateKey 是否为唯一索引冲突错误
+func isDuplicateKey(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == 11000 {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return e.Code == 11000
+	}
+	return false
+}
+
+// dbIndexes 集合索引，unique 为唯一索引
+var dbIndexes = []struct {
+	name    string
+	indexes []bson.D
+	unique  []bson.D
+}{
+	{name: "comments", indexes: []bson.D{{{Key: "content_id", Value: 1}}}},
+	{name: "messages", indexes: []bson.D{{{Key: "user_1", Value: 1}}, {{Key: "user_2", Value: 1}}}},
+	{name: "tasks", indexes: []bson.D{{{Key: "publisher", Value: 1}},
+		{{Key: "status", Value: 1}, {Key: "publish_at", Value: 1}}, {{Key: "campuses", Value: 1}}}},
+	{name: "logs", indexes: []bson.D{{{Key: "user_id", Value: 1}}}},
+	{name: "task_status", indexes: []bson.D{{{Key: "task", Value: 1}}, {{Key: "player", Value: 1}}}},
+	{name: "files", indexes: []bson.D{{{Key: "owner_id", Value: 1}}}},
+	{name: "reports", indexes: []bson.D{{{Key: "target_id", Value: 1}},
+		{{Key: "status", Value: 1}, {Key: "time", Value: 1}}}},
+	{name: "attendance", unique: []bson.D{{{Key: "user", Value: 1}, {Key: "date", Value: 1}}}},
+	{name: "bans", indexes: []bson.D{{{Key: "user", Value: 1}, {Key: "status", Value: 1}},
+		{{Key: "status", Value: 1}, {Key: "end_time", Value: 1}}}},
+	{name: "users", indexes: []bson.D{{{Key: "roles", Value: 1}}, {{Key: "certifications.status", Value: 1}, {Key: "certifications.expire_time", Value: 1}},
+		{{Key: "info.nickname", Value: "text"}, {Key: "info.bio", Value: "text"}}, {{Key: "nickname_key", Value: 1}},
+		{{Key: "info.school", Value: 1}}, {{Key: "certifications.data", Value: 1}},
+		{{Key: "data.follower_count", Value: -1}}, {{Key: "reputation.player.average", Value: -1}}}},
+	{name: "audits", indexes: []bson.D{{{Key: "time", Value: 1}},
+		{{Key: "actor", Value: 1}, {Key: "time", Value: 1}}, {{Key: "target", Value: 1}, {Key: "time", Value: 1}}}},
+	{name: "invites", unique: []bson.D{{{Key: "code", Value: 1}}}},
+	{name: "campuses", indexes: []bson.D{{{Key: "name", Value: 1}}, {{Key: "domains", Value: 1}}}},
+	{name: "follows", indexes: []bson.D{{{Key: "user", Value: 1}, {Key: "time", Value: -1}}, {{Key: "target", Value: 1}, {Key: "time", Value: -1}}},
+		unique: []bson.D{{{Key: "user", Value: 1}, {Key: "target", Value: 1}}}},
+	{name: "reactions", indexes: []bson.D{{{Key: "user", Value: 1}, {Key: "type", Value: 1}, {Key: "time", Value: -1}},
+		{{Key: "target", Value: 1}, {Key: "type", Value: 1}}},
+		unique: []bson.D{{{Key: "user", Value: 1}, {Key: "type", Value: 1}, {Key: "target", Value: 1}}}},
+	{name: "folders", indexes: []bson.D{{{Key: "user", Value: 1}}}},
+	{name: "api_tokens", indexes: []bson.D{{{Key: "user", Value: 1}}}},
 }
 
 // initCollection 初始化集合
 func initCollection() error {
 	ctx, cancel := GetCtx()
 	defer cancel()
-	// 初始化索引
-	DBIndexes := []struct {
-		name    string
-		indexes []bson.M
-	}{
-		{name: "comments", indexes: []bson.M{{"content_id": 1}}},
-		{name: "messages", indexes: []bson.M{{"user_1": 1}, {"user_2": 1}}},
-		{name: "tasks", indexes: []bson.M{{"publisher": 1}}},
-		{name: "logs", indexes: []bson.M{{"user_id": 1}}},
-		{name: "task_status", indexes: []bson.M{{"task": 1}, {"player": 1}}},
-		{name: "files", indexes: []bson.M{{"owner_id": 1}}},
-	}
-	for _, i := range DBIndexes {
-		if err := createIndexes(ctx, i.name, i.indexes); err != nil {
+	for _, i := range dbIndexes {
+		if err := createIndexes(ctx, i.name, i.indexes, false); err != nil {
+			return err
+		}
+		if err := createIndexes(ctx, i.name, i.unique, true); err != nil {
 			return err
 		}
 	}
@@ -106,10 +206,6 @@ func InitDB(config *utils.DBConfig) error {
 	}
 	model.db = model.client.Database(config.DBName)
 
-	// 初始化集合
-	if err := initCollection(); err != nil {
-		return err
-	}
 	// 初始化 Model
 	// 公告数据库
 	model.Article = &ArticleModel{
@@ -155,7 +251,53 @@ func InitDB(config *utils.DBConfig) error {
 	model.System = &SystemModel{
 		Collection: model.db.Collection("system"),
 	}
-	return nil
+	// 举报数据库
+	model.Report = &ReportModel{
+		Collection: model.db.Collection("reports"),
+	}
+	// 签到记录数据库
+	model.Attendance = &AttendanceModel{
+		Collection: model.db.Collection("attendance"),
+	}
+	// 封禁记录数据库
+	model.Ban = &BanModel{
+		Collection: model.db.Collection("bans"),
+	}
+	// 角色数据库
+	model.Role = &RoleModel{
+		Collection: model.db.Collection("roles"),
+	}
+	// 管理操作审计数据库
+	model.Audit = &AuditModel{
+		Collection: model.db.Collection("audits"),
+	}
+	// 邀请码数据库
+	model.Invite = &InviteModel{
+		Collection: model.db.Collection("invites"),
+	}
+	// 校区数据库
+	model.Campus = &CampusModel{
+		Collection: model.db.Collection("campuses"),
+	}
+	// 关注关系数据库
+	model.Follow = &FollowModel{
+		Collection: model.db.Collection("follows"),
+	}
+	// 点赞/收藏数据库
+	model.Reaction = &ReactionModel{
+		Collection: model.db.Collection("reactions"),
+	}
+	// 收藏夹数据库
+	model.Folder = &FolderModel{
+		Collection: model.db.Collection("folders"),
+	}
+	// 个人 API 令牌数据库
+	model.APIToken = &APITokenModel{
+		Collection: model.db.Collection("api_tokens"),
+	}
+
+	// 初始化集合
+	return initCollection()
 }
 
 // 连接数据库
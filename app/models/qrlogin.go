@@ -0,0 +1,209 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 微信扫码登陆
+
+// PC 端获取二维码时创建扫码登陆记录，状态依次为 pending -> scanned -> confirmed/rejected
+// 每次状态变化都会发布到该记录的频道，PC 端订阅频道获取登陆结果
+// 登陆完成后记录即被删除，二维码只能使用一次
+
+// QRLoginModel 扫码登陆数据库
+type QRLoginModel struct {
+	Redis *redis.Client
+}
+
+// QRLoginState 扫码登陆状态
+type QRLoginState string
+
+// QRLoginState 扫码登陆状态
+const (
+	QRLoginPending   QRLoginState = "pending"   // 等待扫码
+	QRLoginScanned   QRLoginState = "scanned"   // 已扫码，等待手机确认
+	QRLoginConfirmed QRLoginState = "confirmed" // 手机已确认登陆
+	QRLoginRejected  QRLoginState = "rejected"  // 手机已拒绝登陆
+	QRLoginExpired   QRLoginState = "expired"   // 已过期或已使用
+)
+
+// QRLogin 扫码登陆记录
+type QRLogin struct {
+	ID        string             `json:"-"` // 随机 nonce
+	Session   string             `json:"-"` // PC 端 Cookie 会话 ID
+	State     QRLoginState       `json:"-"` // 状态
+	User      primitive.ObjectID `json:"-"` // 扫码用户
+	IP        string             // PC 端 IP
+	Device    string             // PC 端设备描述
+	UserAgent string             // PC 端 User-Agent
+	Time      int64              // 创建时间
+}
+
+// qrLoginKey 扫码登陆记录键名
+func qrLoginKey(id string) string {
+	return "qr-" + id
+}
+
+// QRLoginChannel 扫码登陆状态变化的发布频道
+func QRLoginChannel(id string) string {
+	return "qr-channel-" + id
+}
+
+// qrWatchKey 扫码登陆状态订阅计数键名
+func qrWatchKey(key string) string {
+	return "qr-watch-" + key
+}
+
+// scanQRLoginScript 只有等待扫码的记录可以被扫码，同一用户可以重复扫码
+var scanQRLoginScript = redis.NewScript(`
+local state = redis.call("HGET", KEYS[1], "state")
+if state == "pending" or (state == "scanned" and redis.call("HGET", KEYS[1], "user") == ARGV[1]) then
+	redis.call("HMSET", KEYS[1], "state", "scanned", "user", ARGV[1])
+	redis.call("PUBLISH", KEYS[2], "scanned")
+	return 1
+end
+return 0
+`)
+
+// confirmQRLoginScript 只有扫码用户可以确认或拒绝登陆
+var confirmQRLoginScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "state") == "scanned" and redis.call("HGET", KEYS[1], "user") == ARGV[1] then
+	redis.call("HSET", KEYS[1], "state", ARGV[2])
+	redis.call("PUBLISH", KEYS[2], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// finishQRLoginScript 已确认的记录只能由创建它的 PC 端会话使用一次
+var finishQRLoginScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "state") == "confirmed" and redis.call("HGET", KEYS[1], "session") == ARGV[1] then
+	local user = redis.call("HGET", KEYS[1], "user")
+	redis.call("DEL", KEYS[1])
+	return user
+end
+return false
+`)
+
+// acquireWatchScript 订阅计数未达到上限时加一，计数在最后一次订阅 ARGV[2] 秒后过期
+var acquireWatchScript = redis.NewScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("INCR", KEYS[1])
+redis.call("EXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+// releaseWatchScript 订阅计数减一，计数不存在时忽略，减到 0 时删除
+var releaseWatchScript = redis.NewScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count <= 1 then
+	redis.call("DEL", KEYS[1])
+	return 0
+end
+return redis.call("DECR", KEYS[1])
+`)
+
+// AddQRLogin 添加扫码登陆记录
+func (m *QRLoginModel) AddQRLogin(login QRLogin, expire time.Duration) error {
+	pipe := m.Redis.TxPipeline()
+	pipe.HMSet(qrLoginKey(login.ID), map[string]interface{}{
+		"session":    login.Session,
+		"state":      string(QRLoginPending),
+		"ip":         login.IP,
+		"device":     login.Device,
+		"user_agent": login.UserAgent,
+		"time":       login.Time,
+	})
+	pipe.Expire(qrLoginKey(login.ID), expire)
+	_, err := pipe.Exec()
+	return err
+}
+
+// GetQRLogin 获取扫码登陆记录，不存在或已过期时返回 ErrNotExist
+func (m *QRLoginModel) GetQRLogin(id string) (login QRLogin, err error) {
+	data, err := m.Redis.HGetAll(qrLoginKey(id)).Result()
+	if err != nil {
+		return
+	} else if len(data) == 0 {
+		return login, ErrNotExist
+	}
+	login.ID = id
+	login.Session = data["session"]
+	login.State = QRLoginState(data["state"])
+	login.User, _ = primitive.ObjectIDFromHex(data["user"])
+	login.IP = data["ip"]
+	login.Device = data["device"]
+	login.UserAgent = data["user_agent"]
+	login.Time, _ = strconv.ParseInt(data["time"], 10, 64)
+	return
+}
+
+// ScanQRLogin 标记为已扫码，状态不正确时返回 ErrNotExist
+func (m *QRLoginModel) ScanQRLogin(id string, userID primitive.ObjectID) error {
+	res, err := scanQRLoginScript.Run(m.Redis, []string{qrLoginKey(id), QRLoginChannel(id)}, userID.Hex()).Int()
+	if err != nil {
+		return err
+	} else if res == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// ConfirmQRLogin 确认或拒绝登陆，状态不正确时返回 ErrNotExist
+func (m *QRLoginModel) ConfirmQRLogin(id string, userID primitive.ObjectID, confirm bool) error {
+	state := QRLoginRejected
+	if confirm {
+		state = QRLoginConfirmed
+	}
+	res, err := confirmQRLoginScript.Run(m.Redis, []string{qrLoginKey(id), QRLoginChannel(id)},
+		userID.Hex(), string(state)).Int()
+	if err != nil {
+		return err
+	} else if res == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// FinishQRLogin 使用已确认的扫码登陆记录，返回扫码用户，状态或会话不正确时返回 ErrNotExist
+func (m *QRLoginModel) FinishQRLogin(id, session string) (primitive.ObjectID, error) {
+	user, err := finishQRLoginScript.Run(m.Redis, []string{qrLoginKey(id)}, session).String()
+	if err == redis.Nil {
+		return primitive.NilObjectID, ErrNotExist
+	} else if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return primitive.ObjectIDFromHex(user)
+}
+
+// RemoveQRLogin 删除扫码登陆记录，并通知订阅者二维码已过期
+func (m *QRLoginModel) RemoveQRLogin(id string) error {
+	pipe := m.Redis.TxPipeline()
+	pipe.Del(qrLoginKey(id))
+	pipe.Publish(QRLoginChannel(id), string(QRLoginExpired))
+	_, err := pipe.Exec()
+	return err
+}
+
+// AcquireWatch 增加订阅计数，达到上限时返回 false
+func (m *QRLoginModel) AcquireWatch(key string, limit int64, expire time.Duration) (bool, error) {
+	res, err := acquireWatchScript.Run(m.Redis, []string{qrWatchKey(key)}, limit, int64(expire/time.Second)).Int()
+	return res == 1, err
+}
+
+// ReleaseWatch 减少订阅计数
+func (m *QRLoginModel) ReleaseWatch(key string) error {
+	return releaseWatchScript.Run(m.Redis, []string{qrWatchKey(key)}).Err()
+}
+
+// Subscribe 订阅扫码登陆状态变化
+func (m *QRLoginModel) Subscribe(id string) *redis.PubSub {
+	return m.Redis.Subscribe(QRLoginChannel(id))
+}
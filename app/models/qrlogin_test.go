@@ -0,0 +1,116 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestQRLogin(t *testing.T) {
+	t.Run("InitRedis", testInitRedis)
+	t.Run("QRLogin", testQRLogin)
+	t.Run("RemoveQRLogin", testRemoveQRLogin)
+	t.Run("QRLoginWatch", testQRLoginWatch)
+	t.Run("DisconnectRedis", testDisconnectRedis)
+}
+
+func testQRLogin(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+	userID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+	err := redisInst.QRLogin.AddQRLogin(QRLogin{
+		ID:      id,
+		Session: "pc-session",
+		IP:      "127.0.0.1",
+		Device:  "Chrome (Windows)",
+		Time:    time.Now().Unix(),
+	}, time.Minute)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// 未扫码不能确认
+	if err = redisInst.QRLogin.ConfirmQRLogin(id, userID, true); err != ErrNotExist {
+		t.Error("confirm before scan")
+	}
+	if err = redisInst.QRLogin.ScanQRLogin(id, userID); err != nil {
+		t.Error(err)
+	}
+	if err = redisInst.QRLogin.ScanQRLogin(id, otherID); err != ErrNotExist {
+		t.Error("scan by other user")
+	}
+	login, err := redisInst.QRLogin.GetQRLogin(id)
+	if err != nil || login.State != QRLoginScanned || login.User != userID || login.IP != "127.0.0.1" {
+		t.Error("wrong qr login", login, err)
+	}
+	if err = redisInst.QRLogin.ConfirmQRLogin(id, otherID, true); err != ErrNotExist {
+		t.Error("confirm by other user")
+	}
+	if err = redisInst.QRLogin.ConfirmQRLogin(id, userID, true); err != nil {
+		t.Error(err)
+	}
+
+	// 只能由创建二维码的会话使用一次
+	if _, err = redisInst.QRLogin.FinishQRLogin(id, "other-session"); err != ErrNotExist {
+		t.Error("finish by other session")
+	}
+	user, err := redisInst.QRLogin.FinishQRLogin(id, "pc-session")
+	if err != nil || user != userID {
+		t.Error("wrong user", user, err)
+	}
+	if _, err = redisInst.QRLogin.FinishQRLogin(id, "pc-session"); err != ErrNotExist {
+		t.Error("finish twice")
+	}
+}
+
+func testRemoveQRLogin(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+	err := redisInst.QRLogin.AddQRLogin(QRLogin{ID: id, Session: "pc-session", Time: time.Now().Unix()}, time.Minute)
+	if err != nil {
+		t.Error(err)
+	}
+	sub := redisInst.QRLogin.Subscribe(id)
+	defer sub.Close()
+	if _, err = sub.Receive(); err != nil {
+		t.Error(err)
+	}
+	// 作废二维码时通知订阅者
+	if err = redisInst.QRLogin.RemoveQRLogin(id); err != nil {
+		t.Error(err)
+	}
+	msg, err := sub.ReceiveMessage()
+	if err != nil || msg.Payload != string(QRLoginExpired) {
+		t.Error("wrong message", msg, err)
+	}
+	if _, err = redisInst.QRLogin.GetQRLogin(id); err != ErrNotExist {
+		t.Error("qr login remains")
+	}
+}
+
+func testQRLoginWatch(t *testing.T) {
+	ip := primitive.NewObjectID().Hex()
+	for i := 0; i < 3; i++ {
+		ok, err := redisInst.QRLogin.AcquireWatch(ip, 2, time.Minute)
+		if err != nil || ok != (i < 2) {
+			t.Error("wrong watch limit", i, ok, err)
+		}
+	}
+	if err := redisInst.QRLogin.ReleaseWatch(ip); err != nil {
+		t.Error(err)
+	}
+	if ok, err := redisInst.QRLogin.AcquireWatch(ip, 2, time.Minute); err != nil || !ok {
+		t.Error("watch not released", err)
+	}
+	// 计数过期后释放不会变为负数
+	if err := redisInst.QRLogin.Redis.Del(qrWatchKey(ip)).Err(); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := redisInst.QRLogin.ReleaseWatch(ip); err != nil {
+			t.Error(err)
+		}
+	}
+	if count, err := redisInst.QRLogin.Redis.Exists(qrWatchKey(ip)).Result(); err != nil || count != 0 {
+		t.Error("watch counter remains", count, err)
+	}
+}
@@ -228,3 +228,24 @@ func (model *QuestionnaireModel) GetAnswerByUserID(id, userID primitive.ObjectID
 	}
 	return res.Data[0], nil
 }
+
+// GetAnswersByUser 获取用户填写过的问卷及其答案（Data 仅包含该用户的答案）
+func (model *QuestionnaireModel) GetAnswersByUser(userID primitive.ObjectID) (res []QuestionnaireSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cur, err := model.Collection.Find(ctx, bson.M{"data.user_id": userID},
+		options.Find().SetProjection(bson.M{"title": 1, "problems": 1, "data.$": 1}))
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	res = []QuestionnaireSchema{}
+	for cur.Next(ctx) {
+		questionnaire := QuestionnaireSchema{}
+		if err = cur.Decode(&questionnaire); err != nil {
+			return
+		}
+		res = append(res, questionnaire)
+	}
+	return
+}
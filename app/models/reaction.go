@@ -0,0 +1,295 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReactionModel 点赞/收藏数据库
+type ReactionModel struct {
+	Collection *mongo.Collection
+}
+
+// ReactionType 点赞/收藏类型
+type ReactionType string
+
+// ReactionType 点赞/收藏类型
+const (
+	ReactionLikeTask    ReactionType = "like_task"    // 点赞任务
+	ReactionLikeComment ReactionType = "like_comment" // 点赞评论
+	ReactionCollectTask ReactionType = "collect_task" // 收藏任务
+)
+
+// ReactionSchema 点赞/收藏记录
+type ReactionSchema struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"` // 记录 ID
+	User   primitive.ObjectID `bson:"user" json:"-"`          // 用户 [索引]
+	Type   ReactionType       `bson:"type"`                   // 类型
+	Target primitive.ObjectID `bson:"target"`                 // 任务或评论 ID [索引]
+	Folder primitive.ObjectID `bson:"folder,omitempty"`       // 收藏夹，为空时在默认收藏夹
+	Time   int64              `bson:"time"`                   // 点赞/收藏时间
+}
+
+// FolderFilter 收藏夹筛选，Default 为 true 时只获取默认收藏夹中的收藏
+type FolderFilter struct {
+	Default bool
+	Folder  primitive.ObjectID
+}
+
+// AddReaction 添加点赞/收藏，已存在时返回 ErrExist
+func (m *ReactionModel) AddReaction(userID primitive.ObjectID, reactionType ReactionType, targetID, folder primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	insert := bson.M{"time": time.Now().Unix()}
+	if !folder.IsZero() {
+		insert["folder"] = folder
+	}
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"user": userID, "type": reactionType, "target": targetID},
+		bson.M{"$setOnInsert": insert}, options.Update().SetUpsert(true))
+	if isDuplicateKey(err) { // 并发点赞/收藏时由唯一索引保证只添加一次
+		return ErrExist
+	} else if err != nil {
+		return err
+	} else if res.UpsertedCount == 0 {
+		return ErrExist
+	}
+	return nil
+}
+
+// RemoveReaction 取消点赞/收藏
+func (m *ReactionModel) RemoveReaction(userID primitive.ObjectID, reactionType ReactionType, targetID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.DeleteOne(ctx, bson.M{"user": userID, "type": reactionType, "target": targetID})
+	if err != nil {
+		return err
+	} else if res.DeletedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// HasReaction 用户是否已点赞/收藏
+func (m *ReactionModel) HasReaction(userID primitive.ObjectID, reactionType ReactionType, targetID primitive.ObjectID) bool {
+	ctx, over := GetCtx()
+	defer over()
+	count, err := m.Collection.CountDocuments(ctx, bson.M{"user": userID, "type": reactionType, "target": targetID},
+		options.Count().SetLimit(1))
+	return err == nil && count > 0
+}
+
+// SetReactionFolder 将收藏移动到收藏夹，folder 为空时移回默认收藏夹
+func (m *ReactionModel) SetReactionFolder(userID, targetID, folder primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	update := bson.M{"$set": bson.M{"folder": folder}}
+	if folder.IsZero() {
+		update = bson.M{"$unset": bson.M{"folder": ""}}
+	}
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"user": userID, "type": ReactionCollectTask, "target": targetID}, update)
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// ClearFolder 将收藏夹中的收藏全部移回默认收藏夹
+func (m *ReactionModel) ClearFolder(userID, folder primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"user": userID, "type": ReactionCollectTask, "folder": folder},
+		bson.M{"$unset": bson.M{"folder": ""}})
+	return err
+}
+
+// GetCollectedTasks 按收藏时间倒序分页获取用户收藏的任务，并按任务类型、状态、酬劳类型筛选
+func (m *ReactionModel) GetCollectedTasks(userID primitive.ObjectID, folder *FolderFilter, taskTypes []TaskType,
+	statuses []TaskStatus, rewards []RewardType, skip, limit int64) (tasks []TaskSchema, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	match := bson.M{"user": userID, "type": ReactionCollectTask}
+	if folder != nil && folder.Default {
+		match["folder"] = bson.M{"$exists": false}
+	} else if folder != nil {
+		match["folder"] = folder.Folder
+	}
+	cursor, err := m.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{"$sort": bson.M{"time": -1}},
+		bson.M{"$lookup": bson.M{
+			"from":         GetModel().Task.Collection.Name(),
+			"localField":   "target",
+			"foreignField": "_id",
+			"as":           "task",
+		}},
+		bson.M{"$unwind": "$task"},
+		bson.M{"$match": bson.M{
+			"task.type":      bson.M{"$in": taskTypes},
+			"task.status":    bson.M{"$in": statuses},
+			"task.reward":    bson.M{"$in": rewards},
+			"task.is_hidden": bson.M{"$ne": true},
+		}},
+		bson.M{"$facet": bson.M{
+			"data":  bson.A{bson.M{"$skip": skip}, bson.M{"$limit": limit}, bson.M{"$replaceRoot": bson.M{"newRoot": "$task"}}},
+			"count": bson.A{bson.M{"$count": "count"}},
+		}},
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	tasks = []TaskSchema{}
+	if cursor.Next(ctx) {
+		result := struct {
+			Data  []TaskSchema `bson:"data"`
+			Count []struct {
+				Count int64 `bson:"count"`
+			} `bson:"count"`
+		}{}
+		if err = cursor.Decode(&result); err != nil {
+			return
+		}
+		if result.Data != nil {
+			tasks = result.Data
+		}
+		if len(result.Count) > 0 {
+			count = result.Count[0].Count
+		}
+	}
+	return
+}
+
+// MergeReactions 将用户 from 的点赞/收藏合并到用户 to
+func (m *ReactionModel) MergeReactions(from, to primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"user": from})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		reaction := ReactionSchema{}
+		if err = cursor.Decode(&reaction); err != nil {
+			return err
+		}
+		insert := bson.M{}
+		if !reaction.Folder.IsZero() {
+			insert["folder"] = reaction.Folder
+		}
+		update := bson.M{"$min": bson.M{"time": reaction.Time}}
+		if len(insert) > 0 {
+			update["$setOnInsert"] = insert
+		}
+		if _, err = m.Collection.UpdateOne(ctx, bson.M{"user": to, "type": reaction.Type, "target": reaction.Target},
+			update, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	_, err = m.Collection.DeleteMany(ctx, bson.M{"user": from})
+	return err
+}
+
+// DedupeReactions 删除重复的点赞/收藏(只保留最早的一条)，并重新统计相关任务和评论的点赞数、收藏数
+func (m *ReactionModel) DedupeReactions(ctx context.Context, tasks *TaskModel, comments *CommentModel) error {
+	cursor, err := m.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$sort": bson.M{"time": 1}},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"user": "$user", "type": "$type", "target": "$target"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	related := map[ReactionSchema]bool{}
+	for cursor.Next(ctx) {
+		group := struct {
+			ID  ReactionSchema       `bson:"_id"`
+			IDs []primitive.ObjectID `bson:"ids"`
+		}{}
+		if err = cursor.Decode(&group); err != nil {
+			return err
+		}
+		if _, err = m.Collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}}); err != nil {
+			return err
+		}
+		related[ReactionSchema{Type: group.ID.Type, Target: group.ID.Target}] = true
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	for reaction := range related {
+		count, err := m.Collection.CountDocuments(ctx, bson.M{"type": reaction.Type, "target": reaction.Target})
+		if err != nil {
+			return err
+		}
+		collection, field := tasks.Collection, "like_count"
+		switch reaction.Type {
+		case ReactionCollectTask:
+			field = "collect_count"
+		case ReactionLikeComment:
+			collection = comments.Collection
+		}
+		if _, err = collection.UpdateOne(ctx, bson.M{"_id": reaction.Target},
+			bson.M{"$set": bson.M{field: count}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateReactionSets 将集合数据库中的点赞/收藏数组迁移为点赞/收藏记录
+func (m *ReactionModel) MigrateReactionSets(ctx context.Context, sets *SetModel) error {
+	filter := bson.M{"$or": bson.A{
+		bson.M{string(SetOfLikeTask): bson.M{"$exists": true}},
+		bson.M{string(SetOfLikeComment): bson.M{"$exists": true}},
+		bson.M{string(SetOfCollectTask): bson.M{"$exists": true}},
+	}}
+	cursor, err := sets.Collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	now := time.Now().Unix()
+	for cursor.Next(ctx) {
+		set := SetSchemas{}
+		if err = cursor.Decode(&set); err != nil {
+			return err
+		}
+		for reactionType, ids := range map[ReactionType][]primitive.ObjectID{
+			ReactionLikeTask:    set.LikeTaskID,
+			ReactionLikeComment: set.LikeCommentID,
+			ReactionCollectTask: set.CollectTaskID,
+		} {
+			for _, id := range ids {
+				if _, err = m.Collection.UpdateOne(ctx, bson.M{"user": set.UserID, "type": reactionType, "target": id},
+					bson.M{"$setOnInsert": bson.M{"time": now}}, options.Update().SetUpsert(true)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	_, err = sets.Collection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{
+		string(SetOfLikeTask):    "",
+		string(SetOfLikeComment): "",
+		string(SetOfCollectTask): "",
+	}})
+	return err
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReactionModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testReaction", testReaction)
+	t.Run("testCollectFolder", testCollectFolder)
+	t.Run("testConcurrentReaction", testConcurrentReaction)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	if err := model.Reaction.Collection.Drop(ctx); err != nil {
+		t.Error(err)
+	}
+	if err := model.Folder.Collection.Drop(ctx); err != nil {
+		t.Error(err)
+	}
+	if err := model.Task.Collection.Drop(ctx); err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testReaction(t *testing.T) {
+	userID := primitive.NewObjectID()
+	taskID := primitive.NewObjectID()
+
+	if model.Reaction.HasReaction(userID, ReactionLikeTask, taskID) {
+		t.Error("like before add")
+	}
+	err := model.Reaction.AddReaction(userID, ReactionLikeTask, taskID, primitive.NilObjectID)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = model.Reaction.AddReaction(userID, ReactionLikeTask, taskID, primitive.NilObjectID); err != ErrExist {
+		t.Error("like twice")
+	}
+	if !model.Reaction.HasReaction(userID, ReactionLikeTask, taskID) {
+		t.Error("like not found")
+	}
+	// 点赞与收藏互不影响
+	if model.Reaction.HasReaction(userID, ReactionCollectTask, taskID) {
+		t.Error("wrong reaction type")
+	}
+	if err = model.Reaction.RemoveReaction(userID, ReactionLikeTask, taskID); err != nil {
+		t.Error(err)
+	}
+	if err = model.Reaction.RemoveReaction(userID, ReactionLikeTask, taskID); err != ErrNotExist {
+		t.Error("remove twice")
+	}
+}
+
+func testCollectFolder(t *testing.T) {
+	userID := primitive.NewObjectID()
+	folderID, err := model.Folder.AddFolder(userID, "学习")
+	if err != nil {
+		t.Error(err)
+	}
+
+	var taskIDs []primitive.ObjectID
+	for i := 0; i < 3; i++ {
+		taskID, err := model.Task.AddTask(primitive.NewObjectID(), userID, TaskStatusWait)
+		if err != nil {
+			t.Error(err)
+		}
+		if err = model.Task.SetTaskInfoByID(taskID, TaskSchema{Type: TaskTypeInfo, Reward: RewardMoney}); err != nil {
+			t.Error(err)
+		}
+		taskIDs = append(taskIDs, taskID)
+	}
+	types := []TaskType{TaskTypeInfo, TaskTypeRunning, TaskTypeQuestionnaire}
+	statuses := []TaskStatus{TaskStatusWait}
+	rewards := []RewardType{RewardMoney, RewardObject, RewardRMB}
+
+	for i, taskID := range taskIDs {
+		folder := primitive.NilObjectID
+		if i == 0 {
+			folder = folderID
+		}
+		if err = model.Reaction.AddReaction(userID, ReactionCollectTask, taskID, folder); err != nil {
+			t.Error(err)
+		}
+	}
+
+	tasks, count, err := model.Reaction.GetCollectedTasks(userID, nil, types, statuses, rewards, 0, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 3 || len(tasks) != 2 {
+		t.Error("wrong collections", count, len(tasks))
+	}
+	_, count, err = model.Reaction.GetCollectedTasks(userID, &FolderFilter{Folder: folderID}, types, statuses, rewards, 0, 10)
+	if err != nil || count != 1 {
+		t.Error("wrong folder collections", count, err)
+	}
+
+	// 删除收藏夹后收藏移回默认收藏夹
+	if err = model.Folder.RemoveFolder(userID, folderID); err != nil {
+		t.Error(err)
+	}
+	if err = model.Reaction.ClearFolder(userID, folderID); err != nil {
+		t.Error(err)
+	}
+	_, count, err = model.Reaction.GetCollectedTasks(userID, &FolderFilter{Default: true}, types, statuses, rewards, 0, 10)
+	if err != nil || count != 3 {
+		t.Error("wrong default collections", count, err)
+	}
+}
+
+func testConcurrentReaction(t *testing.T) {
+	userID, taskID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	// 并发点赞只能成功一次
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- model.Reaction.AddReaction(userID, ReactionLikeTask, taskID, primitive.NilObjectID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	success := 0
+	for err := range errs {
+		if err == nil {
+			success++
+		} else if err != ErrExist {
+			t.Error(err)
+		}
+	}
+	if success != 1 {
+		t.Error("wrong concurrent reaction", success)
+	}
+}
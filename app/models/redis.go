@@ -13,8 +13,10 @@ var redisInst *Redis
 
 // Redis 缓存
 type Redis struct {
-	Client *redis.Client
-	Cache  *CacheModel
+	Client  *redis.Client
+	Cache   *CacheModel
+	Session *SessionModel
+	QRLogin *QRLoginModel
 }
 
 // GetRedis 获取缓存实例
@@ -41,6 +43,8 @@ func InitRedis(config *utils.RedisConfig) error {
 	}
 	log.Info().Msg("Successful connection to Redis.")
 	redisInst.Cache = &CacheModel{Redis: redisInst.Client}
+	redisInst.Session = &SessionModel{Redis: redisInst.Client}
+	redisInst.QRLogin = &QRLoginModel{Redis: redisInst.Client}
 
 	return nil
 }
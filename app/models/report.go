@@ -0,0 +1,209 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReportModel 举报数据库
+type ReportModel struct {
+	Collection *mongo.Collection
+}
+
+// ReportTargetType 举报内容类型
+type ReportTargetType string
+
+// ReportReason 举报原因
+type ReportReason string
+
+// ReportStatus 举报处理状态
+type ReportStatus string
+
+// ReportAction 举报处理操作
+type ReportAction string
+
+// ReportTargetType 举报内容类型
+const (
+	ReportTargetTask    ReportTargetType = "task"    // 任务
+	ReportTargetComment ReportTargetType = "comment" // 评论
+	ReportTargetChat    ReportTargetType = "chat"    // 聊天消息
+	ReportTargetUser    ReportTargetType = "user"    // 用户资料
+)
+
+// ReportReason 举报原因
+const (
+	ReportReasonSpam    ReportReason = "spam"    // 垃圾广告
+	ReportReasonAbuse   ReportReason = "abuse"   // 辱骂攻击
+	ReportReasonPorn    ReportReason = "porn"    // 色情低俗
+	ReportReasonFraud   ReportReason = "fraud"   // 诈骗
+	ReportReasonIllegal ReportReason = "illegal" // 违法违规
+	ReportReasonOther   ReportReason = "other"   // 其他
+	// 系统检测
+	ReportReasonSensitive ReportReason = "sensitive" // 命中敏感词
+)
+
+// ReportStatus 举报处理状态
+const (
+	ReportStatusWait   ReportStatus = "wait"   // 待处理
+	ReportStatusDone   ReportStatus = "done"   // 举报成立，已处理
+	ReportStatusReject ReportStatus = "reject" // 举报不成立
+)
+
+// ReportAction 举报处理操作
+const (
+	ReportActionNone  ReportAction = "none"  // 不处理
+	ReportActionHide  ReportAction = "hide"  // 隐藏内容
+	ReportActionClose ReportAction = "close" // 关闭任务
+	ReportActionWarn  ReportAction = "warn"  // 警告用户
+	ReportActionBan   ReportAction = "ban"   // 封禁用户
+)
+
+// ReportSchema 举报数据结构
+type ReportSchema struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`    // 举报 ID
+	Reporter    primitive.ObjectID `bson:"reporter" json:"-"`          // 举报人 (系统检测为空) [索引]
+	TargetType  ReportTargetType   `bson:"target_type"`                // 举报内容类型
+	TargetID    primitive.ObjectID `bson:"target_id" json:"target_id"` // 举报内容 ID (聊天消息为会话 ID) [索引]
+	TargetOwner primitive.ObjectID `bson:"target_owner" json:"-"`      // 被举报内容所属用户
+	MessageTime int64              `bson:"message_time,omitempty"`     // 被举报聊天消息的发送时间
+	Snapshot    string             `bson:"snapshot"`                   // 举报时的内容快照
+	Reason      ReportReason       `bson:"reason"`                     // 举报原因
+	Content     string             `bson:"content"`                    // 举报说明
+	Status      ReportStatus       `bson:"status"`                     // 处理状态
+	Action      ReportAction       `bson:"action"`                     // 处理操作
+	Handler     primitive.ObjectID `bson:"handler,omitempty" json:"-"` // 处理人
+	Feedback    string             `bson:"feedback"`                   // 处理反馈
+	Time        int64              `bson:"time"`                       // 举报时间
+	HandleTime  int64              `bson:"handle_time,omitempty"`      // 处理时间
+}
+
+// AddReport 添加举报
+func (m *ReportModel) AddReport(report ReportSchema) (primitive.ObjectID, error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	report.ID = primitive.NewObjectID()
+	report.Status = ReportStatusWait
+	report.Action = ReportActionNone
+	report.Time = time.Now().Unix()
+	_, err := m.Collection.InsertOne(ctx, report)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return report.ID, nil
+}
+
+// GetReportByID 获取指定举报
+func (m *ReportModel) GetReportByID(id primitive.ObjectID) (report ReportSchema, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	err = m.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&report)
+	return
+}
+
+// ExistWaitReport 用户是否已举报该内容且未处理
+func (m *ReportModel) ExistWaitReport(reporter, targetID primitive.ObjectID) bool {
+	ctx, finish := GetCtx()
+	defer finish()
+	count, err := m.Collection.CountDocuments(ctx, bson.M{
+		"reporter":  reporter,
+		"target_id": targetID,
+		"status":    ReportStatusWait,
+	})
+	return err == nil && count > 0
+}
+
+// GetReports 分页获取举报列表（按时间先后排列）
+func (m *ReportModel) GetReports(status []ReportStatus, types []ReportTargetType, skip, limit int64) (reports []ReportSchema, count int64, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	filter := bson.M{
+		"status":      bson.M{"$in": status},
+		"target_type": bson.M{"$in": types},
+	}
+	count, err = m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return
+	}
+	cur, err := m.Collection.Find(ctx, filter,
+		options.Find().SetSort(bson.M{"time": 1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		report := ReportSchema{}
+		err = cur.Decode(&report)
+		if err != nil {
+			return
+		}
+		reports = append(reports, report)
+	}
+	err = cur.Err()
+	return
+}
+
+// GetWaitReportsByTarget 获取同一内容所有待处理的举报
+func (m *ReportModel) GetWaitReportsByTarget(targetID primitive.ObjectID) (reports []ReportSchema, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	cur, err := m.Collection.Find(ctx, bson.M{"target_id": targetID, "status": ReportStatusWait})
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		report := ReportSchema{}
+		err = cur.Decode(&report)
+		if err != nil {
+			return
+		}
+		reports = append(reports, report)
+	}
+	err = cur.Err()
+	return
+}
+
+// SetReportResult 设置举报处理结果，只有待处理的举报可以设置，否则返回 ErrNotExist
+func (m *ReportModel) SetReportResult(id, handler primitive.ObjectID, status ReportStatus, action ReportAction, feedback string) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": ReportStatusWait},
+		bson.M{"$set": bson.M{
+			"status":      status,
+			"action":      action,
+			"handler":     handler,
+			"feedback":    feedback,
+			"handle_time": time.Now().Unix(),
+		}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// SetReportsAction 修改已处理举报的处理操作
+func (m *ReportModel) SetReportsAction(ids []primitive.ObjectID, action ReportAction) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"action": action}})
+	return err
+}
+
+// TransferUser 将用户相关的举报记录转移到另一用户
+func (m *ReportModel) TransferUser(from, to primitive.ObjectID) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	for _, field := range []string{"reporter", "target_owner", "handler"} {
+		if _, err := m.Collection.UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReportModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testReport", testReport)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.Report.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testReport(t *testing.T) {
+	reporter := primitive.NewObjectID()
+	targetID := primitive.NewObjectID()
+	id, err := model.Report.AddReport(ReportSchema{
+		Reporter:    reporter,
+		TargetType:  ReportTargetComment,
+		TargetID:    targetID,
+		TargetOwner: primitive.NewObjectID(),
+		Reason:      ReportReasonSpam,
+		Snapshot:    "Hello, world",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !model.Report.ExistWaitReport(reporter, targetID) {
+		t.Error("report not exist")
+	}
+
+	reports, count, err := model.Report.GetReports([]ReportStatus{ReportStatusWait}, []ReportTargetType{ReportTargetComment}, 0, 10)
+	if err != nil {
+		t.Error(err)
+	} else if count != 1 || len(reports) != 1 {
+		t.Error(reports)
+	}
+	t.Log(reports)
+
+	err = model.Report.SetReportResult(id, primitive.NewObjectID(), ReportStatusDone, ReportActionWarn, "ok")
+	if err != nil {
+		t.Error(err)
+	}
+	err = model.Report.SetReportResult(id, primitive.NewObjectID(), ReportStatusReject, ReportActionNone, "")
+	if err != ErrNotExist {
+		t.Error(err)
+	}
+
+	if err = model.Report.SetReportsAction([]primitive.ObjectID{id}, ReportActionHide); err != nil {
+		t.Error(err)
+	}
+
+	reports, err = model.Report.GetWaitReportsByTarget(targetID)
+	if err != nil {
+		t.Error(err)
+	} else if len(reports) != 0 {
+		t.Error(reports)
+	}
+
+	report, err := model.Report.GetReportByID(id)
+	if err != nil {
+		t.Error(err)
+	} else if report.Status != ReportStatusDone || report.Action != ReportActionHide {
+		t.Error(report)
+	}
+	t.Log(report)
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"context"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recentReviewCount 保留的最近评价数量
+const recentReviewCount = 5
+
+// ReputationKind 口碑类型
+type ReputationKind string
+
+// ReputationKind 口碑类型
+const (
+	ReputationPlayer    ReputationKind = "player"    // 作为参与者，由发布者评价完成度
+	ReputationPublisher ReputationKind = "publisher" // 作为发布者，由参与者评价星级
+)
+
+// ReputationSchema 用户口碑
+type ReputationSchema struct {
+	Player    RatingSchema `bson:"player"`    // 作为参与者获得的评价
+	Publisher RatingSchema `bson:"publisher"` // 作为发布者获得的评价
+}
+
+// RatingSchema 评价统计
+type RatingSchema struct {
+	Count   int64            `bson:"count"`            // 评价数
+	Total   int64            `bson:"total"`            // 评分总和
+	Average float64          `bson:"average"`          // 平均评分(冗余数据，用于排序) [索引]
+	Levels  map[string]int64 `bson:"levels,omitempty"` // 各评分的评价数
+	Recent  []ReviewSchema   `bson:"recent,omitempty"` // 最近的评价
+}
+
+// newRatingSchema 初始化评价统计，避免 levels、recent 存储为 null 后无法 $inc/$push
+func newRatingSchema() RatingSchema {
+	return RatingSchema{
+		Levels: map[string]int64{},
+		Recent: []ReviewSchema{},
+	}
+}
+
+// ReviewSchema 评价
+type ReviewSchema struct {
+	Task    primitive.ObjectID `bson:"task" json:"task_id"` // 任务 ID
+	User    primitive.ObjectID `bson:"user" json:"-"`       // 评价人
+	Rating  int                `bson:"rating"`              // 评分
+	Content string             `bson:"content"`             // 评语
+	Time    int64              `bson:"time"`                // 评价时间
+}
+
+// UpdateReputation 增量更新用户口碑
+// oldRating 为 0 表示首次评价，同一任务同一评价人的评价会被替换
+func (m *UserModel) UpdateReputation(id primitive.ObjectID, kind ReputationKind, oldRating int, review ReviewSchema) error {
+	ctx, over := GetCtx()
+	defer over()
+	prefix := "reputation." + string(kind) + "."
+	inc := bson.M{}
+	if oldRating != review.Rating {
+		inc[prefix+"total"] = review.Rating - oldRating
+		inc[prefix+"levels."+strconv.Itoa(review.Rating)] = 1
+		if oldRating == 0 {
+			inc[prefix+"count"] = 1
+		} else {
+			inc[prefix+"levels."+strconv.Itoa(oldRating)] = -1
+		}
+	}
+	update := bson.M{"$pull": bson.M{prefix + "recent": bson.M{"task": review.Task, "user": review.User}}}
+	if len(inc) > 0 {
+		update["$inc"] = inc
+	}
+	user := UserSchema{}
+	err := m.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"reputation": 1})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotExist
+	} else if err != nil {
+		return err
+	}
+	rating := user.Reputation.Player
+	if kind == ReputationPublisher {
+		rating = user.Reputation.Publisher
+	}
+	_, err = m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{prefix + "average": ratingAverage(rating)},
+		"$push": bson.M{
+			prefix + "recent": bson.M{
+				"$each":  []ReviewSchema{review},
+				"$sort":  bson.M{"time": -1},
+				"$slice": recentReviewCount,
+			},
+		}})
+	return err
+}
+
+// updateRatingAverage 根据评价数和评分总和重新计算平均评分
+func (m *UserModel) updateRatingAverage(ctx context.Context, id primitive.ObjectID) error {
+	user := UserSchema{}
+	if err := m.Collection.FindOne(ctx, bson.M{"_id": id},
+		options.FindOne().SetProjection(bson.M{"reputation": 1})).Decode(&user); err != nil {
+		return err
+	}
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"reputation.player.average":    ratingAverage(user.Reputation.Player),
+		"reputation.publisher.average": ratingAverage(user.Reputation.Publisher),
+	}})
+	return err
+}
+
+// MigrateRatingAverage 为已有评价但缺少平均评分的用户补全平均评分
+func (m *UserModel) MigrateRatingAverage(ctx context.Context) error {
+	cur, err := m.Collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"reputation.player.count": bson.M{"$gt": 0}, "reputation.player.average": bson.M{"$exists": false}},
+		bson.M{"reputation.publisher.count": bson.M{"$gt": 0}, "reputation.publisher.average": bson.M{"$exists": false}},
+	}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := UserSchema{}
+		if err = cur.Decode(&user); err != nil {
+			return err
+		}
+		if err = m.updateRatingAverage(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// MigrateRatingNull 将旧版存储为 null 的 levels、recent 修正为空值，使其可以 $inc/$push
+func (m *UserModel) MigrateRatingNull(ctx context.Context) error {
+	for _, kind := range []ReputationKind{ReputationPlayer, ReputationPublisher} {
+		for field, value := range map[string]interface{}{
+			"levels": bson.M{},
+			"recent": bson.A{},
+		} {
+			key := "reputation." + string(kind) + "." + field
+			if _, err := m.Collection.UpdateMany(ctx, bson.M{key: bson.M{"$type": "null"}},
+				bson.M{"$set": bson.M{key: value}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ratingAverage 计算平均评分，没有评价时为 0
+func ratingAverage(rating RatingSchema) float64 {
+	if rating.Count == 0 {
+		return 0
+	}
+	return float64(rating.Total) / float64(rating.Count)
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RoleModel 角色数据库
+type RoleModel struct {
+	Collection *mongo.Collection
+}
+
+// Permission 管理权限
+type Permission string
+
+// Permission 管理权限
+const (
+	PermissionManageArticles      Permission = "manage_articles"      // 发布、修改公告
+	PermissionReviewCertification Permission = "review_certification" // 审核认证、管理自动认证后缀
+	PermissionModerateContent     Permission = "moderate_content"     // 处理举报、封禁、敏感词、删除违规内容
+	PermissionSendSystemMessage   Permission = "send_system_message"  // 发送系统消息
+	PermissionViewAllLogs         Permission = "view_all_logs"        // 查看所有类型及他人的日志
+	PermissionManageUsers         Permission = "manage_users"         // 修改用户类型
+	PermissionManageCampus        Permission = "manage_campus"        // 管理校区及校区管理员
+)
+
+// AllPermissions 全部权限，管理员与超级管理员默认拥有
+var AllPermissions = []Permission{
+	PermissionManageArticles,
+	PermissionReviewCertification,
+	PermissionModerateContent,
+	PermissionSendSystemMessage,
+	PermissionViewAllLogs,
+	PermissionManageUsers,
+	PermissionManageCampus,
+}
+
+// IsPermission 是否为合法的权限
+func IsPermission(permission Permission) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleSchema 角色数据结构
+type RoleSchema struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name"`        // 角色名称
+	Description string             `bson:"description"` // 角色说明
+	Permissions []Permission       `bson:"permissions"` // 拥有的权限
+	Creator     primitive.ObjectID `bson:"creator" json:"-"`
+	Time        int64              `bson:"time"` // 创建时间
+}
+
+// AddRole 添加角色
+func (m *RoleModel) AddRole(role RoleSchema) (primitive.ObjectID, error) {
+	ctx, over := GetCtx()
+	defer over()
+	role.ID = primitive.NewObjectID()
+	role.Time = time.Now().Unix()
+	if role.Permissions == nil {
+		role.Permissions = []Permission{}
+	}
+	_, err := m.Collection.InsertOne(ctx, role)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return role.ID, nil
+}
+
+// GetRoleByID 获取角色
+func (m *RoleModel) GetRoleByID(id primitive.ObjectID) (role RoleSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	err = m.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&role)
+	return
+}
+
+// GetRoles 获取全部角色
+func (m *RoleModel) GetRoles() (roles []RoleSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"time": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	roles = []RoleSchema{}
+	for cursor.Next(ctx) {
+		role := RoleSchema{}
+		if err = cursor.Decode(&role); err != nil {
+			return
+		}
+		roles = append(roles, role)
+	}
+	return
+}
+
+// SetRole 修改角色名称、说明及权限
+func (m *RoleModel) SetRole(id primitive.ObjectID, name, description string, permissions []Permission) error {
+	ctx, over := GetCtx()
+	defer over()
+	if permissions == nil {
+		permissions = []Permission{}
+	}
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"name":        name,
+		"description": description,
+		"permissions": permissions,
+	}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// RemoveRole 删除角色
+func (m *RoleModel) RemoveRole(id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	} else if res.DeletedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// GetPermissionsByRoles 获取多个角色拥有的权限（去重）
+func (m *RoleModel) GetPermissionsByRoles(ids []primitive.ObjectID) (permissions []Permission, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	permissions = []Permission{}
+	if len(ids) == 0 {
+		return
+	}
+	cursor, err := m.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"permissions": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	exist := map[Permission]bool{}
+	for cursor.Next(ctx) {
+		role := RoleSchema{}
+		if err = cursor.Decode(&role); err != nil {
+			return
+		}
+		for _, p := range role.Permissions {
+			if !exist[p] {
+				exist[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return
+}
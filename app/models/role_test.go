@@ -0,0 +1,95 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestRoleModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("InitRedis", testInitRedis)
+	t.Run("testRole", testRole)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	if err := model.Role.Collection.Drop(ctx); err != nil {
+		t.Error(err)
+	}
+	if err := model.User.Collection.Drop(ctx); err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectRedis", testDisconnectRedis)
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testRole(t *testing.T) {
+	reviewer, err := model.Role.AddRole(RoleSchema{
+		Name:        "认证审核",
+		Permissions: []Permission{PermissionReviewCertification},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	editor, err := model.Role.AddRole(RoleSchema{
+		Name:        "社团公告",
+		Permissions: []Permission{PermissionManageArticles, PermissionReviewCertification},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	userID, err := model.User.AddUserByViolet("role_test")
+	if err != nil {
+		t.Error(err)
+	}
+	permissions, err := GetRedis().Cache.GetUserPermissions(userID)
+	if err != nil || len(permissions) != 0 {
+		t.Error(permissions, err)
+	}
+
+	if err = model.User.AddUserRole(userID, reviewer); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.AddUserRole(userID, editor); err != nil {
+		t.Error(err)
+	}
+	permissions, err = GetRedis().Cache.GetUserPermissions(userID)
+	if err != nil || len(permissions) != 2 {
+		t.Error(permissions, err)
+	}
+
+	users, err := model.User.GetUsersByRole(editor)
+	if err != nil || len(users) != 1 || users[0] != userID {
+		t.Error(users, err)
+	}
+
+	if err = model.Role.SetRole(editor, "社团公告", "", []Permission{PermissionManageArticles}); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.RemoveUserRole(userID, reviewer); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.RemoveUserRole(userID, reviewer); err != ErrNotExist {
+		t.Error("remove twice", err)
+	}
+	permissions, err = GetRedis().Cache.GetUserPermissions(userID)
+	if err != nil || len(permissions) != 1 || permissions[0] != PermissionManageArticles {
+		t.Error(permissions, err)
+	}
+
+	if err = model.Role.RemoveRole(editor); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.RemoveRoleFromUsers(editor); err != nil {
+		t.Error(err)
+	}
+	user, err := model.User.GetUserByID(userID)
+	if err != nil || len(user.Roles) != 0 {
+		t.Error(user.Roles, err)
+	}
+
+	roles, err := model.Role.GetRoles()
+	if err != nil || len(roles) != 1 {
+		t.Error(roles, err)
+	}
+}
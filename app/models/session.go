@@ -0,0 +1,176 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 登陆会话
+
+// 每次登陆创建一个登陆会话，会话记录保存在 Redis 中
+// 该次登陆的 Cookie 登陆状态、访问令牌和刷新令牌都属于同一个会话
+// 删除会话记录即可注销该次登陆
+
+// SessionModel 登陆会话数据库
+type SessionModel struct {
+	Redis *redis.Client
+}
+
+// LoginSession 登陆会话数据
+type LoginSession struct {
+	ID        string             `json:"id"` // 会话 ID
+	User      primitive.ObjectID `json:"-"`  // 用户 ID
+	Method    string             // 登陆方式
+	Refresh   string             `json:"-"` // 当前有效的刷新令牌 ID，未签发令牌时为空
+	Device    string             // 设备描述
+	UserAgent string             // 登陆时的 User-Agent
+	IP        string             // 最后访问 IP
+	Time      int64              // 登陆时间
+	LastSeen  int64              // 最后访问时间
+}
+
+// sessionKey 会话记录键名
+func sessionKey(id string) string {
+	return "auth-" + id
+}
+
+// userSessionKey 用户会话列表键名
+func userSessionKey(userID primitive.ObjectID) string {
+	return "auth-user-" + userID.Hex()
+}
+
+// rotateRefreshScript 刷新令牌只能使用一次，重复使用时视为令牌泄露，删除整个会话
+var rotateRefreshScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "refresh") == ARGV[1] then
+	redis.call("HSET", KEYS[1], "refresh", ARGV[2])
+	redis.call("EXPIRE", KEYS[1], ARGV[3])
+	redis.call("EXPIRE", KEYS[2], ARGV[3])
+	return 1
+end
+redis.call("DEL", KEYS[1])
+return 0
+`)
+
+// AddSession 添加登陆会话
+func (m *SessionModel) AddSession(session LoginSession, expire time.Duration) error {
+	pipe := m.Redis.TxPipeline()
+	pipe.HMSet(sessionKey(session.ID), map[string]interface{}{
+		"user":       session.User.Hex(),
+		"method":     session.Method,
+		"refresh":    session.Refresh,
+		"device":     session.Device,
+		"user_agent": session.UserAgent,
+		"ip":         session.IP,
+		"time":       session.Time,
+		"last_seen":  session.LastSeen,
+	})
+	pipe.Expire(sessionKey(session.ID), expire)
+	pipe.SAdd(userSessionKey(session.User), session.ID)
+	pipe.Expire(userSessionKey(session.User), expire)
+	_, err := pipe.Exec()
+	return err
+}
+
+// parseSession 解析会话记录
+func parseSession(id string, data map[string]string) (session LoginSession, err error) {
+	if len(data) == 0 {
+		return session, ErrNotExist
+	}
+	session.ID = id
+	if session.User, err = primitive.ObjectIDFromHex(data["user"]); err != nil {
+		return
+	}
+	session.Method = data["method"]
+	session.Refresh = data["refresh"]
+	session.Device = data["device"]
+	session.UserAgent = data["user_agent"]
+	session.IP = data["ip"]
+	session.Time, _ = strconv.ParseInt(data["time"], 10, 64)
+	session.LastSeen, _ = strconv.ParseInt(data["last_seen"], 10, 64)
+	return
+}
+
+// GetSession 获取登陆会话，不存在或已过期时返回 ErrNotExist
+func (m *SessionModel) GetSession(id string) (LoginSession, error) {
+	data, err := m.Redis.HGetAll(sessionKey(id)).Result()
+	if err != nil {
+		return LoginSession{}, err
+	}
+	return parseSession(id, data)
+}
+
+// GetUserSessions 获取用户的全部登陆会话，并清理已过期的会话
+func (m *SessionModel) GetUserSessions(userID primitive.ObjectID) (sessions []LoginSession, err error) {
+	ids, err := m.Redis.SMembers(userSessionKey(userID)).Result()
+	if err != nil {
+		return
+	}
+	pipe := m.Redis.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(sessionKey(id))
+	}
+	if _, err = pipe.Exec(); err != nil && err != redis.Nil {
+		return
+	}
+	sessions = []LoginSession{}
+	var expired []interface{}
+	for i, cmd := range cmds {
+		session, err := parseSession(ids[i], cmd.Val())
+		if err != nil || session.User != userID {
+			expired = append(expired, ids[i])
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		err = m.Redis.SRem(userSessionKey(userID), expired...).Err()
+	}
+	return sessions, err
+}
+
+// TouchSession 更新会话最后访问时间和 IP，并延长会话和用户会话列表的有效期
+func (m *SessionModel) TouchSession(id string, userID primitive.ObjectID, ip string, lastSeen int64, expire time.Duration) error {
+	pipe := m.Redis.TxPipeline()
+	pipe.HMSet(sessionKey(id), map[string]interface{}{
+		"ip":        ip,
+		"last_seen": lastSeen,
+	})
+	pipe.Expire(sessionKey(id), expire)
+	pipe.Expire(userSessionKey(userID), expire)
+	_, err := pipe.Exec()
+	return err
+}
+
+// RotateRefresh 更换会话的刷新令牌并延长有效期，旧令牌不匹配时删除会话并返回 ErrNotExist
+func (m *SessionModel) RotateRefresh(id string, userID primitive.ObjectID, oldRefresh, newRefresh string, expire time.Duration) error {
+	res, err := rotateRefreshScript.Run(m.Redis, []string{sessionKey(id), userSessionKey(userID)},
+		oldRefresh, newRefresh, int64(expire/time.Second)).Int()
+	if err != nil {
+		return err
+	} else if res == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// RemoveSession 删除登陆会话
+func (m *SessionModel) RemoveSession(id string) error {
+	return m.Redis.Del(sessionKey(id)).Err()
+}
+
+// RemoveUserSessions 删除用户的全部登陆会话
+func (m *SessionModel) RemoveUserSessions(userID primitive.ObjectID) error {
+	ids, err := m.Redis.SMembers(userSessionKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userSessionKey(userID)}
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+	return m.Redis.Del(keys...).Err()
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSession(t *testing.T) {
+	t.Run("InitRedis", testInitRedis)
+	t.Run("LoginSession", testLoginSession)
+	t.Run("DisconnectRedis", testDisconnectRedis)
+}
+
+func testLoginSession(t *testing.T) {
+	userID := primitive.NewObjectID()
+	now := time.Now().Unix()
+	for _, id := range []string{"web-" + userID.Hex(), "app-" + userID.Hex()} {
+		err := redisInst.Session.AddSession(LoginSession{
+			ID:       id,
+			User:     userID,
+			Method:   "wechat",
+			Refresh:  "refresh-1",
+			Time:     now,
+			LastSeen: now,
+		}, time.Minute)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	webID, appID := "web-"+userID.Hex(), "app-"+userID.Hex()
+
+	// 刷新令牌只能使用一次
+	if err := redisInst.Session.RotateRefresh(appID, userID, "refresh-1", "refresh-2", time.Hour); err != nil {
+		t.Error(err)
+	}
+	// 会话列表随会话一同续期
+	if ttl, err := redisInst.Session.Redis.TTL(userSessionKey(userID)).Result(); err != nil || ttl <= time.Minute {
+		t.Error("user sessions not extended", ttl, err)
+	}
+	if err := redisInst.Session.RotateRefresh(appID, userID, "refresh-1", "refresh-3", time.Minute); err != ErrNotExist {
+		t.Error("reuse refresh token")
+	}
+	if _, err := redisInst.Session.GetSession(appID); err != ErrNotExist {
+		t.Error("session remains after refresh token reuse")
+	}
+
+	if err := redisInst.Session.TouchSession(webID, userID, "127.0.0.1", now+100, 2*time.Hour); err != nil {
+		t.Error(err)
+	}
+	if ttl, err := redisInst.Session.Redis.TTL(userSessionKey(userID)).Result(); err != nil || ttl <= time.Hour {
+		t.Error("user sessions not extended", ttl, err)
+	}
+	sessions, err := redisInst.Session.GetUserSessions(userID)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != webID || sessions[0].IP != "127.0.0.1" || sessions[0].LastSeen != now+100 {
+		t.Error("wrong sessions", sessions)
+	}
+
+	if err = redisInst.Session.RemoveUserSessions(userID); err != nil {
+		t.Error(err)
+	}
+	if _, err = redisInst.Session.GetSession(webID); err != ErrNotExist {
+		t.Error("session remains after remove")
+	}
+}
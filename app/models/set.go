@@ -15,11 +15,12 @@ type SetModel struct {
 // SetSchemas 集合数据结构
 type SetSchemas struct {
 	UserID          primitive.ObjectID   `bson:"_id"`               // 用户 ID
-	LikeTaskID      []primitive.ObjectID `bson:"like_task_id"`      // 点赞的任务 ID
-	LikeCommentID   []primitive.ObjectID `bson:"like_comment_id"`   // 点赞的评论 ID
-	CollectTaskID   []primitive.ObjectID `bson:"collect_task_id"`   // 收藏的任务 ID
-	FollowingUserID []primitive.ObjectID `bson:"following_user_id"` // 关注用户 ID
-	FollowerUserID  []primitive.ObjectID `bson:"follower_user_id"`  // 粉丝用户 ID
+	LikeTaskID      []primitive.ObjectID `bson:"like_task_id"`      // 点赞的任务 ID (已迁移至点赞/收藏数据库)
+	LikeCommentID   []primitive.ObjectID `bson:"like_comment_id"`   // 点赞的评论 ID (已迁移至点赞/收藏数据库)
+	CollectTaskID   []primitive.ObjectID `bson:"collect_task_id"`   // 收藏的任务 ID (已迁移至点赞/收藏数据库)
+	FollowingUserID []primitive.ObjectID `bson:"following_user_id"` // 关注用户 ID (已迁移至关注关系数据库)
+	FollowerUserID  []primitive.ObjectID `bson:"follower_user_id"`  // 粉丝用户 ID (已迁移至关注关系数据库)
+	BlockUserID     []primitive.ObjectID `bson:"block_user_id"`     // 屏蔽用户 ID
 }
 
 // SetKind 集合类型
@@ -32,6 +33,7 @@ const (
 	SetOfCollectTask   SetKind = "collect_task_id"
 	SetOfFollowingUser SetKind = "following_user_id"
 	SetOfFollowerUser  SetKind = "follower_user_id"
+	SetOfBlockUser     SetKind = "block_user_id"
 )
 
 // GetSets 获取集合
@@ -44,6 +46,36 @@ func (m *SetModel) GetSets(userID primitive.ObjectID, kind SetKind) (set SetSche
 	return
 }
 
+// GetSetPage 分页获取集合中的 ID，返回当前页 ID 及集合大小
+func (m *SetModel) GetSetPage(userID primitive.ObjectID, kind SetKind, page, size int64) (ids []primitive.ObjectID, count int64, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	items := bson.M{"$ifNull": bson.A{"$" + string(kind), bson.A{}}}
+	cur, err := m.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"_id": userID}},
+		bson.M{"$project": bson.M{
+			"ids":   bson.M{"$slice": bson.A{items, (page - 1) * size, size}},
+			"count": bson.M{"$size": items},
+		}},
+	})
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	if cur.Next(ctx) {
+		res := struct {
+			IDs   []primitive.ObjectID `bson:"ids"`
+			Count int64                `bson:"count"`
+		}{}
+		if err = cur.Decode(&res); err != nil {
+			return
+		}
+		ids, count = res.IDs, res.Count
+	}
+	err = cur.Err()
+	return
+}
+
 // AddToSet 添加到集合
 func (m *SetModel) AddToSet(userID, targetID primitive.ObjectID, kind SetKind) error {
 	ctx, finish := GetCtx()
@@ -73,3 +105,28 @@ func (m *SetModel) RemoveFromSet(userID, targetID primitive.ObjectID, kind SetKi
 	}
 	return nil
 }
+
+// MergeSets 将用户 from 的集合合并到用户 to
+func (m *SetModel) MergeSets(from, to primitive.ObjectID) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	set := SetSchemas{}
+	err := m.Collection.FindOne(ctx, bson.M{"_id": from}).Decode(&set)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	if err == nil {
+		if len(set.BlockUserID) > 0 {
+			_, err = m.Collection.UpdateOne(ctx, bson.M{"_id": to},
+				bson.M{"$addToSet": bson.M{string(SetOfBlockUser): bson.M{"$each": set.BlockUserID}}},
+				options.Update().SetUpsert(true))
+			if err != nil {
+				return err
+			}
+		}
+		if _, err = m.Collection.DeleteOne(ctx, bson.M{"_id": from}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -82,4 +82,16 @@ func testAddToSet(t *testing.T) {
 		t.Error()
 	}
 
+	ids, count, err := model.GetSetPage(userID, SetOfLikeTask, 2, 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 2 || len(ids) != 1 {
+		t.Error("wrong set page", ids, count)
+	}
+	ids, count, err = model.GetSetPage(primitive.NewObjectID(), SetOfLikeTask, 1, 10)
+	if err != nil || count != 0 || len(ids) != 0 {
+		t.Error("wrong empty set page", ids, count, err)
+	}
+
 }
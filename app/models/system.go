@@ -1,6 +1,8 @@
 package models
 
 import (
+	"strconv"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -18,6 +20,9 @@ type SystemSchemas struct {
 	ID    primitive.ObjectID `bson:"_id,omitempty"` // ID
 	Key   string             // 信息名称
 	Value string             // 信息内容
+
+	Identities []UserIdentity `bson:"identities,omitempty" json:"identities,omitempty"` // 自动认证适用的身份，为空时仅适用学生
+	ValidDays  int64          `bson:"valid_days,omitempty" json:"valid_days,omitempty"` // 自动认证的有效天数，0 为使用默认值
 }
 
 // GetAutoEmail 获取自动认证后缀
@@ -52,13 +57,40 @@ func (m *SystemModel) ExistAutoEmail(email string) string {
 	return data.Value
 }
 
+// GetAutoEmailByDomain 获取邮箱后缀的自动认证信息
+func (m *SystemModel) GetAutoEmailByDomain(email string) (res SystemSchemas, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	err = m.Collection.FindOne(ctx, bson.M{"key": "email-" + email}).Decode(&res)
+	if err == mongo.ErrNoDocuments {
+		err = ErrNotExist
+	}
+	return
+}
+
 // AddAutoEmail 添加自动认证
-func (m *SystemModel) AddAutoEmail(email, data string) error {
+func (m *SystemModel) AddAutoEmail(email, data string, identities []UserIdentity, validDays int64) error {
 	ctx, finish := GetCtx()
 	defer finish()
 	opt := options.Update()
 	opt.SetUpsert(true)
-	_, err := m.Collection.UpdateOne(ctx, bson.M{"key": "email-" + email}, bson.M{"$set": bson.M{"value": data}}, opt)
+	set := bson.M{"value": data}
+	unset := bson.M{}
+	if len(identities) > 0 {
+		set["identities"] = identities
+	} else {
+		unset["identities"] = ""
+	}
+	if validDays > 0 {
+		set["valid_days"] = validDays
+	} else {
+		unset["valid_days"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"key": "email-" + email}, update, opt)
 	return err
 }
 
@@ -74,3 +106,102 @@ func (m *SystemModel) RemoveAutoEmail(email string) error {
 	}
 	return nil
 }
+
+// FilterAction 敏感词处理方式
+type FilterAction string
+
+// FilterAction 敏感词处理方式
+const (
+	FilterActionReject FilterAction = "reject" // 拒绝提交
+	FilterActionMask   FilterAction = "mask"   // 替换为 *
+	FilterActionReview FilterAction = "review" // 允许提交，进入人工审核
+)
+
+// GetSensitiveWords 分页获取敏感词
+func (m *SystemModel) GetSensitiveWords(page, size int64) (res []SystemSchemas, count int64, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	filter := bson.M{"key": bson.M{"$regex": "^word-"}}
+	count, err = m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return
+	}
+	cur, err := m.Collection.Find(ctx, filter, options.Find().SetSkip((page-1)*size).SetLimit(size))
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		item := SystemSchemas{}
+		err = cur.Decode(&item)
+		if err != nil {
+			return
+		}
+		item.Key = item.Key[len("word-"):]
+		res = append(res, item)
+	}
+	err = cur.Err()
+	return
+}
+
+// GetAllSensitiveWords 获取全部敏感词及其处理方式
+func (m *SystemModel) GetAllSensitiveWords() (res map[string]FilterAction, err error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	cur, err := m.Collection.Find(ctx, bson.M{"key": bson.M{"$regex": "^word-"}})
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	res = map[string]FilterAction{}
+	for cur.Next(ctx) {
+		item := SystemSchemas{}
+		err = cur.Decode(&item)
+		if err != nil {
+			return
+		}
+		res[item.Key[len("word-"):]] = FilterAction(item.Value)
+	}
+	err = cur.Err()
+	return
+}
+
+// AddSensitiveWord 添加或修改敏感词
+func (m *SystemModel) AddSensitiveWord(word string, action FilterAction) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	opt := options.Update()
+	opt.SetUpsert(true)
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"key": "word-" + word}, bson.M{"$set": bson.M{"value": action}}, opt)
+	return err
+}
+
+// RemoveSensitiveWord 移除敏感词
+func (m *SystemModel) RemoveSensitiveWord(word string) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	res, err := m.Collection.DeleteOne(ctx, bson.M{"key": "word-" + word})
+	if err != nil {
+		return err
+	} else if res.DeletedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// IsMigrated 数据迁移是否已完成
+func (m *SystemModel) IsMigrated(name string) (bool, error) {
+	ctx, finish := GetCtx()
+	defer finish()
+	count, err := m.Collection.CountDocuments(ctx, bson.M{"key": "migration-" + name})
+	return count > 0, err
+}
+
+// SetMigrated 记录数据迁移已完成，值为完成时间
+func (m *SystemModel) SetMigrated(name string, doneTime int64) error {
+	ctx, finish := GetCtx()
+	defer finish()
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"key": "migration-" + name},
+		bson.M{"$set": bson.M{"value": strconv.FormatInt(doneTime, 10)}}, options.Update().SetUpsert(true))
+	return err
+}
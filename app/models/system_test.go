@@ -5,6 +5,7 @@ import "testing"
 func TestSystemModel(t *testing.T) {
 	t.Run("InitDB", testInitDB)
 	t.Run("testExistAutoEmail", testExistAutoEmail)
+	t.Run("testSensitiveWord", testSensitiveWord)
 
 	ctx, finish := GetCtx()
 	defer finish()
@@ -19,10 +20,39 @@ func TestSystemModel(t *testing.T) {
 func testExistAutoEmail(t *testing.T) {
 	res := model.System.ExistAutoEmail("em.com")
 	t.Log(res)
-	err := model.System.AddAutoEmail("em.com", "大山中学")
+	err := model.System.AddAutoEmail("em.com", "大山中学", nil, 0)
 	if err != nil {
 		t.Error(err)
 	}
 	res = model.System.ExistAutoEmail("em.com")
 	t.Log(res)
+	err = model.System.AddAutoEmail("em.com", "大山中学", []UserIdentity{IdentityTeacher, IdentityStaff}, 365)
+	if err != nil {
+		t.Error(err)
+	}
+	auto, err := model.System.GetAutoEmailByDomain("em.com")
+	if err != nil || auto.Value != "大山中学" || len(auto.Identities) != 2 || auto.ValidDays != 365 {
+		t.Error(auto, err)
+	}
+	if _, err = model.System.GetAutoEmailByDomain("none.com"); err != ErrNotExist {
+		t.Error(err)
+	}
+}
+
+func testSensitiveWord(t *testing.T) {
+	err := model.System.AddSensitiveWord("敏感词", FilterActionMask)
+	if err != nil {
+		t.Error(err)
+	}
+	words, err := model.System.GetAllSensitiveWords()
+	if err != nil {
+		t.Error(err)
+	}
+	if words["敏感词"] != FilterActionMask {
+		t.Error("sensitive word not found")
+	}
+	err = model.System.RemoveSensitiveWord("敏感词")
+	if err != nil {
+		t.Error(err)
+	}
 }
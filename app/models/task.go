@@ -64,6 +64,7 @@ type TaskSchema struct {
 	RewardObject string     `bson:"reward_object"` // 酬劳物体
 
 	PublishDate int64 `bson:"publish_date"` // 任务发布时间
+	PublishAt   int64 `bson:"publish_at"`   // 定时发布时间(仅草稿)，0为不定时
 	StartDate   int64 `bson:"start_date"`   // 任务开始时间
 	EndDate     int64 `bson:"end_date"`     // 任务结束时间
 
@@ -71,6 +72,12 @@ type TaskSchema struct {
 	MaxPlayer   int64 `bson:"max_player"`   // 参与用户上限, -1为无限制
 	AutoAccept  bool  `bson:"auto_accept"`  // 自动同意领取任务
 
+	Identities []UserIdentity `bson:"identities,omitempty"` // 仅允许持有这些认证身份的用户参与，为空不限制
+	Schools    []string       `bson:"schools,omitempty"`    // 仅允许认证学校/单位为这些的用户参与，为空不限制
+
+	Campuses    []primitive.ObjectID `bson:"campuses,omitempty"` // 所属校区，为空时所有用户可见 [索引]
+	CrossCampus bool                 `bson:"cross_campus"`       // 是否对其他校区的用户可见
+
 	ViewCount    int64 `bson:"view_count"`    // 任务浏览数
 	CollectCount int64 `bson:"collect_count"` // 收藏数(冗余)
 	CommentCount int64 `bson:"comment_count"` // 评论数(冗余)
@@ -78,6 +85,8 @@ type TaskSchema struct {
 
 	// 由[浏览量、评论数、收藏数、参与人数、时间、置顶、酬劳、发布者粉丝、信用]等数据加权计算，10分钟更新一次，用于排序
 	Hot int64 `bson:"hot"` // 任务热度
+
+	IsHidden bool `bson:"is_hidden" json:"-"` // 是否已被管理员屏蔽
 }
 
 // AddTask 添加任务
@@ -106,7 +115,7 @@ func (m *TaskModel) SetTaskInfoByID(id primitive.ObjectID, info TaskSchema) erro
 	values := reflect.ValueOf(info)
 	for i := 0; i < names.NumField(); i++ {
 		name := names.Field(i).Tag.Get("bson")
-		if name == "top_time" || name == "publish_date" || name == "start_date" || name == "end_date" || name == "player_count" || name == "max_player" {
+		if name == "top_time" || name == "publish_date" || name == "publish_at" || name == "start_date" || name == "end_date" || name == "player_count" || name == "max_player" {
 			if values.Field(i).Int() != 0 {
 				updateItem[name] = values.Field(i).Int()
 			}
@@ -114,7 +123,7 @@ func (m *TaskModel) SetTaskInfoByID(id primitive.ObjectID, info TaskSchema) erro
 			if values.Field(i).Float() != 0 {
 				updateItem[name] = values.Field(i).Float()
 			}
-		} else if name == "auto_accept" {
+		} else if name == "auto_accept" || name == "cross_campus" {
 			updateItem[name] = values.Field(i).Bool()
 		} else if name == "title" || name == "type" || name == "content" || name == "reward" || name == "reward_object" || name == "status" { // 其他字段为 string
 			if values.Field(i).String() != "" {
@@ -128,6 +137,15 @@ func (m *TaskModel) SetTaskInfoByID(id primitive.ObjectID, info TaskSchema) erro
 	if len(info.Tags) > 0 {
 		updateItem["tags"] = info.Tags
 	}
+	if info.Identities != nil {
+		updateItem["identities"] = info.Identities
+	}
+	if info.Schools != nil {
+		updateItem["schools"] = info.Schools
+	}
+	if info.Campuses != nil {
+		updateItem["campuses"] = info.Campuses
+	}
 	//updateItem["publisher"] = _uid
 	if res, err := m.Collection.UpdateOne(ctx,
 		bson.M{"_id": id},
@@ -140,6 +158,131 @@ func (m *TaskModel) SetTaskInfoByID(id primitive.ObjectID, info TaskSchema) erro
 	return nil
 }
 
+// CountRunningTasks 获取用户进行中的发布任务数
+func (m *TaskModel) CountRunningTasks(publisher primitive.ObjectID) (int64, error) {
+	ctx, over := GetCtx()
+	defer over()
+	return m.Collection.CountDocuments(ctx, bson.M{"publisher": publisher, "status": TaskStatusWait})
+}
+
+// GetTopPublisher 获取时间段内发布任务最多的用户
+func (m *TaskModel) GetTopPublisher(start, end int64) (publisher primitive.ObjectID, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{
+			"publish_date": bson.M{"$gte": start, "$lt": end},
+			"status":       bson.M{"$ne": TaskStatusDraft},
+			"is_hidden":    bson.M{"$ne": true},
+		}},
+		{"$group": bson.M{"_id": "$publisher", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": 1},
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		err = ErrNotExist
+		return
+	}
+	res := struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}{}
+	err = cursor.Decode(&res)
+	return res.ID, res.Count, err
+}
+
+// ClearExpiredTop 取消已过期的置顶
+func (m *TaskModel) ClearExpiredTop(now int64) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"top_time": bson.M{"$gt": 0, "$lte": now}},
+		bson.M{"$set": bson.M{"top_time": 0}})
+	return err
+}
+
+// SetTaskPublishAt 设置任务定时发布时间，0为取消定时
+func (m *TaskModel) SetTaskPublishAt(id primitive.ObjectID, publishAt int64) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"publish_at": publishAt}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// GetScheduledTasks 获取已到定时发布时间的草稿任务
+func (m *TaskModel) GetScheduledTasks(now int64) (tasks []TaskSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cur, err := m.Collection.Find(ctx, bson.M{
+		"status":     TaskStatusDraft,
+		"publish_at": bson.M{"$gt": 0, "$lte": now},
+	})
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		task := TaskSchema{}
+		err = cur.Decode(&task)
+		if err != nil {
+			return
+		}
+		tasks = append(tasks, task)
+	}
+	err = cur.Err()
+	return
+}
+
+// PublishScheduledTask 发布定时任务
+// 仅当任务仍为草稿且定时发布时间未被修改时发布，避免重复发布
+func (m *TaskModel) PublishScheduledTask(id primitive.ObjectID, publishAt int64) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{
+		"_id":        id,
+		"status":     TaskStatusDraft,
+		"publish_at": publishAt,
+	}, bson.M{"$set": bson.M{
+		"status":       TaskStatusWait,
+		"publish_date": time.Now().Unix(),
+		"publish_at":   0,
+	}})
+	if err != nil {
+		return err
+	} else if res.ModifiedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// UnpublishTask 将刚发布的任务恢复为草稿（发布扣费失败时使用）
+func (m *TaskModel) UnpublishTask(id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": TaskStatusWait,
+	}, bson.M{"$set": bson.M{
+		"status":       TaskStatusDraft,
+		"publish_date": 0,
+	}})
+	if err != nil {
+		return err
+	} else if res.ModifiedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
 // GetTaskByID 获取用户
 func (m *TaskModel) GetTaskByID(id primitive.ObjectID) (task TaskSchema, err error) {
 	ctx, over := GetCtx()
@@ -150,7 +293,8 @@ func (m *TaskModel) GetTaskByID(id primitive.ObjectID) (task TaskSchema, err err
 
 // GetTasks 获取任务列表，需要按类型/状态/酬劳类型筛选，按关键词搜索，按不同规则排序
 func (m *TaskModel) GetTasks(sort string, taskIDs []primitive.ObjectID, taskTypes []TaskType,
-	statuses []TaskStatus, rewards []RewardType, keywords []string, user string, skip, limit int64) (tasks []TaskSchema, count int64, err error) {
+	statuses []TaskStatus, rewards []RewardType, keywords []string, user string, campus *CampusFilter, skip, limit int64,
+	excludePublishers ...primitive.ObjectID) (tasks []TaskSchema, count int64, err error) {
 	ctx, over := GetCtx()
 	defer over()
 
@@ -165,9 +309,10 @@ func (m *TaskModel) GetTasks(sort string, taskIDs []primitive.ObjectID, taskType
 	// TODO 关键词筛选
 	// 按类型、状态、酬劳类型、关键词筛选
 	filter := bson.M{
-		"type":   bson.M{"$in": taskTypes},
-		"status": bson.M{"$in": statuses},
-		"reward": bson.M{"$in": rewards},
+		"type":      bson.M{"$in": taskTypes},
+		"status":    bson.M{"$in": statuses},
+		"reward":    bson.M{"$in": rewards},
+		"is_hidden": bson.M{"$ne": true},
 	}
 
 	if len(keywords) > 0 {
@@ -182,14 +327,35 @@ func (m *TaskModel) GetTasks(sort string, taskIDs []primitive.ObjectID, taskType
 		filter["_id"] = bson.M{"$in": taskIDs}
 	}
 
+	// 按校区筛选
+	if campus != nil {
+		visible := []bson.M{{"campuses.0": bson.M{"$exists": false}}, {"cross_campus": true}}
+		if len(campus.Visible) > 0 {
+			visible = append(visible, bson.M{"campuses": bson.M{"$in": campus.Visible}})
+		}
+		and := []bson.M{{"$or": visible}}
+		if len(campus.Campuses) > 0 {
+			and = append(and, bson.M{"campuses": bson.M{"$in": campus.Campuses}})
+		}
+		filter["$and"] = and
+	}
+
 	// 筛选发布者
+	publisher := bson.M{}
 	if user != "" {
 		var _id primitive.ObjectID
 		_id, err = primitive.ObjectIDFromHex(user)
 		if err != nil {
 			return
 		}
-		filter["publisher"] = _id
+		publisher["$eq"] = _id
+	}
+	// 排除屏蔽的发布者
+	if len(excludePublishers) > 0 {
+		publisher["$nin"] = excludePublishers
+	}
+	if len(publisher) > 0 {
+		filter["publisher"] = publisher
 	}
 
 	count, err = m.Collection.CountDocuments(ctx, filter)
@@ -197,7 +363,12 @@ func (m *TaskModel) GetTasks(sort string, taskIDs []primitive.ObjectID, taskType
 		return
 	}
 
-	cursor, err := m.Collection.Find(ctx, filter, options.Find().SetSort(bson.M{sort: -1}).SetSkip(skip).SetLimit(limit))
+	// 默认按发布时间排序时置顶任务优先
+	sortRule := bson.D{{Key: sort, Value: -1}}
+	if sort == "publish_date" {
+		sortRule = bson.D{{Key: "top_time", Value: -1}, {Key: sort, Value: -1}}
+	}
+	cursor, err := m.Collection.Find(ctx, filter, options.Find().SetSort(sortRule).SetSkip(skip).SetLimit(limit))
 	if err != nil {
 		return
 	}
@@ -215,6 +386,20 @@ func (m *TaskModel) GetTasks(sort string, taskIDs []primitive.ObjectID, taskType
 	return
 }
 
+// SetTaskHidden 设置任务屏蔽状态
+func (m *TaskModel) SetTaskHidden(taskID primitive.ObjectID, hidden bool) error {
+	ctx, over := GetCtx()
+	defer over()
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": taskID},
+		bson.M{"$set": bson.M{"is_hidden": hidden}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
 // RemoveTask 删除任务
 func (m *TaskModel) RemoveTask(taskID primitive.ObjectID) error {
 	ctx, over := GetCtx()
@@ -257,6 +442,20 @@ func (m *TaskModel) InsertCount(taskID primitive.ObjectID, name ContentCountType
 	return nil
 }
 
+// CampusFilter 任务校区筛选
+type CampusFilter struct {
+	Campuses []primitive.ObjectID // 只看这些校区的任务，为空则不限
+	Visible  []primitive.ObjectID // 浏览者所属校区，其他校区的任务仅在开放跨校区可见时返回
+}
+
+// RemoveTaskCampus 将校区从所有任务中移除
+func (m *TaskModel) RemoveTaskCampus(campus primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"campuses": campus}, bson.M{"$pull": bson.M{"campuses": campus}})
+	return err
+}
+
 // GetTasksByIDs 根据多个ID获取任务列表
 func (m *TaskModel) GetTasksByIDs(taskIDs []primitive.ObjectID) (tasks []TaskSchema, err error) {
 	ctx, over := GetCtx()
@@ -284,3 +483,31 @@ func (m *TaskModel) GetTasksByIDs(taskIDs []primitive.ObjectID) (tasks []TaskSch
 
 	return
 }
+
+// TransferPublisher 将用户发布的任务转移到另一用户
+func (m *TaskModel) TransferPublisher(from, to primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"publisher": from}, bson.M{"$set": bson.M{"publisher": to}})
+	return err
+}
+
+// GetTasksByPublisher 获取用户发布的所有任务
+func (m *TaskModel) GetTasksByPublisher(publisher primitive.ObjectID) (tasks []TaskSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"publisher": publisher})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	tasks = []TaskSchema{}
+	for cursor.Next(ctx) {
+		task := TaskSchema{}
+		if err = cursor.Decode(&task); err != nil {
+			return
+		}
+		tasks = append(tasks, task)
+	}
+	return
+}
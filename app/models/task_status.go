@@ -1,7 +1,9 @@
 package models
 
 import (
+	"context"
 	"reflect"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -42,6 +44,9 @@ type TaskStatusSchema struct {
 	// 用户的反馈
 	Score    int    `bson:"score"`    // 五星好评
 	Feedback string `bson:"feedback"` // 反馈
+	// 时间
+	Time       int64 `bson:"time"`        // 申请时间
+	UpdateTime int64 `bson:"update_time"` // 状态更新时间
 }
 
 // AddTaskStatus 添加任务状态
@@ -53,6 +58,7 @@ func (m *TaskStatusModel) AddTaskStatus(taskID, userID primitive.ObjectID, statu
 		Player: userID,
 		Status: status,
 		Note:   note,
+		Time:   time.Now().Unix(),
 	})
 	if err != nil {
 		return err
@@ -81,6 +87,8 @@ func (m *TaskStatusModel) SetTaskStatus(id primitive.ObjectID, info TaskStatusSc
 		}
 	}
 
+	updateItem["update_time"] = time.Now().Unix()
+
 	if res, err := m.Collection.UpdateOne(ctx,
 		bson.M{"_id": id},
 		bson.M{"$set": updateItem}); err != nil {
@@ -92,6 +100,49 @@ func (m *TaskStatusModel) SetTaskStatus(id primitive.ObjectID, info TaskStatusSc
 	return nil
 }
 
+// exportTimeout 遍历任务状态(导出表格)的超时时间，写入响应较慢时仍需保持游标
+const exportTimeout = 10 * time.Minute
+
+// ForEachTaskStatusByTaskID 按批遍历任务的全部任务状态，不一次性读入内存
+func (m *TaskStatusModel) ForEachTaskStatusByTaskID(taskID primitive.ObjectID, batchSize int,
+	fn func(batch []TaskStatusSchema) error) error {
+	ctx, over := context.WithTimeout(context.Background(), exportTimeout)
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"task": taskID},
+		options.Find().SetSort(bson.M{"_id": 1}).SetBatchSize(int32(batchSize)))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	batch := make([]TaskStatusSchema, 0, batchSize)
+	for cursor.Next(ctx) {
+		taskStatus := TaskStatusSchema{}
+		if err = cursor.Decode(&taskStatus); err != nil {
+			return err
+		}
+		if batch = append(batch, taskStatus); len(batch) == batchSize {
+			if err = fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// CountPlayerStatus 获取用户指定状态的任务数
+func (m *TaskStatusModel) CountPlayerStatus(userID primitive.ObjectID, status PlayerStatus) (int64, error) {
+	ctx, over := GetCtx()
+	defer over()
+	return m.Collection.CountDocuments(ctx, bson.M{"player": userID, "status": status})
+}
+
 // GetTaskStatusListByTaskID 获取任务状态列表
 func (m *TaskStatusModel) GetTaskStatusListByTaskID(taskID primitive.ObjectID, status []PlayerStatus, skip, limit int64) (taskStatusList []TaskStatusSchema, count int64, err error) {
 	ctx, over := GetCtx()
@@ -165,6 +216,34 @@ func (m *TaskStatusModel) GetTaskStatusListByUserID(userID primitive.ObjectID, s
 	return
 }
 
+// IsPartner 两个用户是否在同一任务中互为发布者和参与者，且参与状态为 status 之一
+func (m *TaskStatusModel) IsPartner(userID, otherID primitive.ObjectID, status []PlayerStatus) (bool, error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{
+			"player": bson.M{"$in": []primitive.ObjectID{userID, otherID}},
+			"status": bson.M{"$in": status},
+		}},
+		{"$lookup": bson.M{
+			"from":         "tasks",
+			"localField":   "task",
+			"foreignField": "_id",
+			"as":           "task_info",
+		}},
+		{"$match": bson.M{"$or": []bson.M{
+			{"player": userID, "task_info.publisher": otherID},
+			{"player": otherID, "task_info.publisher": userID},
+		}}},
+		{"$limit": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close(ctx)
+	return cursor.Next(ctx), cursor.Err()
+}
+
 // GetTaskStatus 获取任务状态
 func (m *TaskStatusModel) GetTaskStatus(userID, taskID primitive.ObjectID) (taskStatus TaskStatusSchema, err error) {
 	ctx, over := GetCtx()
@@ -193,3 +272,11 @@ func (m *TaskStatusModel) DeleteTaskStatus(id primitive.ObjectID) error {
 	}
 	return nil
 }
+
+// TransferPlayer 将用户的任务参与状态转移到另一用户
+func (m *TaskStatusModel) TransferPlayer(from, to primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"player": from}, bson.M{"$set": bson.M{"player": to}})
+	return err
+}
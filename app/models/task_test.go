@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -9,6 +10,8 @@ import (
 func TestTaskModel(t *testing.T) {
 	t.Run("InitDB", testInitDB)
 	t.Run("testTask", testTaskModelAll)
+	t.Run("testScheduledTask", testScheduledTask)
+	t.Run("testPartner", testPartner)
 
 	ctx, finish := GetCtx()
 	defer finish()
@@ -16,6 +19,10 @@ func TestTaskModel(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	err = model.TaskStatus.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
 
 	t.Run("DisconnectDB", testDisconnectDB)
 }
@@ -59,3 +66,67 @@ func testTaskModelAll(t *testing.T) {
 	}
 	t.Log("")
 }
+
+func testScheduledTask(t *testing.T) {
+	uid := primitive.NewObjectID()
+	tid, err := model.Task.AddTask(primitive.NewObjectID(), uid, TaskStatusDraft)
+	if err != nil {
+		t.Error(err)
+	}
+	publishAt := time.Now().Unix() - 1
+	err = model.Task.SetTaskPublishAt(tid, publishAt)
+	if err != nil {
+		t.Error(err)
+	}
+	tasks, err := model.Task.GetScheduledTasks(time.Now().Unix())
+	if err != nil {
+		t.Error(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != tid {
+		t.Error("scheduled task not found")
+	}
+	err = model.Task.PublishScheduledTask(tid, publishAt)
+	if err != nil {
+		t.Error(err)
+	}
+	// 不可重复发布
+	err = model.Task.PublishScheduledTask(tid, publishAt)
+	if err != ErrNotExist {
+		t.Error("task published twice")
+	}
+	// 扣费失败时恢复为草稿
+	if err = model.Task.UnpublishTask(tid); err != nil {
+		t.Error(err)
+	}
+	task, err := model.Task.GetTaskByID(tid)
+	if err != nil || task.Status != TaskStatusDraft || task.PublishDate != 0 {
+		t.Error("task not unpublished", task.Status, err)
+	}
+}
+
+func testPartner(t *testing.T) {
+	publisher := primitive.NewObjectID()
+	player := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+	taskID, err := model.Task.AddTask(primitive.NewObjectID(), publisher, TaskStatusWait)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = model.TaskStatus.AddTaskStatus(taskID, player, PlayerRunning, ""); err != nil {
+		t.Error(err)
+	}
+
+	running := []PlayerStatus{PlayerRunning}
+	if partner, err := model.TaskStatus.IsPartner(publisher, player, running); err != nil || !partner {
+		t.Error("should be partner", err)
+	}
+	if partner, err := model.TaskStatus.IsPartner(player, publisher, running); err != nil || !partner {
+		t.Error("should be partner", err)
+	}
+	if partner, err := model.TaskStatus.IsPartner(publisher, player, []PlayerStatus{PlayerFinish}); err != nil || partner {
+		t.Error("should not be partner", err)
+	}
+	if partner, err := model.TaskStatus.IsPartner(publisher, other, running); err != nil || partner {
+		t.Error("should not be partner", err)
+	}
+}
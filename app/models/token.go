@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APITokenModel 个人 API 令牌数据库
+type APITokenModel struct {
+	Collection *mongo.Collection
+}
+
+// APIScope 个人 API 令牌权限范围
+type APIScope string
+
+// APIScope 个人 API 令牌权限范围
+const (
+	ScopeRead  APIScope = "read"  // 只读
+	ScopeWrite APIScope = "write" // 读写
+	ScopeAdmin APIScope = "admin" // 管理操作(仍需用户拥有相应权限)
+)
+
+// AllScopes 全部权限范围
+var AllScopes = []APIScope{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// APITokenSchema 个人 API 令牌数据结构
+// 只记录令牌信息，令牌本身为签名数据，创建后不再保存
+type APITokenSchema struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"` // 令牌 ID
+	User       primitive.ObjectID `bson:"user" json:"-"`           // 所属用户 [索引]
+	Name       string             `bson:"name"`                    // 令牌名称
+	Scopes     []APIScope         `bson:"scopes"`                  // 权限范围
+	ExpireTime int64              `bson:"expire_time"`             // 过期时间，0 为永不过期
+	LastUsed   int64              `bson:"last_used"`               // 最后使用时间
+	Time       int64              `bson:"time"`                    // 创建时间
+}
+
+// lastUsedInterval 最后使用时间的更新间隔(秒)，避免每次请求都写数据库
+const lastUsedInterval = 60
+
+// AddToken 添加个人 API 令牌
+func (m *APITokenModel) AddToken(userID primitive.ObjectID, name string, scopes []APIScope, expireTime int64) (APITokenSchema, error) {
+	ctx, over := GetCtx()
+	defer over()
+	token := APITokenSchema{
+		ID:         primitive.NewObjectID(),
+		User:       userID,
+		Name:       name,
+		Scopes:     scopes,
+		ExpireTime: expireTime,
+		Time:       time.Now().Unix(),
+	}
+	_, err := m.Collection.InsertOne(ctx, token)
+	return token, err
+}
+
+// GetTokens 获取用户的全部个人 API 令牌（按创建时间倒序）
+func (m *APITokenModel) GetTokens(userID primitive.ObjectID) (tokens []APITokenSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"user": userID}, options.Find().SetSort(bson.M{"time": -1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	tokens = []APITokenSchema{}
+	for cursor.Next(ctx) {
+		token := APITokenSchema{}
+		if err = cursor.Decode(&token); err != nil {
+			return
+		}
+		tokens = append(tokens, token)
+	}
+	return
+}
+
+// GetToken 获取个人 API 令牌
+func (m *APITokenModel) GetToken(id primitive.ObjectID) (token APITokenSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	err = m.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		err = ErrNotExist
+	}
+	return
+}
+
+// CountTokens 统计用户的个人 API 令牌数量
+func (m *APITokenModel) CountTokens(userID primitive.ObjectID) (int64, error) {
+	ctx, over := GetCtx()
+	defer over()
+	return m.Collection.CountDocuments(ctx, bson.M{"user": userID})
+}
+
+// SetLastUsed 更新令牌最后使用时间
+func (m *APITokenModel) SetLastUsed(id primitive.ObjectID, lastUsed int64) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id, "last_used": bson.M{"$lt": lastUsed - lastUsedInterval}},
+		bson.M{"$set": bson.M{"last_used": lastUsed}})
+	return err
+}
+
+// RemoveToken 删除个人 API 令牌
+func (m *APITokenModel) RemoveToken(userID, id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.DeleteOne(ctx, bson.M{"_id": id, "user": userID})
+	if err != nil {
+		return err
+	} else if res.DeletedCount == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// RemoveUserTokens 删除用户的全部个人 API 令牌
+func (m *APITokenModel) RemoveUserTokens(userID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.DeleteMany(ctx, bson.M{"user": userID})
+	return err
+}
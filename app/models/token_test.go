@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAPITokenModel(t *testing.T) {
+	t.Run("InitDB", testInitDB)
+	t.Run("testAPIToken", testAPIToken)
+
+	ctx, finish := GetCtx()
+	defer finish()
+	err := model.APIToken.Collection.Drop(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("DisconnectDB", testDisconnectDB)
+}
+
+func testAPIToken(t *testing.T) {
+	userID := primitive.NewObjectID()
+	token, err := model.APIToken.AddToken(userID, "backup", []APIScope{ScopeRead}, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err = model.APIToken.AddToken(userID, "deploy", []APIScope{ScopeRead, ScopeWrite}, 0); err != nil {
+		t.Error(err)
+	}
+	count, err := model.APIToken.CountTokens(userID)
+	if err != nil || count != 2 {
+		t.Error("wrong count", count, err)
+	}
+
+	now := time.Now().Unix()
+	if err = model.APIToken.SetLastUsed(token.ID, now); err != nil {
+		t.Error(err)
+	}
+	res, err := model.APIToken.GetToken(token.ID)
+	if err != nil || res.LastUsed != now || res.Scopes[0] != ScopeRead {
+		t.Error("wrong token", res, err)
+	}
+
+	// 只能删除自己的令牌
+	if err = model.APIToken.RemoveToken(primitive.NewObjectID(), token.ID); err != ErrNotExist {
+		t.Error("remove token of other user")
+	}
+	if err = model.APIToken.RemoveToken(userID, token.ID); err != nil {
+		t.Error(err)
+	}
+	if _, err = model.APIToken.GetToken(token.ID); err != ErrNotExist {
+		t.Error("token remains")
+	}
+	if err = model.APIToken.RemoveUserTokens(userID); err != nil {
+		t.Error(err)
+	}
+	tokens, err := model.APIToken.GetTokens(userID)
+	if err != nil || len(tokens) != 0 {
+		t.Error("wrong tokens", tokens, err)
+	}
+}
@@ -1,8 +1,11 @@
 package models
 
 import (
+	"context"
 	"github.com/TimeForCoin/Server/app/utils"
 	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -29,6 +32,9 @@ type UserIdentity string
 // CertificationStatus 用户认证状态
 type CertificationStatus string
 
+// CertificationMethod 认证方式
+type CertificationMethod string
+
 // UserGender 用户性别
 const (
 	GenderMan   UserGender = "man"   // 男
@@ -47,7 +53,17 @@ const (
 // UserIdentity 用户认证身份
 const (
 	IdentityNone    UserIdentity = "none"    // 未认证
-	IdentityStudent UserIdentity = "student" // 目前仅支持学生认证
+	IdentityStudent UserIdentity = "student" // 学生
+	IdentityTeacher UserIdentity = "teacher" // 教师
+	IdentityStaff   UserIdentity = "staff"   // 教职工
+	IdentityAlumni  UserIdentity = "alumni"  // 合作学校校友
+)
+
+// CertificationMethod 认证方式
+const (
+	CertificationByEmail    CertificationMethod = "email"    // 学校邮箱认证
+	CertificationByMaterial CertificationMethod = "material" // 提交材料人工审核
+	CertificationByInvite   CertificationMethod = "invite"   // 管理员发放的邀请码
 )
 
 // CertificationStatus 用户认证状态
@@ -59,6 +75,7 @@ const (
 	CertificationCheckEmail CertificationStatus = "check_email" // 认证邮件已发送，待确认
 	CertificationWaitEmail  CertificationStatus = "wait_email"  // 认证邮件已发确认，待审核
 	CertificationCancel     CertificationStatus = "cancel"      // 认证已被用户取消
+	CertificationExpired    CertificationStatus = "expired"     // 认证已过期，需重新认证
 )
 
 // UserInfoSchema 用户信息结构
@@ -74,6 +91,26 @@ type UserInfoSchema struct {
 	Birthday int64      `bson:"birthday"` // 生日
 }
 
+// PrivacyLevel 个人信息可见范围
+type PrivacyLevel string
+
+// PrivacyLevel 个人信息可见范围
+const (
+	PrivacyPublic    PrivacyLevel = "public"    // 所有人可见
+	PrivacyFollowers PrivacyLevel = "followers" // 关注自己的用户可见
+	PrivacyPartners  PrivacyLevel = "partners"  // 任务伙伴可见，联系方式仅在任务进行中可见
+	PrivacyPrivate   PrivacyLevel = "private"   // 仅自己可见
+)
+
+// UserPrivacySchema 个人信息隐私设置，为空时使用默认值
+type UserPrivacySchema struct {
+	Email    PrivacyLevel `bson:"email,omitempty"`    // 联系邮箱，默认任务伙伴可见
+	Phone    PrivacyLevel `bson:"phone,omitempty"`    // 联系手机，默认任务伙伴可见
+	School   PrivacyLevel `bson:"school,omitempty"`   // 学校，默认公开
+	Location PrivacyLevel `bson:"location,omitempty"` // 具体位置，默认公开
+	Birthday PrivacyLevel `bson:"birthday,omitempty"` // 生日，默认公开
+}
+
 // UserDataSchema 用户数据结构
 type UserDataSchema struct {
 	Money  int64    // 当前持有闲币
@@ -81,8 +118,13 @@ type UserDataSchema struct {
 	Credit int64    // 个人信誉
 	Type   UserType // 用户类型
 
-	AttendanceDate int64    `bson:"attendance_date"` // 签到时间戳
-	SearchHistory  []string `bson:"search_history"`  // 搜索历史(仅保留最近的 20 条)
+	Experience int64 `bson:"experience"` // 累计获得积分
+	Level      int64 `bson:"level"`      // 用户等级(由累计获得积分计算)
+
+	AttendanceDate   int64    `bson:"attendance_date"`   // 签到时间戳
+	AttendanceStreak int64    `bson:"attendance_streak"` // 连续签到天数
+	LongestStreak    int64    `bson:"longest_streak"`    // 最长连续签到天数
+	SearchHistory    []string `bson:"search_history"`    // 搜索历史(仅保留最近的 20 条)
 	// 冗余数据
 	PublishCount    int64 `bson:"publish_count"`     // 发布任务数
 	PublishRunCount int64 `bson:"publish_run_count"` // 发布并进行中任务数
@@ -90,30 +132,57 @@ type UserDataSchema struct {
 	ReceiveRunCount int64 `bson:"receive_run_count"` // 领取并进行中任务数
 	FollowingCount  int64 `bson:"following_count"`   // 关注人数量
 	FollowerCount   int64 `bson:"follower_count"`    // 粉丝数量
+	ViolationCount  int64 `bson:"violation_count"`   // 被举报并确认违规次数
 }
 
 // UserCertificationSchema 用户认证信息
 type UserCertificationSchema struct {
-	Identity UserIdentity         `bson:"identity"` // 认证身份类型
-	Data     string               `bson:"data"`     // 认证内容
+	Identity UserIdentity         `bson:"identity"` // 认证身份类型，每种身份至多一条认证
+	Method   CertificationMethod  `bson:"method"`   // 认证方式
+	Data     string               `bson:"data"`     // 认证内容(学校/单位)
 	Status   CertificationStatus  `bson:"status"`   // 认证状态
 	Date     int64                `bson:"date"`     // 认证时间
 	Material []primitive.ObjectID `bson:"material"` // 人工认证材料
 	Feedback string               `bson:"feedback"` // 审核不通过后的反馈
 	Email    string               `bson:"email"`    // 邮箱认证
+
+	Campus     primitive.ObjectID `bson:"campus,omitempty" json:"-"` // 认证通过时匹配到的校区
+	ExpireTime int64              `bson:"expire_time"`               // 认证过期时间，0 为永不过期
+	RemindTime int64              `bson:"remind_time,omitempty"`     // 已发送过期提醒的时间
 }
 
 // UserSchema User 基本数据结构
 type UserSchema struct {
-	ID            primitive.ObjectID      `bson:"_id,omitempty"` // 用户ID [索引]
-	WechatID      string                  `bson:"wechat_id"`     // 微信OpenID
-	WechatName    string                  `bson:"wechat_name"`   // 微信名
-	VioletID      string                  `bson:"violet_id"`     // VioletID
-	VioletName    string                  `bson:"violet_name"`   // Violet 用户名
-	RegisterTime  int64                   `bson:"register_time"` // 用户注册时间
-	Info          UserInfoSchema          `bson:"info"`          // 用户个性信息
-	Data          UserDataSchema          `bson:"data"`          // 用户数据
-	Certification UserCertificationSchema `bson:"certification"` // 用户认证信息
+	ID             primitive.ObjectID        `bson:"_id,omitempty"`                  // 用户ID [索引]
+	WechatID       string                    `bson:"wechat_id"`                      // 微信OpenID
+	WechatName     string                    `bson:"wechat_name"`                    // 微信名
+	VioletID       string                    `bson:"violet_id"`                      // VioletID
+	VioletName     string                    `bson:"violet_name"`                    // Violet 用户名
+	RegisterTime   int64                     `bson:"register_time"`                  // 用户注册时间
+	Info           UserInfoSchema            `bson:"info"`                           // 用户个性信息
+	NicknameKey    string                    `bson:"nickname_key" json:"-"`          // 小写昵称，用于不区分大小写的前缀搜索 [索引]
+	Privacy        UserPrivacySchema         `bson:"privacy" json:"-"`               // 个人信息隐私设置
+	Data           UserDataSchema            `bson:"data"`                           // 用户数据
+	Certifications []UserCertificationSchema `bson:"certifications"`                 // 用户认证信息(可同时持有多种身份) [索引]
+	Reputation     ReputationSchema          `bson:"reputation"`                     // 用户口碑
+	Badges         []BadgeSchema             `bson:"badges,omitempty"`               // 已获得的徽章
+	Roles          []primitive.ObjectID      `bson:"roles,omitempty" json:"-"`       // 被授予的角色 [索引]
+	DeleteTime     int64                     `bson:"delete_time,omitempty" json:"-"` // 注销时间，0 为未注销
+}
+
+// BadgeSchema 用户徽章
+type BadgeSchema struct {
+	ID     string `bson:"id"`               // 徽章 ID
+	Time   int64  `bson:"time"`             // 获得时间
+	Period string `bson:"period,omitempty"` // 周期性徽章的获得周期，例如 2019-10
+}
+
+// badgeFilter 用户未拥有该徽章(周期性徽章为未拥有该周期的徽章)的条件
+func badgeFilter(id primitive.ObjectID, badge BadgeSchema) bson.M {
+	if badge.Period == "" {
+		return bson.M{"_id": id, "badges.id": bson.M{"$ne": badge.ID}}
+	}
+	return bson.M{"_id": id, "badges": bson.M{"$not": bson.M{"$elemMatch": bson.M{"id": badge.ID, "period": badge.Period}}}}
 }
 
 func makeNewUserSchema() UserSchema {
@@ -126,10 +195,11 @@ func makeNewUserSchema() UserSchema {
 			Value:         1000,
 			Credit:        100,
 		},
-		Certification: UserCertificationSchema{
-			Identity: IdentityNone,
-			Status:   CertificationNone,
+		Reputation: ReputationSchema{
+			Player:    newRatingSchema(),
+			Publisher: newRatingSchema(),
 		},
+		Certifications: []UserCertificationSchema{},
 	}
 }
 
@@ -157,6 +227,7 @@ func (m *UserModel) AddUserByWechat(openid string) (primitive.ObjectID, error) {
 	newUser.ID = userID
 	newUser.WechatID = openid
 	newUser.Info.Nickname = "微信用户" + utils.GetRandomString(6)
+	newUser.NicknameKey = nicknameKey(newUser.Info.Nickname)
 	newUser.Info.Avatar = "https://coin-1252808268.cos.ap-guangzhou.myqcloud.com/avatar-5cfe5cab2cfbe5ed600f9665.png"
 	_, err := m.Collection.InsertOne(ctx, newUser)
 	if err != nil {
@@ -173,6 +244,26 @@ func (m *UserModel) GetUserByID(id primitive.ObjectID) (user UserSchema, err err
 	return
 }
 
+// GetUsersByIDs 批量获取用户
+func (m *UserModel) GetUsersByIDs(ids []primitive.ObjectID) (users []UserSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cur, err := m.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := UserSchema{}
+		if err = cur.Decode(&user); err != nil {
+			return
+		}
+		users = append(users, user)
+	}
+	err = cur.Err()
+	return
+}
+
 // GetUserByViolet 通过 VioletID 查找用户
 func (m *UserModel) GetUserByViolet(id string) (user UserSchema, err error) {
 	ctx, over := GetCtx()
@@ -209,6 +300,9 @@ func (m *UserModel) SetUserInfoByID(id primitive.ObjectID, info UserInfoSchema)
 			}
 		}
 	}
+	if info.Nickname != "" {
+		updateItem["nickname_key"] = nicknameKey(info.Nickname)
+	}
 	if res, err := m.Collection.UpdateOne(ctx,
 		bson.M{"_id": id},
 		bson.M{"$set": updateItem}); err != nil {
@@ -225,18 +319,23 @@ type UserDataCount struct {
 	Money           int64 `bson:"money"`             // 当前持有闲币
 	Value           int64 `bson:"value"`             // 用户积分
 	Credit          int64 `bson:"credit"`            // 个人信誉
+	Experience      int64 `bson:"experience"`        // 累计获得积分(积分增加时自动累计)
 	PublishCount    int64 `bson:"publish_count"`     // 发布任务数
 	PublishRunCount int64 `bson:"publish_run_count"` // 发布并进行中任务数
 	ReceiveCount    int64 `bson:"receive_count"`     // 领取任务数
 	ReceiveRunCount int64 `bson:"receive_run_count"` // 领取并进行中任务数
 	FollowingCount  int64 `bson:"following_count"`   // 关注人数量
 	FollowerCount   int64 `bson:"follower_count"`    // 粉丝数量
+	ViolationCount  int64 `bson:"violation_count"`   // 违规次数
 }
 
 // UpdateUserDataCount 更新用户数值数据（偏移值）
 func (m *UserModel) UpdateUserDataCount(id primitive.ObjectID, data UserDataCount) error {
 	ctx, over := GetCtx()
 	defer over()
+	if data.Value > 0 {
+		data.Experience += data.Value
+	}
 	// 通过反射获取非零字段
 	updateItem := bson.M{}
 	names := reflect.TypeOf(data)
@@ -256,20 +355,238 @@ func (m *UserModel) UpdateUserDataCount(id primitive.ObjectID, data UserDataCoun
 	return nil
 }
 
-// GetUsers 按关键字搜索用户
-func (m *UserModel) GetUsers(key string, page, size int64) (res []UserSchema) {
+// ChangeCredit 调整用户信用并限制在 [0, max] 范围内，max 为 0 时不设上限，返回实际变化值
+func (m *UserModel) ChangeCredit(id primitive.ObjectID, value, max int64) (int64, error) {
+	ctx, over := GetCtx()
+	defer over()
+	credit := bson.M{"$add": bson.A{"$data.credit", value}}
+	if max > 0 {
+		credit = bson.M{"$min": bson.A{max, credit}}
+	}
+	credit = bson.M{"$max": bson.A{0, credit}}
+	var before UserSchema
+	err := m.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id},
+		bson.A{bson.M{"$set": bson.M{"data.credit": credit}}},
+		options.FindOneAndUpdate().SetProjection(bson.M{"data.credit": 1}).SetReturnDocument(options.Before)).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return 0, ErrNotExist
+	} else if err != nil {
+		return 0, err
+	}
+	after := before.Data.Credit + value
+	if max > 0 && after > max {
+		after = max
+	}
+	if after < 0 {
+		after = 0
+	}
+	return after - before.Data.Credit, nil
+}
+
+// SpendMoney 扣除用户金币，余额不足时返回 ErrNotExist
+func (m *UserModel) SpendMoney(id primitive.ObjectID, money int64) error {
+	ctx, over := GetCtx()
+	defer over()
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "data.money": bson.M{"$gte": money}},
+		bson.M{"$inc": bson.M{"data.money": -money}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// SetFollowCount 设置用户关注数和粉丝数（用于重新统计）
+func (m *UserModel) SetFollowCount(id primitive.ObjectID, following, follower int64) error {
+	ctx, over := GetCtx()
+	defer over()
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"data.following_count": following, "data.follower_count": follower}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// MigrateExperience 为旧版用户补全累计获得积分和等级
+// 累计获得积分为积分日志中增加的积分之和(不少于当前积分)，levels 为各等级所需累计获得积分(从低到高)，等级只升不降
+func (m *UserModel) MigrateExperience(ctx context.Context, logs *LogModel, levels []int64) error {
+	gained := map[primitive.ObjectID]int64{}
+	logCur, err := logs.Collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"type": LogTypeValue, "value": bson.M{"$gt": 0}}},
+		bson.M{"$group": bson.M{"_id": "$user_id", "total": bson.M{"$sum": "$value"}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer logCur.Close(ctx)
+	for logCur.Next(ctx) {
+		item := struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Total int64              `bson:"total"`
+		}{}
+		if err = logCur.Decode(&item); err != nil {
+			return err
+		}
+		gained[item.ID] = item.Total
+	}
+	if err = logCur.Err(); err != nil {
+		return err
+	}
+
+	cur, err := m.Collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{
+		"data.value": 1, "data.experience": 1, "data.level": 1,
+	}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := struct {
+			ID   primitive.ObjectID `bson:"_id"`
+			Data struct {
+				Value      int64  `bson:"value"`
+				Experience *int64 `bson:"experience"`
+				Level      int64  `bson:"level"`
+			} `bson:"data"`
+		}{}
+		if err = cur.Decode(&user); err != nil {
+			return err
+		}
+		update := bson.M{}
+		var experience int64
+		if user.Data.Experience != nil {
+			experience = *user.Data.Experience
+		} else {
+			experience = gained[user.ID]
+			if user.Data.Value > experience {
+				experience = user.Data.Value
+			}
+			update["data.experience"] = experience
+		}
+		var level int64
+		for i, need := range levels {
+			if experience < need {
+				break
+			}
+			level = int64(i + 1)
+		}
+		if level > user.Data.Level {
+			update["data.level"] = level
+		}
+		if len(update) == 0 {
+			continue
+		}
+		if _, err = m.Collection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": update}); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// SetUserLevel 设置用户等级
+func (m *UserModel) SetUserLevel(id primitive.ObjectID, level int64) error {
 	ctx, over := GetCtx()
 	defer over()
-	cursor, err := m.Collection.Find(ctx, bson.M{"info.nickname": bson.M{"$regex": key, "$options": "$i"}},
-		options.Find().SetSkip((page-1)*size).SetLimit(size))
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"data.level": level}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	// 更新缓存
+	return GetRedis().Cache.WillUpdate(id, KindOfBaseInfo)
+}
+
+// UserSearchFilter 用户搜索条件，为空的条件不筛选
+type UserSearchFilter struct {
+	Key       string              // 关键字，匹配昵称前缀或昵称、简介中的词
+	School    string              // 学校，匹配公开的学校或通过认证的学校/单位
+	Identity  UserIdentity        // 认证身份
+	Status    CertificationStatus // 认证状态，与认证身份同时指定时匹配该身份的认证状态
+	MinCredit int64               // 最低信誉
+	MinLevel  int64               // 最低等级
+	Gender    UserGender          // 性别
+}
+
+// UserSearchSort 用户搜索排序方式
+type UserSearchSort string
+
+// UserSearchSort 用户搜索排序方式
+const (
+	UserSortRelevance UserSearchSort = "relevance" // 按关键字相关度，无关键字时按粉丝数
+	UserSortFollower  UserSearchSort = "follower"  // 按粉丝数
+	UserSortRating    UserSearchSort = "rating"    // 按作为参与者的平均评分
+)
+
+// SearchUsers 按条件搜索用户，返回当前页用户及符合条件的用户总数
+func (m *UserModel) SearchUsers(search UserSearchFilter, sort UserSearchSort, skip, limit int64) (res []UserSchema, count int64, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	and := bson.A{bson.M{"delete_time": bson.M{"$in": bson.A{nil, 0}}}}
+	if search.Key != "" {
+		// 昵称前缀和全文索引均可使用索引，避免全表正则扫描；前缀匹配小写昵称，不区分大小写
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"$text": bson.M{"$search": search.Key}},
+			bson.M{"nickname_key": bson.M{"$regex": "^" + regexp.QuoteMeta(nicknameKey(search.Key))}},
+		}})
+	}
+	if search.School != "" {
+		// 未公开的学校信息不参与筛选
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"info.school": search.School, "privacy.school": bson.M{"$in": bson.A{nil, PrivacyPublic}}},
+			bson.M{"certifications": bson.M{"$elemMatch": bson.M{"data": search.School, "status": CertificationTrue}}},
+		}})
+	}
+	if search.Identity != "" {
+		certification := bson.M{"identity": search.Identity}
+		if search.Status != "" {
+			certification["status"] = search.Status
+		}
+		and = append(and, bson.M{"certifications": bson.M{"$elemMatch": certification}})
+	} else if search.Status == CertificationNone {
+		and = append(and, bson.M{"certifications.status": bson.M{"$ne": CertificationTrue}})
+	} else if search.Status != "" {
+		and = append(and, bson.M{"certifications.status": search.Status})
+	}
+	if search.MinCredit > 0 {
+		and = append(and, bson.M{"data.credit": bson.M{"$gte": search.MinCredit}})
+	}
+	if search.MinLevel > 0 {
+		and = append(and, bson.M{"data.level": bson.M{"$gte": search.MinLevel}})
+	}
+	if search.Gender != "" {
+		and = append(and, bson.M{"info.gender": search.Gender})
+	}
+	filter := bson.M{"$and": and}
+
+	count, err = m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	opts := options.Find().SetSkip(skip).SetLimit(limit)
+	switch {
+	case sort == UserSortRating:
+		opts.SetSort(bson.D{{Key: "reputation.player.average", Value: -1}, {Key: "reputation.player.count", Value: -1}})
+	case sort == UserSortRelevance && search.Key != "":
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+		opts.SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "data.follower_count", Value: -1}})
+	default:
+		opts.SetSort(bson.D{{Key: "data.follower_count", Value: -1}})
+	}
+	cursor, err := m.Collection.Find(ctx, filter, opts)
 	if err != nil {
 		return
 	}
 	defer cursor.Close(ctx)
 	for cursor.Next(ctx) {
 		user := UserSchema{}
-		err = cursor.Decode(&user)
-		if err != nil {
+		if err = cursor.Decode(&user); err != nil {
 			return
 		}
 		res = append(res, user)
@@ -277,6 +594,32 @@ func (m *UserModel) GetUsers(key string, page, size int64) (res []UserSchema) {
 	return
 }
 
+// nicknameKey 昵称搜索键
+func nicknameKey(nickname string) string {
+	return strings.ToLower(nickname)
+}
+
+// MigrateNicknameKey 为已有用户补充小写昵称
+func (m *UserModel) MigrateNicknameKey(ctx context.Context) error {
+	cur, err := m.Collection.Find(ctx, bson.M{"nickname_key": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"info.nickname": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := UserSchema{}
+		if err = cur.Decode(&user); err != nil {
+			return err
+		}
+		if _, err = m.Collection.UpdateOne(ctx, bson.M{"_id": user.ID},
+			bson.M{"$set": bson.M{"nickname_key": nicknameKey(user.Info.Nickname)}}); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
 // SetUserType 设置用户类型
 func (m *UserModel) SetUserType(id primitive.ObjectID, userType UserType) error {
 	ctx, over := GetCtx()
@@ -289,16 +632,103 @@ func (m *UserModel) SetUserType(id primitive.ObjectID, userType UserType) error
 		return ErrNotExist
 	}
 	// 更新缓存
+	if err := GetRedis().Cache.WillUpdate(id, KindOfBaseInfo); err != nil {
+		return err
+	}
+	return GetRedis().Cache.WillUpdate(id, KindOfPermission)
+}
+
+// AddUserRole 为用户授予角色
+func (m *UserModel) AddUserRole(id, roleID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$addToSet": bson.M{"roles": roleID}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return GetRedis().Cache.WillUpdate(id, KindOfPermission)
+}
+
+// RemoveUserRole 撤销用户的角色
+func (m *UserModel) RemoveUserRole(id, roleID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "roles": roleID},
+		bson.M{"$pull": bson.M{"roles": roleID}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return GetRedis().Cache.WillUpdate(id, KindOfPermission)
+}
+
+// GetUsersByRole 获取拥有某角色的全部用户
+func (m *UserModel) GetUsersByRole(roleID primitive.ObjectID) (res []primitive.ObjectID, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cursor, err := m.Collection.Find(ctx, bson.M{"roles": roleID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		user := UserSchema{}
+		if err = cursor.Decode(&user); err != nil {
+			return
+		}
+		res = append(res, user.ID)
+	}
+	return
+}
+
+// RemoveRoleFromUsers 从全部用户中移除某角色
+func (m *UserModel) RemoveRoleFromUsers(roleID primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"roles": roleID},
+		bson.M{"$pull": bson.M{"roles": roleID}})
+	return err
+}
+
+// HideUserInfo 屏蔽用户违规资料（重置昵称、清空简介）
+func (m *UserModel) HideUserInfo(id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	nickname := "用户" + utils.GetRandomString(6)
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"info.nickname": nickname,
+			"nickname_key":  nicknameKey(nickname),
+			"info.bio":      "",
+		}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	// 更新缓存
 	return GetRedis().Cache.WillUpdate(id, KindOfBaseInfo)
 }
 
 // SetUserAttend 用户签到
-func (m *UserModel) SetUserAttend(id primitive.ObjectID) error {
+func (m *UserModel) SetUserAttend(id primitive.ObjectID, streak int64) error {
 	ctx, over := GetCtx()
 	defer over()
 	if res, err := m.Collection.UpdateOne(ctx,
 		bson.M{"_id": id},
-		bson.M{"$set": bson.M{"data.attendance_date": time.Now().Unix()}}); err != nil {
+		bson.M{"$set": bson.M{
+			"data.attendance_date":   time.Now().Unix(),
+			"data.attendance_streak": streak,
+		}, "$max": bson.M{
+			"data.longest_streak": streak,
+		}}); err != nil {
 		return err
 	} else if res.MatchedCount < 1 {
 		return ErrNotExist
@@ -306,13 +736,71 @@ func (m *UserModel) SetUserAttend(id primitive.ObjectID) error {
 	return nil
 }
 
-// SetUserCertification 设置用户认证信息
-func (m *UserModel) SetUserCertification(id primitive.ObjectID, data UserCertificationSchema) error {
+// SetAttendanceStreak 更新连续签到天数(补签后)
+func (m *UserModel) SetAttendanceStreak(id primitive.ObjectID, streak int64) error {
 	ctx, over := GetCtx()
 	defer over()
 	if res, err := m.Collection.UpdateOne(ctx,
 		bson.M{"_id": id},
-		bson.M{"$set": bson.M{"certification": data}}); err != nil {
+		bson.M{"$set": bson.M{
+			"data.attendance_streak": streak,
+		}, "$max": bson.M{
+			"data.longest_streak": streak,
+		}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// AddUserBadge 为用户添加徽章，已拥有时不重复添加
+// period 不为空时为周期性徽章，每个周期可获得一次
+func (m *UserModel) AddUserBadge(id primitive.ObjectID, badgeID, period string) (added bool, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	badge := BadgeSchema{
+		ID:     badgeID,
+		Time:   time.Now().Unix(),
+		Period: period,
+	}
+	res, err := m.Collection.UpdateOne(ctx, badgeFilter(id, badge),
+		bson.M{"$push": bson.M{"badges": badge}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// SetUserPrivacy 设置个人信息隐私
+func (m *UserModel) SetUserPrivacy(id primitive.ObjectID, privacy UserPrivacySchema) error {
+	ctx, over := GetCtx()
+	defer over()
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"privacy": privacy}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// SetUserCertification 设置用户某一身份的认证信息，不存在则添加
+func (m *UserModel) SetUserCertification(id primitive.ObjectID, data UserCertificationSchema) error {
+	ctx, over := GetCtx()
+	defer over()
+	res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "certifications.identity": data.Identity},
+		bson.M{"$set": bson.M{"certifications.$": data}})
+	if err != nil {
+		return err
+	} else if res.MatchedCount > 0 {
+		return nil
+	}
+	if res, err = m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "certifications.identity": bson.M{"$ne": data.Identity}},
+		bson.M{"$push": bson.M{"certifications": data}}); err != nil {
 		return err
 	} else if res.MatchedCount < 1 {
 		return ErrNotExist
@@ -320,11 +808,11 @@ func (m *UserModel) SetUserCertification(id primitive.ObjectID, data UserCertifi
 	return nil
 }
 
-// GetCertification 获取认证
+// GetCertification 获取存在指定状态认证的用户
 func (m *UserModel) GetCertification(status []CertificationStatus, page, size int64) (res []UserSchema, err error) {
 	ctx, over := GetCtx()
 	defer over()
-	cur, err := m.Collection.Find(ctx, bson.M{"certification.status": bson.M{"$in": status}}, options.Find().SetSkip((page-1)*size).SetLimit(size))
+	cur, err := m.Collection.Find(ctx, bson.M{"certifications.status": bson.M{"$in": status}}, options.Find().SetSkip((page-1)*size).SetLimit(size))
 	if err != nil {
 		return
 	}
@@ -344,13 +832,183 @@ func (m *UserModel) GetCertification(status []CertificationStatus, page, size in
 func (m *UserModel) CheckCertificationEmail(email string) bool {
 	ctx, over := GetCtx()
 	defer over()
-	if res, err := m.Collection.CountDocuments(ctx,
-		bson.M{"certification.email": email, "certification.status": CertificationTrue}); err == nil && res == 0 {
+	if res, err := m.Collection.CountDocuments(ctx, bson.M{"certifications": bson.M{"$elemMatch": bson.M{
+		"email":  email,
+		"status": CertificationTrue,
+	}}}); err == nil && res == 0 {
 		return true
 	}
 	return false
 }
 
+// GetExpiringCertifications 获取存在将在 deadline 前过期且未提醒的认证的用户
+func (m *UserModel) GetExpiringCertifications(deadline int64) (res []UserSchema, err error) {
+	return m.findCertifications(bson.M{
+		"status":      CertificationTrue,
+		"expire_time": bson.M{"$gt": 0, "$lte": deadline},
+		"remind_time": bson.M{"$exists": false},
+	})
+}
+
+// GetExpiredCertifications 获取存在已到过期时间但仍为通过状态的认证的用户
+func (m *UserModel) GetExpiredCertifications(now int64) (res []UserSchema, err error) {
+	return m.findCertifications(bson.M{
+		"status":      CertificationTrue,
+		"expire_time": bson.M{"$gt": 0, "$lte": now},
+	})
+}
+
+// findCertifications 获取存在符合条件的认证的用户
+func (m *UserModel) findCertifications(match bson.M) (res []UserSchema, err error) {
+	ctx, over := GetCtx()
+	defer over()
+	cur, err := m.Collection.Find(ctx, bson.M{"certifications": bson.M{"$elemMatch": match}})
+	if err != nil {
+		return
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := UserSchema{}
+		err = cur.Decode(&user)
+		if err != nil {
+			return
+		}
+		res = append(res, user)
+	}
+	return
+}
+
+// MigrateCertification 将旧版单一认证信息迁移到认证列表，并为缺少过期时间的已通过认证补全过期时间
+// 过期时间为认证时间加上有效天数，邮箱后缀设置了有效天数时优先使用，defaultDays 为 0 时永不过期
+func (m *UserModel) MigrateCertification(ctx context.Context, system *SystemModel, defaultDays int64) error {
+	expireTime := func(certification UserCertificationSchema) int64 {
+		days := defaultDays
+		if emailParts := strings.Split(certification.Email, "@"); len(emailParts) == 2 {
+			if auto, err := system.GetAutoEmailByDomain(emailParts[1]); err == nil && auto.ValidDays > 0 {
+				days = auto.ValidDays
+			}
+		}
+		if days <= 0 {
+			return 0
+		}
+		return time.Unix(certification.Date, 0).Add(time.Duration(days) * 24 * time.Hour).Unix()
+	}
+	cur, err := m.Collection.Find(ctx, bson.M{"certification": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := struct {
+			ID            primitive.ObjectID      `bson:"_id"`
+			Certification UserCertificationSchema `bson:"certification"`
+		}{}
+		if err = cur.Decode(&user); err != nil {
+			return err
+		}
+		certifications := []UserCertificationSchema{}
+		if user.Certification.Identity != IdentityNone && user.Certification.Identity != "" {
+			user.Certification.Method = CertificationByMaterial
+			if user.Certification.Email != "" {
+				user.Certification.Method = CertificationByEmail
+			}
+			if user.Certification.Status == CertificationTrue {
+				user.Certification.ExpireTime = expireTime(user.Certification)
+			}
+			certifications = append(certifications, user.Certification)
+		}
+		if _, err = m.Collection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+			"$set":   bson.M{"certifications": certifications},
+			"$unset": bson.M{"certification": ""},
+		}); err != nil {
+			return err
+		}
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+
+	// 旧版通过的认证没有过期时间字段
+	noExpire := bson.M{"status": CertificationTrue, "expire_time": bson.M{"$exists": false}}
+	cur, err = m.Collection.Find(ctx, bson.M{"certifications": bson.M{"$elemMatch": noExpire}},
+		options.Find().SetProjection(bson.M{"certifications": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := struct {
+			ID             primitive.ObjectID `bson:"_id"`
+			Certifications []bson.M           `bson:"certifications"`
+		}{}
+		if err = cur.Decode(&user); err != nil {
+			return err
+		}
+		for _, item := range user.Certifications {
+			if _, exists := item["expire_time"]; exists || item["status"] != string(CertificationTrue) {
+				continue
+			}
+			certification := UserCertificationSchema{}
+			data, _ := bson.Marshal(item)
+			if err = bson.Unmarshal(data, &certification); err != nil {
+				return err
+			}
+			if _, err = m.Collection.UpdateOne(ctx,
+				bson.M{"_id": user.ID, "certifications": bson.M{"$elemMatch": bson.M{"identity": certification.Identity, "expire_time": bson.M{"$exists": false}}}},
+				bson.M{"$set": bson.M{"certifications.$.expire_time": expireTime(certification)}}); err != nil {
+				return err
+			}
+		}
+	}
+	return cur.Err()
+}
+
+// MigrateCertificationCampus 为缺少校区的已通过认证匹配校区
+func (m *UserModel) MigrateCertificationCampus(ctx context.Context, campuses *CampusModel) error {
+	noCampus := bson.M{"status": CertificationTrue, "campus": bson.M{"$exists": false}}
+	cur, err := m.Collection.Find(ctx, bson.M{"certifications": bson.M{"$elemMatch": noCampus}},
+		options.Find().SetProjection(bson.M{"certifications": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		user := UserSchema{}
+		if err = cur.Decode(&user); err != nil {
+			return err
+		}
+		for _, certification := range user.Certifications {
+			if certification.Status != CertificationTrue || !certification.Campus.IsZero() {
+				continue
+			}
+			campus := campuses.MatchCampus(certification.Email, certification.Data)
+			if campus.IsZero() {
+				continue
+			}
+			if _, err = m.Collection.UpdateOne(ctx, bson.M{"_id": user.ID},
+				bson.M{"$set": bson.M{"certifications.$[item].campus": campus}},
+				options.Update().SetArrayFilters(options.ArrayFilters{
+					Filters: []interface{}{bson.M{"item.identity": certification.Identity}},
+				})); err != nil {
+				return err
+			}
+		}
+	}
+	return cur.Err()
+}
+
+// RemoveCertificationCampus 将校区从所有认证中移除
+func (m *UserModel) RemoveCertificationCampus(campus primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	_, err := m.Collection.UpdateMany(ctx, bson.M{"certifications.campus": campus},
+		bson.M{"$unset": bson.M{"certifications.$[item].campus": ""}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"item.campus": campus}},
+		}))
+	return err
+}
+
 // AddSearchHistory 添加搜索历史
 func (m *UserModel) AddSearchHistory(id primitive.ObjectID, key string) error {
 	ctx, over := GetCtx()
@@ -407,3 +1065,113 @@ func (m *UserModel) GetAllUser() (res []primitive.ObjectID) {
 	}
 	return res
 }
+
+// SetUserBind 绑定 Violet 或微信账号，参数为空则不修改
+func (m *UserModel) SetUserBind(id primitive.ObjectID, violetID, wechatID string) error {
+	ctx, over := GetCtx()
+	defer over()
+	update := bson.M{}
+	if violetID != "" {
+		update["violet_id"] = violetID
+	}
+	if wechatID != "" {
+		update["wechat_id"] = wechatID
+	}
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": update}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
+
+// MergeUser 将用户 from 的账号数据合并到用户 to，并删除用户 from，应在其他数据转移完成后最后调用
+// 余额、积分和统计数据累加，信用取较低值，徽章合并，关注数据由集合合并后重新统计
+func (m *UserModel) MergeUser(to primitive.ObjectID, from UserSchema) error {
+	ctx, over := GetCtx()
+	defer over()
+	inc := bson.M{
+		"data.money":             from.Data.Money,
+		"data.value":             from.Data.Value,
+		"data.experience":        from.Data.Experience,
+		"data.publish_count":     from.Data.PublishCount,
+		"data.publish_run_count": from.Data.PublishRunCount,
+		"data.receive_count":     from.Data.ReceiveCount,
+		"data.receive_run_count": from.Data.ReceiveRunCount,
+		"data.violation_count":   from.Data.ViolationCount,
+	}
+	for kind, rating := range map[ReputationKind]RatingSchema{
+		ReputationPlayer:    from.Reputation.Player,
+		ReputationPublisher: from.Reputation.Publisher,
+	} {
+		prefix := "reputation." + string(kind) + "."
+		inc[prefix+"count"] = rating.Count
+		inc[prefix+"total"] = rating.Total
+		for level, count := range rating.Levels {
+			inc[prefix+"levels."+level] = count
+		}
+	}
+	set := bson.M{}
+	if from.VioletID != "" {
+		set["violet_id"] = from.VioletID
+		set["violet_name"] = from.VioletName
+	}
+	if from.WechatID != "" {
+		set["wechat_id"] = from.WechatID
+		set["wechat_name"] = from.WechatName
+	}
+	update := bson.M{
+		"$inc": inc,
+		"$min": bson.M{"data.credit": from.Data.Credit},
+		"$max": bson.M{"data.longest_streak": from.Data.LongestStreak},
+	}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if res, err := m.Collection.UpdateOne(ctx, bson.M{"_id": to}, update); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	if err := m.updateRatingAverage(ctx, to); err != nil {
+		return err
+	}
+	// 合并徽章，已拥有的徽章保留原获得时间
+	for _, badge := range from.Badges {
+		if _, err := m.Collection.UpdateOne(ctx, badgeFilter(to, badge),
+			bson.M{"$push": bson.M{"badges": badge}}); err != nil {
+			return err
+		}
+	}
+	_, err := m.Collection.DeleteOne(ctx, bson.M{"_id": from.ID})
+	return err
+}
+
+// AnonymizeUser 注销用户，清除个人信息和登陆凭证
+func (m *UserModel) AnonymizeUser(id primitive.ObjectID) error {
+	ctx, over := GetCtx()
+	defer over()
+	if res, err := m.Collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"wechat_id":   "",
+			"wechat_name": "",
+			"violet_id":   "",
+			"violet_name": "",
+			"info": UserInfoSchema{
+				Nickname: "已注销用户",
+				Avatar:   "https://coin-1252808268.cos.ap-guangzhou.myqcloud.com/avatar-5cfe5cab2cfbe5ed600f9665.png",
+			},
+			"nickname_key":        nicknameKey("已注销用户"),
+			"certifications":      []UserCertificationSchema{},
+			"data.search_history": []string{},
+			"delete_time":         time.Now().Unix(),
+		}}); err != nil {
+		return err
+	} else if res.MatchedCount < 1 {
+		return ErrNotExist
+	}
+	return nil
+}
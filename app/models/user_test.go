@@ -1,9 +1,11 @@
 package models
 
 import (
+	"sync"
 	"testing"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -12,6 +14,16 @@ func TestUserModel(t *testing.T) {
 	t.Run("InitRedis", testInitRedis)
 
 	t.Run("testUser", testUserModelAll)
+	t.Run("testReputation", testReputation)
+	t.Run("testSearchUsers", testSearchUsers)
+	t.Run("testExperience", testExperience)
+	t.Run("testSpendMoney", testSpendMoney)
+	t.Run("testChangeCredit", testChangeCredit)
+	t.Run("testBadge", testBadge)
+	t.Run("testCertification", testCertification)
+	t.Run("testMigrateCertification", testMigrateCertification)
+	t.Run("testMergeUser", testMergeUser)
+	t.Run("testAnonymizeUser", testAnonymizeUser)
 
 	ctx, finish := GetCtx()
 	defer finish()
@@ -99,7 +111,7 @@ func testUserModelAll(t *testing.T) {
 		t.Error(err)
 	}
 
-	if err = model.User.SetUserAttend(id); err != nil {
+	if err = model.User.SetUserAttend(id, 1); err != nil {
 		t.Error(err)
 	}
 
@@ -110,3 +122,394 @@ func testUserModelAll(t *testing.T) {
 	t.Log(user)
 
 }
+
+func testReputation(t *testing.T) {
+	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	review := ReviewSchema{
+		Task:    primitive.NewObjectID(),
+		User:    primitive.NewObjectID(),
+		Rating:  4,
+		Content: "不错",
+		Time:    time.Now().Unix(),
+	}
+	err = model.User.UpdateReputation(id, ReputationPlayer, 0, review)
+	if err != nil {
+		t.Error(err)
+	}
+	// 修改评价
+	review.Rating = 5
+	err = model.User.UpdateReputation(id, ReputationPlayer, 4, review)
+	if err != nil {
+		t.Error(err)
+	}
+	user, err := model.User.GetUserByID(id)
+	if err != nil {
+		t.Error(err)
+	}
+	rating := user.Reputation.Player
+	if rating.Count != 1 || rating.Total != 5 || rating.Levels["5"] != 1 || rating.Levels["4"] != 0 || len(rating.Recent) != 1 {
+		t.Error("wrong reputation", rating)
+	}
+	if rating.Average != 5 {
+		t.Error("wrong average", rating.Average)
+	}
+
+	// 旧版数据中 levels、recent 为 null
+	ctx, over := GetCtx()
+	defer over()
+	if _, err = model.User.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"reputation.publisher.levels": nil,
+		"reputation.publisher.recent": nil,
+	}}); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.MigrateRatingNull(ctx); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.UpdateReputation(id, ReputationPublisher, 0, review); err != nil {
+		t.Error(err)
+	}
+	if user, err = model.User.GetUserByID(id); err != nil || user.Reputation.Publisher.Levels["5"] != 1 {
+		t.Error("wrong reputation after migration", user.Reputation.Publisher, err)
+	}
+}
+
+func testSearchUsers(t *testing.T) {
+	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	err = model.User.SetUserInfoByID(id, UserInfoSchema{Nickname: "Searchable", School: "中山大学", Gender: GenderWoman})
+	if err != nil {
+		t.Error(err)
+	}
+	users, count, err := model.User.SearchUsers(UserSearchFilter{Key: "search"}, UserSortRelevance, 0, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 || len(users) != 1 || users[0].ID != id {
+		t.Error("wrong search result", count, users)
+	}
+	// 昵称前缀不区分大小写，与全文搜索同时使用
+	users, count, err = model.User.SearchUsers(UserSearchFilter{Key: "SEARCH"}, UserSortRelevance, 0, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 || len(users) != 1 || users[0].ID != id {
+		t.Error("wrong case-insensitive search result", count, users)
+	}
+	_, count, err = model.User.SearchUsers(UserSearchFilter{School: "中山大学", Gender: GenderWoman}, UserSortFollower, 0, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 {
+		t.Error("wrong school result", count)
+	}
+	// 未公开的学校不参与筛选
+	err = model.User.SetUserPrivacy(id, UserPrivacySchema{School: PrivacyPrivate})
+	if err != nil {
+		t.Error(err)
+	}
+	_, count, err = model.User.SearchUsers(UserSearchFilter{School: "中山大学"}, UserSortRating, 0, 10)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 0 {
+		t.Error("private school matched", count)
+	}
+}
+
+func testExperience(t *testing.T) {
+	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	err = model.User.UpdateUserDataCount(id, UserDataCount{Value: 10})
+	if err != nil {
+		t.Error(err)
+	}
+	// 扣除积分不影响累计获得积分
+	err = model.User.UpdateUserDataCount(id, UserDataCount{Value: -5})
+	if err != nil {
+		t.Error(err)
+	}
+	err = model.User.SetUserLevel(id, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	user, err := model.User.GetUserByID(id)
+	if err != nil {
+		t.Error(err)
+	}
+	if user.Data.Experience != 10 || user.Data.Level != 2 {
+		t.Error("wrong experience", user.Data)
+	}
+
+	// 旧版用户没有累计获得积分
+	ctx, over := GetCtx()
+	defer over()
+	oldID, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err = model.User.Collection.UpdateOne(ctx, bson.M{"_id": oldID}, bson.M{
+		"$set":   bson.M{"data.value": 7},
+		"$unset": bson.M{"data.experience": ""},
+	}); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.MigrateExperience(ctx, model.Log, []int64{0, 5, 100}); err != nil {
+		t.Error(err)
+	}
+	if user, err = model.User.GetUserByID(oldID); err != nil || user.Data.Experience != 7 || user.Data.Level != 2 {
+		t.Error("wrong migrated experience", user.Data, err)
+	}
+	// 已有累计获得积分的用户不受影响
+	if user, err = model.User.GetUserByID(id); err != nil || user.Data.Experience != 10 || user.Data.Level != 2 {
+		t.Error("wrong experience after migration", user.Data, err)
+	}
+}
+
+func testSpendMoney(t *testing.T) {
+	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	if err = model.User.UpdateUserDataCount(id, UserDataCount{Money: 10}); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.SpendMoney(id, 6); err != nil {
+		t.Error(err)
+	}
+	// 余额不足时不扣除
+	if err = model.User.SpendMoney(id, 6); err != ErrNotExist {
+		t.Error("spend more than balance", err)
+	}
+	user, err := model.User.GetUserByID(id)
+	if err != nil || user.Data.Money != 4 {
+		t.Error("wrong money", user.Data.Money, err)
+	}
+}
+
+func testChangeCredit(t *testing.T) {
+	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	// 并发调整时不超过上限
+	var wg sync.WaitGroup
+	deltas := make([]int64, 5)
+	for i := range deltas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			delta, err := model.User.ChangeCredit(id, 5, 110)
+			if err != nil {
+				t.Error(err)
+			}
+			deltas[i] = delta
+		}(i)
+	}
+	wg.Wait()
+	var sum int64
+	for _, delta := range deltas {
+		sum += delta
+	}
+	user, err := model.User.GetUserByID(id)
+	if err != nil || user.Data.Credit != 110 || sum != 10 {
+		t.Error("wrong credit", user.Data.Credit, sum, err)
+	}
+	// 不低于 0
+	delta, err := model.User.ChangeCredit(id, -200, 110)
+	if err != nil || delta != -110 {
+		t.Error("wrong delta", delta, err)
+	}
+}
+
+func testBadge(t *testing.T) {
+	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	added, err := model.User.AddUserBadge(id, "first_task", "")
+	if err != nil || !added {
+		t.Error("badge not added", err)
+	}
+	// 重复发放
+	added, err = model.User.AddUserBadge(id, "first_task", "")
+	if err != nil || added {
+		t.Error("badge added twice", err)
+	}
+	// 周期性徽章每个周期发放一次
+	for i, period := range []string{"2019-09", "2019-10", "2019-10"} {
+		added, err = model.User.AddUserBadge(id, "top_publisher", period)
+		if err != nil || added != (i < 2) {
+			t.Error("wrong periodic badge", period, added, err)
+		}
+	}
+	user, err := model.User.GetUserByID(id)
+	if err != nil || len(user.Badges) != 3 {
+		t.Error("wrong badges", user.Badges, err)
+	}
+}
+
+func testCertification(t *testing.T) {
+	id, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	err = model.User.SetUserCertification(id, UserCertificationSchema{
+		Identity: IdentityStudent,
+		Method:   CertificationByEmail,
+		Status:   CertificationCheckEmail,
+		Email:    "student@em.com",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	err = model.User.SetUserCertification(id, UserCertificationSchema{
+		Identity: IdentityAlumni,
+		Method:   CertificationByInvite,
+		Data:     "大山中学",
+		Status:   CertificationTrue,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	// 同一身份只保留一条认证
+	err = model.User.SetUserCertification(id, UserCertificationSchema{
+		Identity: IdentityStudent,
+		Method:   CertificationByEmail,
+		Status:   CertificationTrue,
+		Email:    "student@em.com",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	user, err := model.User.GetUserByID(id)
+	if err != nil || len(user.Certifications) != 2 || user.Certifications[0].Status != CertificationTrue {
+		t.Error(user.Certifications, err)
+	}
+	if model.User.CheckCertificationEmail("student@em.com") {
+		t.Error("email should be certified")
+	}
+	users, err := model.User.GetCertification([]CertificationStatus{CertificationTrue}, 1, 10)
+	if err != nil || len(users) == 0 {
+		t.Error(users, err)
+	}
+
+	// 认证过期
+	now := time.Now().Unix()
+	err = model.User.SetUserCertification(id, UserCertificationSchema{
+		Identity:   IdentityAlumni,
+		Method:     CertificationByInvite,
+		Data:       "大山中学",
+		Status:     CertificationTrue,
+		ExpireTime: now + 3600,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	users, err = model.User.GetExpiringCertifications(now + 7200)
+	if err != nil || len(users) != 1 || users[0].ID != id {
+		t.Error(users, err)
+	}
+	users, err = model.User.GetExpiredCertifications(now)
+	if err != nil || len(users) != 0 {
+		t.Error(users, err)
+	}
+	users, err = model.User.GetExpiredCertifications(now + 3600)
+	if err != nil || len(users) != 1 {
+		t.Error(users, err)
+	}
+}
+
+func testMigrateCertification(t *testing.T) {
+	legacyID, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	listID, err := model.User.AddUserByViolet(primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Error(err)
+	}
+	date := time.Now().AddDate(0, 0, -1).Unix()
+	ctx, over := GetCtx()
+	defer over()
+	// 旧版单一认证和缺少过期时间的认证列表
+	if _, err = model.User.Collection.UpdateOne(ctx, bson.M{"_id": legacyID}, bson.M{"$set": bson.M{"certification": bson.M{
+		"identity": IdentityStudent, "data": "中山大学", "status": CertificationTrue, "date": date,
+	}}}); err != nil {
+		t.Error(err)
+	}
+	if _, err = model.User.Collection.UpdateOne(ctx, bson.M{"_id": listID}, bson.M{"$set": bson.M{"certifications": bson.A{
+		bson.M{"identity": IdentityStudent, "data": "中山大学", "status": CertificationTrue, "date": date},
+		bson.M{"identity": IdentityTeacher, "data": "中山大学", "status": CertificationWait, "date": date},
+	}}}); err != nil {
+		t.Error(err)
+	}
+	if err = model.User.MigrateCertification(ctx, model.System, 10); err != nil {
+		t.Error(err)
+	}
+	expireTime := date + 10*24*3600
+	for _, id := range []primitive.ObjectID{legacyID, listID} {
+		user, err := model.User.GetUserByID(id)
+		if err != nil || len(user.Certifications) == 0 || user.Certifications[0].ExpireTime != expireTime {
+			t.Error("wrong expire time", user.Certifications, err)
+		}
+	}
+}
+
+func testMergeUser(t *testing.T) {
+	violetID := primitive.NewObjectID().Hex()
+	wechatID := primitive.NewObjectID().Hex()
+	to, err := model.User.AddUserByViolet(violetID)
+	if err != nil {
+		t.Error(err)
+	}
+	from, err := model.User.AddUserByWechat(wechatID)
+	if err != nil {
+		t.Error(err)
+	}
+	fromUser, err := model.User.GetUserByID(from)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = model.User.MergeUser(to, fromUser); err != nil {
+		t.Error(err)
+	}
+	if _, err = model.User.GetUserByID(from); err == nil {
+		t.Error("merged user not removed")
+	}
+	user, err := model.User.GetUserByWechat(wechatID)
+	if err != nil {
+		t.Error(err)
+	}
+	if user.ID != to || user.VioletID != violetID || user.Data.Money != 200 {
+		t.Error("wrong merged user", user)
+	}
+}
+
+func testAnonymizeUser(t *testing.T) {
+	violetID := primitive.NewObjectID().Hex()
+	id, err := model.User.AddUserByViolet(violetID)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = model.User.AnonymizeUser(id); err != nil {
+		t.Error(err)
+	}
+	if _, err = model.User.GetUserByViolet(violetID); err == nil {
+		t.Error("violet id not removed")
+	}
+	user, err := model.User.GetUserByID(id)
+	if err != nil {
+		t.Error(err)
+	}
+	if user.DeleteTime == 0 || user.Info.Nickname != "已注销用户" {
+		t.Error("wrong anonymized user", user)
+	}
+}
@@ -4,15 +4,16 @@ import (
 	"github.com/TimeForCoin/Server/app/models"
 	"github.com/TimeForCoin/Server/app/utils"
 	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ArticleService 公告服务
 type ArticleService interface {
 	GetArticles(page, size int64) (int64, []ArticleBrief)
-	AddArticle(userID primitive.ObjectID, title string, content string, publisher string, images []primitive.ObjectID) primitive.ObjectID
+	AddArticle(userID primitive.ObjectID, title string, content string, publisher string, images []primitive.ObjectID, meta models.RequestMeta) primitive.ObjectID
 	GetArticleByID(id primitive.ObjectID) ArticleDetail
-	SetArticleByID(userID primitive.ObjectID, id primitive.ObjectID, title, content, publisher string, images []primitive.ObjectID)
+	SetArticleByID(userID primitive.ObjectID, id primitive.ObjectID, title, content, publisher string, images []primitive.ObjectID, meta models.RequestMeta)
 }
 
 func newArticleService() ArticleService {
@@ -74,11 +75,8 @@ func (s *articleService) GetArticles(page, size int64) (count int64, articleList
 }
 
 // AddArticle 添加公告
-func (s *articleService) AddArticle(userID primitive.ObjectID, title string, content string, publisher string, images []primitive.ObjectID) primitive.ObjectID {
-	user, err := s.userModel.GetUserByID(userID)
-	utils.AssertErr(err, "invalid_session", 400)
-	utils.Assert(user.Data.Type == models.UserTypeAdmin || user.Data.Type == models.UserTypeRoot,
-		"permission_deny", 500)
+func (s *articleService) AddArticle(userID primitive.ObjectID, title string, content string, publisher string, images []primitive.ObjectID, meta models.RequestMeta) primitive.ObjectID {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionManageArticles)
 
 	articleID := primitive.NewObjectID()
 
@@ -93,6 +91,8 @@ func (s *articleService) AddArticle(userID primitive.ObjectID, title string, con
 
 	id, err := s.model.AddArticle(articleID, title, content, publisher, images)
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, userID, models.AuditAddArticle, models.AuditTargetArticle, id.Hex(), nil,
+		bson.M{"title": title, "content": content, "publisher": publisher, "images": images})
 	return id
 }
 
@@ -121,11 +121,10 @@ func (s *articleService) GetArticleByID(id primitive.ObjectID) ArticleDetail {
 }
 
 // SetArticleByID 根据ID修改公告文章
-func (s *articleService) SetArticleByID(userID primitive.ObjectID, id primitive.ObjectID, title, content, publisher string, images []primitive.ObjectID) {
-	user, err := s.userModel.GetUserByID(userID)
-	utils.AssertErr(err, "invalid_session", 400)
-	utils.Assert(user.Data.Type == models.UserTypeAdmin || user.Data.Type == models.UserTypeRoot,
-		"permission_deny", 500)
+func (s *articleService) SetArticleByID(userID primitive.ObjectID, id primitive.ObjectID, title, content, publisher string, images []primitive.ObjectID, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionManageArticles)
+	article, err := s.model.GetArticleByID(id)
+	utils.AssertErr(err, "faked_article", 403)
 
 	for _, imageID := range images {
 		_, err := s.fileModel.GetFile(imageID)
@@ -170,6 +169,9 @@ func (s *articleService) SetArticleByID(userID primitive.ObjectID, id primitive.
 
 	err = s.model.SetArticleByID(id, title, content, publisher, images)
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, userID, models.AuditSetArticle, models.AuditTargetArticle, id.Hex(),
+		bson.M{"title": article.Title, "content": article.Content, "publisher": article.Publisher, "images": article.Images},
+		bson.M{"title": title, "content": content, "publisher": publisher, "images": images})
 
 	// 删除无用文件
 	for _, file := range toRemove {
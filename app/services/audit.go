@@ -0,0 +1,73 @@
+package services
+
+import (
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditService 管理操作审计服务
+type AuditService interface {
+	GetAudits(rootID primitive.ObjectID, filter models.AuditFilter, page, size int64) (int64, []AuditDetail)
+	// 内部服务
+	record(meta models.RequestMeta, actor primitive.ObjectID, action models.AuditAction,
+		targetType models.AuditTarget, target string, before, after bson.M)
+}
+
+// newAuditService 初始化
+func newAuditService() AuditService {
+	return &auditService{
+		model: models.GetModel().Audit,
+		cache: models.GetRedis().Cache,
+	}
+}
+
+type auditService struct {
+	model *models.AuditModel
+	cache *models.CacheModel
+}
+
+// AuditDetail 审计记录详情
+type AuditDetail struct {
+	*models.AuditSchema
+	Actor models.UserBaseInfo
+}
+
+// GetAudits 查询审计记录[超级管理员]
+func (s *auditService) GetAudits(rootID primitive.ObjectID, filter models.AuditFilter, page, size int64) (int64, []AuditDetail) {
+	root, err := s.cache.GetUserBaseInfo(rootID)
+	utils.AssertErr(err, "invalid_session", 401)
+	utils.Assert(root.Type == models.UserTypeRoot, "permission_deny", 403)
+
+	audits, count, err := s.model.GetAudits(filter, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := make([]AuditDetail, len(audits))
+	for i := range audits {
+		res[i] = AuditDetail{
+			AuditSchema: &audits[i],
+			Actor:       GetServiceManger().User.GetUserBaseInfo(audits[i].Actor),
+		}
+	}
+	return count, res
+}
+
+// record 记录管理操作
+func (s *auditService) record(meta models.RequestMeta, actor primitive.ObjectID, action models.AuditAction,
+	targetType models.AuditTarget, target string, before, after bson.M) {
+	err := s.model.AddAudit(models.AuditSchema{
+		Actor:      actor,
+		Action:     action,
+		TargetType: targetType,
+		Target:     target,
+		Before:     before,
+		After:      after,
+		Meta:       meta,
+	})
+	// 操作已经完成，审计记录写入失败不影响请求结果
+	if err != nil {
+		log.Error().Err(err).Str("action", string(action)).Str("target", target).Msg("Failure to record audit")
+	}
+}
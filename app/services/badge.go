@@ -0,0 +1,165 @@
+package services
+
+import (
+	"time"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BadgeEvent 徽章触发事件
+type BadgeEvent string
+
+// BadgeEvent 徽章触发事件
+const (
+	BadgeTaskFinish   BadgeEvent = "task_finish"   // 完成任务
+	BadgeFiveStar     BadgeEvent = "five_star"     // 获得五星评价
+	BadgeAttendance   BadgeEvent = "attendance"    // 连续签到
+	BadgeCertified    BadgeEvent = "certified"     // 通过认证
+	BadgeTopPublisher BadgeEvent = "top_publisher" // 月度发布任务最多
+)
+
+// BadgeService 徽章服务
+type BadgeService interface {
+	// 内部服务
+	onEvent(userID primitive.ObjectID, event BadgeEvent)
+	awardTopPublisher()
+	getUserBadges(badges []models.BadgeSchema) []BadgeDetail
+}
+
+// newBadgeService 初始化
+func newBadgeService() BadgeService {
+	return &badgeService{
+		userModel:       models.GetModel().User,
+		taskModel:       models.GetModel().Task,
+		taskStatusModel: models.GetModel().TaskStatus,
+		messageModel:    models.GetModel().Message,
+	}
+}
+
+type badgeService struct {
+	userModel       *models.UserModel
+	taskModel       *models.TaskModel
+	taskStatusModel *models.TaskStatusModel
+	messageModel    *models.MessageModel
+}
+
+// BadgeDetail 徽章详情
+type BadgeDetail struct {
+	ID          string
+	Name        string
+	Description string
+	Icon        string
+	Time        int64  // 获得时间
+	Period      string // 周期性徽章的获得周期
+}
+
+// badges 获取徽章配置
+func (s *badgeService) badges() []utils.BadgeConfig {
+	if conf := utils.GetConf(); conf != nil {
+		return conf.Badges
+	}
+	return nil
+}
+
+// onEvent 事件发生后检查并发放对应徽章
+func (s *badgeService) onEvent(userID primitive.ObjectID, event BadgeEvent) {
+	var badges []utils.BadgeConfig
+	for _, badge := range s.badges() {
+		if BadgeEvent(badge.Event) == event {
+			badges = append(badges, badge)
+		}
+	}
+	if len(badges) == 0 {
+		return
+	}
+	user, err := s.userModel.GetUserByID(userID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+
+	var value int64
+	switch event {
+	case BadgeTaskFinish:
+		value, err = s.taskStatusModel.CountPlayerStatus(userID, models.PlayerFinish)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	case BadgeFiveStar:
+		value = user.Reputation.Player.Levels["5"] + user.Reputation.Publisher.Levels["5"]
+	case BadgeAttendance:
+		value = user.Data.AttendanceStreak
+	case BadgeCertified:
+		if hasCertification(user, nil, nil) {
+			value = 1
+		}
+	}
+	for _, badge := range badges {
+		if event == BadgeCertified && badge.Identity != "" &&
+			!hasCertification(user, []models.UserIdentity{models.UserIdentity(badge.Identity)}, nil) {
+			continue
+		}
+		if value >= badge.Threshold && value > 0 {
+			s.award(userID, badge, "")
+		}
+	}
+}
+
+// award 发放徽章，已拥有时不重复发放，周期性徽章每个周期发放一次
+func (s *badgeService) award(userID primitive.ObjectID, badge utils.BadgeConfig, period string) {
+	added, err := s.userModel.AddUserBadge(userID, badge.ID, period)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	if !added {
+		return
+	}
+	_, err = s.messageModel.AddMessage(userID, models.MessageTypeSystem, models.MessageSchema{
+		Title:   "恭喜你获得徽章「" + badge.Name + "」",
+		Content: badge.Description,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// awardTopPublisher 为上月发布任务最多的用户发放徽章
+func (s *badgeService) awardTopPublisher() {
+	var badges []utils.BadgeConfig
+	for _, badge := range s.badges() {
+		if BadgeEvent(badge.Event) == BadgeTopPublisher {
+			badges = append(badges, badge)
+		}
+	}
+	if len(badges) == 0 {
+		return
+	}
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	start := end.AddDate(0, -1, 0)
+	publisher, count, err := s.taskModel.GetTopPublisher(start.Unix(), end.Unix())
+	if err == models.ErrNotExist {
+		return
+	}
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, badge := range badges {
+		if count >= badge.Threshold {
+			s.award(publisher, badge, start.Format("2006-01"))
+		}
+	}
+}
+
+// getUserBadges 获取用户徽章详情，忽略已从配置中移除的徽章
+func (s *badgeService) getUserBadges(badges []models.BadgeSchema) []BadgeDetail {
+	res := []BadgeDetail{}
+	for _, b := range badges {
+		for _, badge := range s.badges() {
+			if badge.ID == b.ID {
+				res = append(res, BadgeDetail{
+					ID:          badge.ID,
+					Name:        badge.Name,
+					Description: badge.Description,
+					Icon:        badge.Icon,
+					Time:        b.Time,
+					Period:      b.Period,
+				})
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"time"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BanService 封禁服务
+type BanService interface {
+	BanUser(adminID, userID primitive.ObjectID, reason string, days int64, meta models.RequestMeta)
+	UnbanUser(adminID, userID primitive.ObjectID, meta models.RequestMeta)
+	GetBans(adminID, userID primitive.ObjectID, page, size int64) (int64, []BanDetail)
+	GetActiveBan(userID primitive.ObjectID) *BanDetail
+	IsBanned(userID primitive.ObjectID) bool
+	// 内部服务
+	banUser(userID, adminID primitive.ObjectID, reason string, endTime int64)
+	liftBans(userID, adminID primitive.ObjectID) models.UserType
+	expireBans()
+}
+
+// newBanService 初始化
+func newBanService() BanService {
+	return &banService{
+		model:        models.GetModel().Ban,
+		userModel:    models.GetModel().User,
+		messageModel: models.GetModel().Message,
+		cache:        models.GetRedis().Cache,
+	}
+}
+
+type banService struct {
+	model        *models.BanModel
+	userModel    *models.UserModel
+	messageModel *models.MessageModel
+	cache        *models.CacheModel
+}
+
+// BanDetail 封禁详情
+type BanDetail struct {
+	*models.BanSchema
+	Admin     models.UserBaseInfo
+	LiftAdmin *models.UserBaseInfo `json:",omitempty"`
+}
+
+// BanUser 封禁用户[管理员]，days 为 0 时永久封禁
+func (s *banService) BanUser(adminID, userID primitive.ObjectID, reason string, days int64, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+	user, err := s.cache.GetUserBaseInfo(userID)
+	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(user.Type == models.UserTypeNormal || user.Type == models.UserTypeBan, "not_allow_user", 403)
+	var endTime int64
+	if days > 0 {
+		endTime = time.Now().AddDate(0, 0, int(days)).Unix()
+	}
+	s.banUser(userID, adminID, reason, endTime)
+	GetServiceManger().Audit.record(meta, adminID, models.AuditBanUser, models.AuditTargetUser, userID.Hex(),
+		bson.M{"type": user.Type}, bson.M{"type": models.UserTypeBan, "reason": reason, "end_time": endTime})
+}
+
+// UnbanUser 解除封禁[管理员]
+func (s *banService) UnbanUser(adminID, userID primitive.ObjectID, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+	user, err := s.cache.GetUserBaseInfo(userID)
+	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(user.Type == models.UserTypeBan, "not_banned", 403)
+	before := bson.M{"type": user.Type}
+	if ban, err := s.model.GetActiveBan(userID); err == nil {
+		before["reason"] = ban.Reason
+		before["end_time"] = ban.EndTime
+	}
+	userType := s.liftBans(userID, adminID)
+	GetServiceManger().Audit.record(meta, adminID, models.AuditUnbanUser, models.AuditTargetUser, userID.Hex(),
+		before, bson.M{"type": userType})
+}
+
+// GetBans 获取用户的封禁记录[管理员]
+func (s *banService) GetBans(adminID, userID primitive.ObjectID, page, size int64) (int64, []BanDetail) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+	bans, count, err := s.model.GetBansByUser(userID, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := make([]BanDetail, len(bans))
+	for i := range bans {
+		res[i] = s.makeBanDetail(&bans[i])
+	}
+	return count, res
+}
+
+// GetActiveBan 获取用户当前生效的封禁，未被封禁时返回 nil
+func (s *banService) GetActiveBan(userID primitive.ObjectID) *BanDetail {
+	ban, err := s.model.GetActiveBan(userID)
+	if err == models.ErrNotExist {
+		return nil
+	}
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	detail := s.makeBanDetail(&ban)
+	return &detail
+}
+
+// IsBanned 用户是否处于封禁状态
+func (s *banService) IsBanned(userID primitive.ObjectID) bool {
+	user, err := s.cache.GetUserBaseInfo(userID)
+	if err != nil {
+		return false
+	}
+	return user.Type == models.UserTypeBan
+}
+
+func (s *banService) makeBanDetail(ban *models.BanSchema) BanDetail {
+	detail := BanDetail{BanSchema: ban}
+	if ban.Admin.IsZero() {
+		detail.Admin = models.UserBaseInfo{Nickname: "系统检测"}
+	} else {
+		detail.Admin = GetServiceManger().User.GetUserBaseInfo(ban.Admin)
+	}
+	if !ban.LiftAdmin.IsZero() {
+		liftAdmin := GetServiceManger().User.GetUserBaseInfo(ban.LiftAdmin)
+		detail.LiftAdmin = &liftAdmin
+	}
+	return detail
+}
+
+// reportBanEndTime 处理举报封禁的结束时间
+func reportBanEndTime() int64 {
+	if conf := utils.GetConf(); conf != nil && conf.ReportBanDays > 0 {
+		return time.Now().AddDate(0, 0, int(conf.ReportBanDays)).Unix()
+	}
+	return 0
+}
+
+// banUser 封禁用户并通过系统消息告知原因，adminID 为空表示系统自动封禁，endTime 为 0 表示永久封禁
+func (s *banService) banUser(userID, adminID primitive.ObjectID, reason string, endTime int64) {
+	if reason == "" {
+		reason = "违反社区规范"
+	}
+	user, err := s.userModel.GetUserByID(userID)
+	utils.AssertErr(err, "faked_user", 403)
+	// 记录封禁前的用户类型，已被封禁时沿用原有记录
+	prevType := user.Data.Type
+	if prevType == models.UserTypeBan {
+		prevType = s.prevType(userID)
+	}
+	_, err = s.model.AddBan(models.BanSchema{
+		User:     userID,
+		Admin:    adminID,
+		Reason:   reason,
+		EndTime:  endTime,
+		PrevType: prevType,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.userModel.SetUserType(userID, models.UserTypeBan)
+	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(s.cache.WillUpdate(userID, models.KindOfBaseInfo) == nil, "redis_error", iris.StatusInternalServerError)
+	utils.Assert(s.cache.WillUpdate(userID, models.KindOfPermission) == nil, "redis_error", iris.StatusInternalServerError)
+
+	content := "封禁原因：" + reason + "\n解封时间："
+	if endTime == 0 {
+		content += "永久"
+	} else {
+		content += time.Unix(endTime, 0).Format("2006-01-02 15:04")
+	}
+	_, err = s.messageModel.AddMessage(userID, models.MessageTypeSystem, models.MessageSchema{
+		Title:   "你的账号已被封禁",
+		Content: content,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// prevType 获取用户封禁前的类型，没有记录时为普通用户
+func (s *banService) prevType(userID primitive.ObjectID) models.UserType {
+	ban, err := s.model.GetActiveBan(userID)
+	if err != models.ErrNotExist {
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+	if ban.PrevType == "" || ban.PrevType == models.UserTypeBan {
+		return models.UserTypeNormal
+	}
+	return ban.PrevType
+}
+
+// liftBans 解除用户全部封禁并恢复封禁前的用户类型
+func (s *banService) liftBans(userID, adminID primitive.ObjectID) models.UserType {
+	userType := s.prevType(userID)
+	err := s.model.LiftBans(userID, adminID)
+	if err != models.ErrNotExist {
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+	s.restoreUser(userID, userType, "你的账号已被管理员解除封禁")
+	return userType
+}
+
+// restoreUser 恢复封禁用户的类型并通知
+func (s *banService) restoreUser(userID primitive.ObjectID, userType models.UserType, title string) {
+	err := s.userModel.SetUserType(userID, userType)
+	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(s.cache.WillUpdate(userID, models.KindOfBaseInfo) == nil, "redis_error", iris.StatusInternalServerError)
+	utils.Assert(s.cache.WillUpdate(userID, models.KindOfPermission) == nil, "redis_error", iris.StatusInternalServerError)
+	_, err = s.messageModel.AddMessage(userID, models.MessageTypeSystem, models.MessageSchema{
+		Title:   title,
+		Content: "请遵守社区规范，多次违规将被永久封禁",
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// expireBans 处理到期的封禁，用户没有其他生效中的封禁时自动解封
+func (s *banService) expireBans() {
+	bans, err := s.model.GetExpiredBans(time.Now().Unix())
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, ban := range bans {
+		if err = s.model.ExpireBan(ban.ID); err != nil {
+			continue
+		}
+		if _, err = s.model.GetActiveBan(ban.User); err != models.ErrNotExist {
+			continue
+		}
+		user, err := s.userModel.GetUserByID(ban.User)
+		if err != nil || user.Data.Type != models.UserTypeBan {
+			continue
+		}
+		userType := ban.PrevType
+		if userType == "" || userType == models.UserTypeBan {
+			userType = models.UserTypeNormal
+		}
+		s.restoreUser(ban.User, userType, "你的账号封禁已到期")
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CampusService 校区服务
+type CampusService interface {
+	GetCampuses() []CampusDetail
+	GetCampus(id primitive.ObjectID) CampusDetail
+	GetUserCampuses(userID primitive.ObjectID) []primitive.ObjectID
+	AddCampus(adminID primitive.ObjectID, name string, domains []string, meta models.RequestMeta) primitive.ObjectID
+	SetCampus(userID, id primitive.ObjectID, name string, domains []string, meta models.RequestMeta)
+	RemoveCampus(adminID, id primitive.ObjectID, meta models.RequestMeta)
+	AddCampusAdmin(adminID, id, userID primitive.ObjectID, meta models.RequestMeta)
+	RemoveCampusAdmin(adminID, id, userID primitive.ObjectID, meta models.RequestMeta)
+	SetTaskHidden(userID, id, taskID primitive.ObjectID, hidden bool, meta models.RequestMeta)
+	// 内部服务
+	userCampuses(user models.UserSchema) []primitive.ObjectID
+	matchCampus(email, data string) primitive.ObjectID
+	checkPublishCampuses(user models.UserSchema, campuses []primitive.ObjectID)
+	canViewTask(task models.TaskSchema, userID primitive.ObjectID) bool
+}
+
+// newCampusService 初始化
+func newCampusService() CampusService {
+	return &campusService{
+		model:     models.GetModel().Campus,
+		userModel: models.GetModel().User,
+		taskModel: models.GetModel().Task,
+		cache:     models.GetRedis().Cache,
+	}
+}
+
+type campusService struct {
+	model     *models.CampusModel
+	userModel *models.UserModel
+	taskModel *models.TaskModel
+	cache     *models.CacheModel
+}
+
+// CampusDetail 校区详情
+type CampusDetail struct {
+	*models.CampusSchema
+	Admins []models.UserBaseInfo
+}
+
+// GetCampuses 获取校区列表
+func (s *campusService) GetCampuses() []CampusDetail {
+	campuses, err := s.model.GetCampuses()
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := []CampusDetail{}
+	for i := range campuses {
+		res = append(res, s.makeCampusDetail(&campuses[i]))
+	}
+	return res
+}
+
+// GetCampus 获取校区详情
+func (s *campusService) GetCampus(id primitive.ObjectID) CampusDetail {
+	campus, err := s.model.GetCampusByID(id)
+	utils.AssertErr(err, "faked_campus", 403)
+	return s.makeCampusDetail(&campus)
+}
+
+// GetUserCampuses 获取用户通过认证的校区
+func (s *campusService) GetUserCampuses(userID primitive.ObjectID) []primitive.ObjectID {
+	user, err := s.userModel.GetUserByID(userID)
+	if err != nil {
+		return nil
+	}
+	return s.userCampuses(user)
+}
+
+// AddCampus 添加校区
+func (s *campusService) AddCampus(adminID primitive.ObjectID, name string, domains []string, meta models.RequestMeta) primitive.ObjectID {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionManageCampus)
+	_, err := s.model.GetCampusByName(name)
+	utils.Assert(err == models.ErrNotExist, "exist_campus", 403)
+	s.checkDomains(primitive.NilObjectID, domains)
+	id, err := s.model.AddCampus(models.CampusSchema{
+		Name:    name,
+		Domains: domains,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, adminID, models.AuditSetCampus, models.AuditTargetCampus, id.Hex(),
+		nil, bson.M{"name": name, "domains": domains})
+	return id
+}
+
+// SetCampus 修改校区，校区管理员只能修改邮箱后缀
+func (s *campusService) SetCampus(userID, id primitive.ObjectID, name string, domains []string, meta models.RequestMeta) {
+	campus, err := s.model.GetCampusByID(id)
+	utils.AssertErr(err, "faked_campus", 403)
+	if name != "" && name != campus.Name {
+		GetServiceManger().Role.checkPermission(userID, models.PermissionManageCampus)
+		_, err = s.model.GetCampusByName(name)
+		utils.Assert(err == models.ErrNotExist, "exist_campus", 403)
+	} else {
+		s.checkCampusAdmin(userID, campus)
+	}
+	if domains != nil {
+		s.checkDomains(id, domains)
+	}
+	err = s.model.SetCampus(id, name, domains)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, userID, models.AuditSetCampus, models.AuditTargetCampus, id.Hex(),
+		bson.M{"name": campus.Name, "domains": campus.Domains}, bson.M{"name": name, "domains": domains})
+}
+
+// RemoveCampus 删除校区，并取消任务的校区限制和认证所属的校区
+func (s *campusService) RemoveCampus(adminID, id primitive.ObjectID, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionManageCampus)
+	campus, err := s.model.GetCampusByID(id)
+	utils.AssertErr(err, "faked_campus", 403)
+	err = s.model.RemoveCampus(id)
+	utils.AssertErr(err, "faked_campus", 403)
+	err = s.taskModel.RemoveTaskCampus(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.userModel.RemoveCertificationCampus(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, adminID, models.AuditRemoveCampus, models.AuditTargetCampus, id.Hex(),
+		bson.M{"name": campus.Name, "domains": campus.Domains}, nil)
+}
+
+// AddCampusAdmin 添加校区管理员
+func (s *campusService) AddCampusAdmin(adminID, id, userID primitive.ObjectID, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionManageCampus)
+	_, err := s.userModel.GetUserByID(userID)
+	utils.AssertErr(err, "faked_user", 403)
+	err = s.model.AddCampusAdmin(id, userID)
+	utils.AssertErr(err, "faked_campus", 403)
+	GetServiceManger().Audit.record(meta, adminID, models.AuditSetCampusAdmin, models.AuditTargetCampus, id.Hex(),
+		nil, bson.M{"admin": userID})
+}
+
+// RemoveCampusAdmin 移除校区管理员
+func (s *campusService) RemoveCampusAdmin(adminID, id, userID primitive.ObjectID, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionManageCampus)
+	err := s.model.RemoveCampusAdmin(id, userID)
+	utils.AssertErr(err, "faked_admin", 403)
+	GetServiceManger().Audit.record(meta, adminID, models.AuditSetCampusAdmin, models.AuditTargetCampus, id.Hex(),
+		bson.M{"admin": userID}, nil)
+}
+
+// SetTaskHidden 屏蔽或恢复校区内的任务[校区管理员]
+func (s *campusService) SetTaskHidden(userID, id, taskID primitive.ObjectID, hidden bool, meta models.RequestMeta) {
+	campus, err := s.model.GetCampusByID(id)
+	utils.AssertErr(err, "faked_campus", 403)
+	s.checkCampusAdmin(userID, campus)
+	task, err := s.taskModel.GetTaskByID(taskID)
+	utils.AssertErr(err, "faked_task", 403)
+	utils.Assert(containsID(task.Campuses, id), "not_campus_task", 403)
+	err = s.taskModel.SetTaskHidden(taskID, hidden)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, userID, models.AuditSetTaskHidden, models.AuditTargetTask, taskID.Hex(),
+		bson.M{"hidden": task.IsHidden}, bson.M{"hidden": hidden, "campus": id})
+}
+
+// userCampuses 获取用户有效认证所属的校区
+func (s *campusService) userCampuses(user models.UserSchema) []primitive.ObjectID {
+	var campuses []primitive.ObjectID
+	for _, certification := range user.Certifications {
+		if certification.Status == models.CertificationTrue && !certification.Campus.IsZero() &&
+			!containsID(campuses, certification.Campus) {
+			campuses = append(campuses, certification.Campus)
+		}
+	}
+	return campuses
+}
+
+// matchCampus 根据认证邮箱后缀或认证内容匹配校区，匹配失败返回空 ID
+func (s *campusService) matchCampus(email, data string) primitive.ObjectID {
+	return s.model.MatchCampus(email, data)
+}
+
+// checkPublishCampuses 检查用户是否可以向这些校区发布任务
+func (s *campusService) checkPublishCampuses(user models.UserSchema, campuses []primitive.ObjectID) {
+	if len(campuses) == 0 || GetServiceManger().Role.HasPermission(user.ID, models.PermissionManageCampus) {
+		for _, id := range campuses {
+			_, err := s.model.GetCampusByID(id)
+			utils.AssertErr(err, "faked_campus", 403)
+		}
+		return
+	}
+	mine := s.userCampuses(user)
+	for _, id := range campuses {
+		utils.Assert(containsID(mine, id), "not_campus_member", 403)
+	}
+}
+
+// canViewTask 用户是否可以查看任务，未开放跨校区的任务仅本校区用户和发布者可见
+func (s *campusService) canViewTask(task models.TaskSchema, userID primitive.ObjectID) bool {
+	if len(task.Campuses) == 0 || task.CrossCampus || task.Publisher == userID {
+		return true
+	}
+	if userID.IsZero() {
+		return false
+	}
+	for _, id := range s.GetUserCampuses(userID) {
+		if containsID(task.Campuses, id) {
+			return true
+		}
+	}
+	return GetServiceManger().Role.HasPermission(userID, models.PermissionManageCampus)
+}
+
+// checkCampusAdmin 检查校区管理员权限，拥有校区管理权限的用户可管理所有校区
+func (s *campusService) checkCampusAdmin(userID primitive.ObjectID, campus models.CampusSchema) {
+	if containsID(campus.Admins, userID) {
+		return
+	}
+	GetServiceManger().Role.checkPermission(userID, models.PermissionManageCampus)
+}
+
+// checkDomains 检查邮箱后缀没有被其他校区使用
+func (s *campusService) checkDomains(id primitive.ObjectID, domains []string) {
+	for _, domain := range domains {
+		utils.Assert(domain != "" && !strings.Contains(domain, "@"), "invalid_domain", 400)
+		campus, err := s.model.GetCampusByDomain(domain)
+		utils.Assert(err == models.ErrNotExist || campus.ID == id, "exist_domain", 403)
+	}
+}
+
+// makeCampusDetail 组合校区详情
+func (s *campusService) makeCampusDetail(campus *models.CampusSchema) CampusDetail {
+	res := CampusDetail{
+		CampusSchema: campus,
+		Admins:       []models.UserBaseInfo{},
+	}
+	for _, id := range campus.Admins {
+		res.Admins = append(res.Admins, GetServiceManger().User.GetUserBaseInfo(id))
+	}
+	return res
+}
+
+// containsID ID 列表中是否包含指定 ID
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -11,7 +11,7 @@ import (
 type CommentService interface {
 	AddCommentForTask(userID, taskID primitive.ObjectID, content string)
 	AddCommentForComment(userID, commentID primitive.ObjectID, content string)
-	RemoveComment(userID, commentID primitive.ObjectID)
+	RemoveComment(userID, commentID primitive.ObjectID, meta models.RequestMeta)
 	ChangeLike(userID, commentID primitive.ObjectID, like bool)
 	GetComments(contentID primitive.ObjectID, userID string, page, size int64, sort string) []CommentData
 }
@@ -19,18 +19,20 @@ type CommentService interface {
 // NewUserService 初始化
 func newCommentService() CommentService {
 	return &commentService{
-		model:     models.GetModel().Comment,
-		taskModel: models.GetModel().Task,
-		setModel:  models.GetModel().Set,
-		cache:     models.GetRedis().Cache,
+		model:         models.GetModel().Comment,
+		taskModel:     models.GetModel().Task,
+		setModel:      models.GetModel().Set,
+		reactionModel: models.GetModel().Reaction,
+		cache:         models.GetRedis().Cache,
 	}
 }
 
 type commentService struct {
-	model     *models.CommentModel
-	taskModel *models.TaskModel
-	setModel  *models.SetModel
-	cache     *models.CacheModel
+	model         *models.CommentModel
+	taskModel     *models.TaskModel
+	setModel      *models.SetModel
+	reactionModel *models.ReactionModel
+	cache         *models.CacheModel
 }
 
 // AddCommentForTask 为任务添加评论
@@ -38,8 +40,13 @@ func (s *commentService) AddCommentForTask(userID, taskID primitive.ObjectID, co
 	task, err := s.taskModel.GetTaskByID(taskID)
 	utils.AssertErr(err, "faked_content", 403)
 	utils.Assert(task.Status != models.TaskStatusDraft, "not_allow_status", 403)
-	err = s.model.AddComment(taskID, task.Publisher, userID, content, false)
+	utils.Assert(!s.cache.IsBlockUser(task.Publisher, userID), "blocked", 403)
+	content, review := GetServiceManger().Filter.CheckContent(content)
+	id, err := s.model.AddComment(taskID, task.Publisher, userID, content, false)
 	utils.AssertErr(err, "", 500)
+	if review {
+		GetServiceManger().Filter.AddReview(userID, models.ReportTargetComment, id, 0, content)
+	}
 	err = s.taskModel.InsertCount(taskID, models.CommentCount, 1)
 	utils.AssertErr(err, "", 500)
 }
@@ -49,23 +56,32 @@ func (s *commentService) AddCommentForComment(userID, commentID primitive.Object
 	comment, err := s.model.GetCommentByID(commentID)
 	utils.AssertErr(err, "faked_content", 403)
 	utils.Assert(comment.IsReply == false, "faked_content", 403)
-	err = s.model.AddComment(commentID, comment.UserID, userID, content, true)
+	utils.Assert(!s.cache.IsBlockUser(comment.UserID, userID) &&
+		!s.cache.IsBlockUser(comment.ContentOwn, userID), "blocked", 403)
+	content, review := GetServiceManger().Filter.CheckContent(content)
+	id, err := s.model.AddComment(commentID, comment.UserID, userID, content, true)
 	utils.AssertErr(err, "", 500)
+	if review {
+		GetServiceManger().Filter.AddReview(userID, models.ReportTargetComment, id, 0, content)
+	}
 	err = s.model.InsertCount(commentID, models.ReplyCount, 1)
 	utils.AssertErr(err, "", 500)
 }
 
 // RemoveComment 删除评论
-func (s *commentService) RemoveComment(userID, commentID primitive.ObjectID) {
+func (s *commentService) RemoveComment(userID, commentID primitive.ObjectID, meta models.RequestMeta) {
 	comment, err := s.model.GetCommentByID(commentID)
 	utils.AssertErr(err, "faked_comment", 403)
 	if comment.UserID != userID {
-		userInfo, err := s.cache.GetUserBaseInfo(userID)
-		utils.AssertErr(err, "", 500)
-		utils.Assert(userInfo.Type == models.UserTypeAdmin || userInfo.Type == models.UserTypeRoot, "permission_deny", 403)
+		GetServiceManger().Role.checkPermission(userID, models.PermissionModerateContent)
 	}
 	err = s.model.RemoveContentByID(commentID)
 	utils.AssertErr(err, "", 500)
+	// 删除他人评论需要记录
+	if comment.UserID != userID {
+		GetServiceManger().Audit.record(meta, userID, models.AuditRemoveComment, models.AuditTargetComment, commentID.Hex(),
+			bson.M{"user_id": comment.UserID, "content_id": comment.ContentID, "content": comment.Content}, nil)
+	}
 }
 
 // ChangeLike 改变点赞状态
@@ -74,18 +90,17 @@ func (s *commentService) ChangeLike(userID, commentID primitive.ObjectID, like b
 	utils.AssertErr(err, "faked_comment", 403)
 	utils.Assert(comment.IsDelete == false, "deleted_comment", 403)
 	if like {
-		err := s.setModel.AddToSet(userID, commentID, models.SetOfLikeComment)
-		utils.AssertErr(err, "exist_like", 403)
+		err := s.reactionModel.AddReaction(userID, models.ReactionLikeComment, commentID, primitive.NilObjectID)
+		utils.Assert(err != models.ErrExist, "exist_like", 403)
+		utils.AssertErr(err, "", 500)
 		err = s.model.InsertCount(commentID, models.LikeCount, 1)
 		utils.AssertErr(err, "", 500)
 	} else {
-		err := s.setModel.RemoveFromSet(userID, commentID, models.SetOfLikeComment)
+		err := s.reactionModel.RemoveReaction(userID, models.ReactionLikeComment, commentID)
 		utils.AssertErr(err, "faked_like", 403)
 		err = s.model.InsertCount(commentID, models.LikeCount, -1)
 		utils.AssertErr(err, "", 500)
 	}
-	err = s.cache.WillUpdate(userID, models.KindOfLikeComment)
-	utils.AssertErr(err, "", 500)
 }
 
 // CommentWithUserInfo 带用户信息的评论数据
@@ -115,7 +130,14 @@ func (s *commentService) GetComments(contentID primitive.ObjectID, userID string
 	} else {
 		sortRule["like_count"] = -1
 	}
-	comments, err := s.model.GetCommentsByContent(contentID, page, size, sortRule)
+	// 隐藏已屏蔽用户的评论
+	var blocked []primitive.ObjectID
+	if userID != "" {
+		_id, err := primitive.ObjectIDFromHex(userID)
+		utils.AssertErr(err, "", 500)
+		blocked = s.setModel.GetSets(_id, models.SetOfBlockUser).BlockUserID
+	}
+	comments, err := s.model.GetCommentsByContent(contentID, page, size, sortRule, blocked...)
 	utils.AssertErr(err, "faked_content", 403)
 
 	if len(comments) == 0 {
@@ -135,11 +157,11 @@ func (s *commentService) GetComments(contentID primitive.ObjectID, userID string
 		if userID != "" && c.IsDelete == false {
 			_id, err := primitive.ObjectIDFromHex(userID)
 			utils.AssertErr(err, "", 500)
-			comment.Liked = s.cache.IsLikeComment(_id, c.ID)
+			comment.Liked = s.reactionModel.HasReaction(_id, models.ReactionLikeComment, c.ID)
 		}
 		if !c.IsReply && c.ReplyCount > 0 {
 			// 默认显示最先5条回复
-			replies, err := s.model.GetCommentsByContent(c.ID, 1, 5, bson.M{"time": 1})
+			replies, err := s.model.GetCommentsByContent(c.ID, 1, 5, bson.M{"time": 1}, blocked...)
 			utils.AssertErr(err, "", 500)
 			for j, r := range replies {
 				reply := CommentWithUserInfo{}
@@ -153,7 +175,7 @@ func (s *commentService) GetComments(contentID primitive.ObjectID, userID string
 				if userID != "" && r.IsDelete == false {
 					_id, err := primitive.ObjectIDFromHex(userID)
 					utils.AssertErr(err, "", 500)
-					reply.Liked = s.cache.IsLikeComment(_id, r.ID)
+					reply.Liked = s.reactionModel.HasReaction(_id, models.ReactionLikeComment, r.ID)
 				}
 				comment.Reply = append(comment.Reply, reply)
 			}
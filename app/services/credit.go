@@ -0,0 +1,100 @@
+package services
+
+import (
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreditEvent 信用变动事件
+type CreditEvent string
+
+// CreditEvent 信用变动事件
+const (
+	CreditTaskFinish      CreditEvent = "task_finish"       // 完成任务
+	CreditTaskFailure     CreditEvent = "task_failure"      // 任务失败
+	CreditTaskGiveUp      CreditEvent = "task_give_up"      // 放弃进行中的任务
+	CreditCloseWithPlayer CreditEvent = "close_with_player" // 关闭仍有参与者进行中的任务
+	CreditReportUpheld    CreditEvent = "report_upheld"     // 被举报且举报成立
+)
+
+// CreditService 信用服务
+type CreditService interface {
+	// 内部服务
+	changeCredit(userID, aboutID primitive.ObjectID, event CreditEvent, times int64)
+	checkAddPlayer(user models.UserSchema)
+	canPublish(user models.UserSchema, rewardValue float32, maxPlayer int64) bool
+}
+
+// newCreditService 初始化
+func newCreditService() CreditService {
+	return &creditService{
+		userModel: models.GetModel().User,
+		logModel:  models.GetModel().Log,
+	}
+}
+
+type creditService struct {
+	userModel *models.UserModel
+	logModel  *models.LogModel
+}
+
+// rules 获取信用规则
+func (s *creditService) rules() utils.CreditConfig {
+	if conf := utils.GetConf(); conf != nil {
+		return conf.Credit
+	}
+	return utils.CreditConfig{}
+}
+
+// eventValue 获取事件对应的信用变动值
+func (s *creditService) eventValue(event CreditEvent) int64 {
+	rules := s.rules()
+	switch event {
+	case CreditTaskFinish:
+		return rules.TaskFinish
+	case CreditTaskFailure:
+		return rules.TaskFailure
+	case CreditTaskGiveUp:
+		return rules.TaskGiveUp
+	case CreditCloseWithPlayer:
+		return rules.CloseWithPlayer
+	case CreditReportUpheld:
+		return rules.ReportUpheld
+	}
+	return 0
+}
+
+// changeCredit 按规则调整用户信用并记录日志，信用范围为 [0, 上限]
+func (s *creditService) changeCredit(userID, aboutID primitive.ObjectID, event CreditEvent, times int64) {
+	value := s.eventValue(event) * times
+	if value == 0 {
+		return
+	}
+	value, err := s.userModel.ChangeCredit(userID, value, s.rules().Max)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	if value == 0 {
+		return
+	}
+	logID, err := s.logModel.AddLog(userID, aboutID, models.LogTypeCredit)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.logModel.SetValue(logID, value)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.logModel.SetMsg(logID, string(event))
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// checkAddPlayer 检查用户信用是否允许参与任务
+func (s *creditService) checkAddPlayer(user models.UserSchema) {
+	utils.Assert(user.Data.Credit >= s.rules().MinAddPlayer, "low_credit", 403)
+}
+
+// canPublish 用户信用是否允许发布该酬劳的任务
+func (s *creditService) canPublish(user models.UserSchema, rewardValue float32, maxPlayer int64) bool {
+	rules := s.rules()
+	if rules.HighReward <= 0 || rewardValue*float32(maxPlayer) < float32(rules.HighReward) {
+		return true
+	}
+	return user.Data.Credit >= rules.MinHighReward
+}
@@ -4,9 +4,11 @@ import (
 	"github.com/TimeForCoin/Server/app/utils"
 	"mime/multipart"
 	"path"
+	"time"
 
 	"github.com/TimeForCoin/Server/app/libs"
 	"github.com/TimeForCoin/Server/app/models"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -19,7 +21,9 @@ type FileService interface {
 	RemoveFile(fileID primitive.ObjectID)
 	UpdateFileInfo(fileID, userID primitive.ObjectID, name, description string, public bool)
 	RemoveUserFile(userID, fileID primitive.ObjectID)
-	RemoveUselessFile(userID primitive.ObjectID, all bool) (removeCount int64)
+	RemoveUselessFile(userID primitive.ObjectID, all bool, meta models.RequestMeta) (removeCount int64)
+	// 内部服务
+	removeExpiredFiles()
 }
 
 // newFileService 初始化
@@ -134,13 +138,11 @@ func (s *fileService) RemoveFile(fileID primitive.ObjectID) {
 }
 
 // RemoveFiles 移除无用文件
-func (s *fileService) RemoveUselessFile(userID primitive.ObjectID, all bool) (removeCount int64) {
+func (s *fileService) RemoveUselessFile(userID primitive.ObjectID, all bool, meta models.RequestMeta) (removeCount int64) {
 	// 验证权限
 	var files []models.FileSchema
 	if all {
-		user, err := s.cache.GetUserBaseInfo(userID)
-		utils.AssertErr(err, "", 500)
-		utils.Assert(user.Type == models.UserTypeAdmin || user.Type == models.UserTypeRoot, "permission_deny", 403)
+		GetServiceManger().Role.checkPermission(userID, models.PermissionModerateContent)
 		files = s.model.GetUselessFile()
 		removeCount = s.model.RemoveUselessFile()
 	} else {
@@ -156,9 +158,29 @@ func (s *fileService) RemoveUselessFile(userID primitive.ObjectID, all bool) (re
 			utils.AssertErr(err, "", 500)
 		}
 	}
+	if all {
+		GetServiceManger().Audit.record(meta, userID, models.AuditRemoveUselessFiles, models.AuditTargetFile, "all", nil,
+			bson.M{"count": removeCount})
+	}
 	return
 }
 
+// removeExpiredFiles 删除已到计划删除时间的文件
+func (s *fileService) removeExpiredFiles() {
+	files, err := s.model.GetExpiredFiles(time.Now().Unix())
+	utils.AssertErr(err, "", 500)
+	for _, file := range files {
+		err = s.model.RemoveFile(file.ID)
+		utils.AssertErr(err, "", 500)
+		_, err := s.model.GetFileByHash(file.Hash)
+		if err != nil {
+			// 没有相同的文件
+			err = libs.GetCOS().DeleteFile(file.COSName)
+			utils.AssertErr(err, "", 500)
+		}
+	}
+}
+
 // RemoveUserFile 移除用户临时文件
 func (s *fileService) RemoveUserFile(userID, fileID primitive.ObjectID) {
 	f, err := s.model.GetFile(fileID)
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// filterReloadInterval 敏感词表重新加载间隔(秒)
+const filterReloadInterval = 60
+
+// FilterService 内容过滤服务
+type FilterService interface {
+	CheckContent(text string) (res string, review bool)
+	AddReview(ownerID primitive.ObjectID, targetType models.ReportTargetType, targetID primitive.ObjectID, msgTime int64, snapshot string)
+	GetWords(adminID primitive.ObjectID, page, size int64) (count int64, words []models.SystemSchemas)
+	AddWord(adminID primitive.ObjectID, word string, action models.FilterAction, meta models.RequestMeta)
+	RemoveWord(adminID primitive.ObjectID, word string, meta models.RequestMeta)
+}
+
+// newFilterService 初始化
+func newFilterService() FilterService {
+	return &filterService{
+		system:      models.GetModel().System,
+		reportModel: models.GetModel().Report,
+		cache:       models.GetRedis().Cache,
+	}
+}
+
+type filterService struct {
+	system      *models.SystemModel
+	reportModel *models.ReportModel
+	cache       *models.CacheModel
+	// 敏感词匹配器缓存
+	lock     sync.RWMutex
+	matcher  *utils.Matcher
+	actions  []models.FilterAction
+	loadTime int64
+}
+
+// getMatcher 获取敏感词匹配器，过期则重新加载
+func (s *filterService) getMatcher() (*utils.Matcher, []models.FilterAction) {
+	s.lock.RLock()
+	if s.matcher != nil && time.Now().Unix()-s.loadTime < filterReloadInterval {
+		defer s.lock.RUnlock()
+		return s.matcher, s.actions
+	}
+	s.lock.RUnlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.matcher != nil && time.Now().Unix()-s.loadTime < filterReloadInterval {
+		return s.matcher, s.actions
+	}
+	words, err := s.system.GetAllSensitiveWords()
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	var list []string
+	var actions []models.FilterAction
+	for word, action := range words {
+		list = append(list, word)
+		actions = append(actions, action)
+	}
+	s.matcher = utils.NewMatcher(list)
+	s.actions = actions
+	s.loadTime = time.Now().Unix()
+	return s.matcher, s.actions
+}
+
+// expire 使匹配器缓存失效
+func (s *filterService) expire() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.matcher = nil
+}
+
+// CheckContent 检查内容是否包含敏感词
+// 命中拒绝类敏感词直接报错，屏蔽类替换为 *，审核类返回 review 为 true
+func (s *filterService) CheckContent(text string) (res string, review bool) {
+	if text == "" {
+		return text, false
+	}
+	matcher, actions := s.getMatcher()
+	results := matcher.Match(text)
+	if len(results) == 0 {
+		return text, false
+	}
+	runes := []rune(text)
+	for _, r := range results {
+		switch actions[r.Index] {
+		case models.FilterActionReject:
+			utils.Assert(false, "sensitive_content", 403)
+		case models.FilterActionMask:
+			for i := r.Start; i < r.End; i++ {
+				runes[i] = '*'
+			}
+		case models.FilterActionReview:
+			review = true
+		}
+	}
+	return string(runes), review
+}
+
+// AddReview 将内容加入人工审核队列
+func (s *filterService) AddReview(ownerID primitive.ObjectID, targetType models.ReportTargetType, targetID primitive.ObjectID, msgTime int64, snapshot string) {
+	_, err := s.reportModel.AddReport(models.ReportSchema{
+		TargetType:  targetType,
+		TargetID:    targetID,
+		TargetOwner: ownerID,
+		MessageTime: msgTime,
+		Snapshot:    snapshot,
+		Reason:      models.ReportReasonSensitive,
+		Content:     "系统检测到疑似敏感内容",
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// GetWords 获取敏感词列表[管理员]
+func (s *filterService) GetWords(adminID primitive.ObjectID, page, size int64) (count int64, words []models.SystemSchemas) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+	words, count, err := s.system.GetSensitiveWords(page, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return
+}
+
+// AddWord 添加或修改敏感词[管理员]
+func (s *filterService) AddWord(adminID primitive.ObjectID, word string, action models.FilterAction, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+	utils.Assert(word != "" && len(word) < 64, "invalid_word", 400)
+	utils.Assert(action == models.FilterActionReject || action == models.FilterActionMask ||
+		action == models.FilterActionReview, "invalid_action", 400)
+	before := s.wordState(word)
+	err := s.system.AddSensitiveWord(word, action)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	s.expire()
+	GetServiceManger().Audit.record(meta, adminID, models.AuditSetFilterWord, models.AuditTargetWord, word,
+		before, bson.M{"action": action})
+}
+
+// RemoveWord 移除敏感词[管理员]
+func (s *filterService) RemoveWord(adminID primitive.ObjectID, word string, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+	before := s.wordState(word)
+	err := s.system.RemoveSensitiveWord(word)
+	utils.AssertErr(err, "faked_word", 403)
+	s.expire()
+	GetServiceManger().Audit.record(meta, adminID, models.AuditRemoveFilterWord, models.AuditTargetWord, word,
+		before, nil)
+}
+
+// wordState 获取敏感词当前的处理方式，用于记录管理操作
+func (s *filterService) wordState(word string) bson.M {
+	words, err := s.system.GetAllSensitiveWords()
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	if action, ok := words[word]; ok {
+		return bson.M{"action": action}
+	}
+	return nil
+}
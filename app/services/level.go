@@ -0,0 +1,110 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LevelService 等级服务
+type LevelService interface {
+	// 内部服务
+	checkLevelUp(userID primitive.ObjectID)
+	checkMaxPlayer(user models.UserSchema, maxPlayer int64)
+	canPublishRunning(user models.UserSchema) bool
+	checkTopTime(user models.UserSchema, topTime int64)
+}
+
+// newLevelService 初始化
+func newLevelService() LevelService {
+	return &levelService{
+		userModel:    models.GetModel().User,
+		taskModel:    models.GetModel().Task,
+		messageModel: models.GetModel().Message,
+	}
+}
+
+type levelService struct {
+	userModel    *models.UserModel
+	taskModel    *models.TaskModel
+	messageModel *models.MessageModel
+}
+
+// getLevel 根据累计获得积分计算等级，未配置等级时为 0 级
+func (s *levelService) getLevel(experience int64) (level int64, conf utils.LevelConfig) {
+	if utils.GetConf() == nil {
+		return
+	}
+	for i, l := range utils.GetConf().Levels {
+		if experience < l.Experience {
+			break
+		}
+		level = int64(i + 1)
+		conf = l
+	}
+	return
+}
+
+// checkLevelUp 检查用户是否升级，升级后发送系统消息
+func (s *levelService) checkLevelUp(userID primitive.ObjectID) {
+	user, err := s.userModel.GetUserByID(userID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	level, conf := s.getLevel(user.Data.Experience)
+	if level <= user.Data.Level {
+		return
+	}
+	err = s.userModel.SetUserLevel(userID, level)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+
+	content := "单个任务参与人数上限："
+	if conf.MaxPlayer > 0 {
+		content += strconv.FormatInt(conf.MaxPlayer, 10) + " 人"
+	} else {
+		content += "不限"
+	}
+	content += "\n同时进行中的任务数上限："
+	if conf.RunningTask > 0 {
+		content += strconv.FormatInt(conf.RunningTask, 10) + " 个"
+	} else {
+		content += "不限"
+	}
+	if conf.TopDays > 0 {
+		content += "\n任务最长置顶：" + strconv.FormatInt(conf.TopDays, 10) + " 天"
+	}
+	_, err = s.messageModel.AddMessage(userID, models.MessageTypeSystem, models.MessageSchema{
+		Title:   "恭喜你升级到 Lv" + strconv.FormatInt(level, 10) + " " + conf.Name,
+		Content: content,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// checkMaxPlayer 检查任务参与人数是否超出等级上限
+func (s *levelService) checkMaxPlayer(user models.UserSchema, maxPlayer int64) {
+	_, conf := s.getLevel(user.Data.Experience)
+	if conf.MaxPlayer > 0 {
+		utils.Assert(maxPlayer > 0 && maxPlayer <= conf.MaxPlayer, "max_player_limit", 403)
+	}
+}
+
+// canPublishRunning 用户进行中的任务数是否允许再发布任务
+func (s *levelService) canPublishRunning(user models.UserSchema) bool {
+	_, conf := s.getLevel(user.Data.Experience)
+	if conf.RunningTask <= 0 {
+		return true
+	}
+	count, err := s.taskModel.CountRunningTasks(user.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return count < conf.RunningTask
+}
+
+// checkTopTime 检查置顶时间是否在等级允许范围内
+func (s *levelService) checkTopTime(user models.UserSchema, topTime int64) {
+	_, conf := s.getLevel(user.Data.Experience)
+	utils.Assert(conf.TopDays > 0, "level_too_low", 403)
+	now := time.Now().Unix()
+	utils.Assert(topTime > now && topTime <= now+conf.TopDays*24*60*60, "invalid_top_time", 400)
+}
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"time"
+
 	"github.com/TimeForCoin/Server/app/models"
 	"github.com/TimeForCoin/Server/app/utils"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -11,7 +14,7 @@ type MessageService interface {
 	GetSessions(userID primitive.ObjectID, page, size int64) (res []SessionListDetail)
 	GetSession(userID, sessionID primitive.ObjectID, page, size int64) SessionDetail
 	GetSessionByUser(userID, targetID primitive.ObjectID, page, size int64) SessionDetail
-	SendSystemMessage(userID, aboutID primitive.ObjectID, title, content string) (total int64)
+	SendSystemMessage(userID, aboutID primitive.ObjectID, title, content string, meta models.RequestMeta) (total int64)
 	SendChatMessage(userID, targetID primitive.ObjectID, msg string) primitive.ObjectID
 }
 
@@ -141,20 +144,20 @@ func (s *messageService) GetSessionByUser(userID, targetID primitive.ObjectID, p
 }
 
 // SendSystemMessage 发送系统消息
-func (s *messageService) SendSystemMessage(userID, aboutID primitive.ObjectID, title, content string) (total int64) {
-	admin, err := s.cache.GetUserBaseInfo(userID)
-	utils.AssertErr(err, "faked_user", 403)
-	utils.Assert(admin.Type == models.UserTypeAdmin || admin.Type == models.UserTypeRoot, "permission_deny", 403)
+func (s *messageService) SendSystemMessage(userID, aboutID primitive.ObjectID, title, content string, meta models.RequestMeta) (total int64) {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionSendSystemMessage)
 
 	users := s.userModel.GetAllUser()
-	for _, userID := range users {
-		_, err := s.model.AddMessage(userID, models.MessageTypeSystem, models.MessageSchema{
+	for _, id := range users {
+		_, err := s.model.AddMessage(id, models.MessageTypeSystem, models.MessageSchema{
 			Title:   title,
 			Content: content,
 			About:   aboutID,
 		})
 		utils.AssertErr(err, "", 500)
 	}
+	GetServiceManger().Audit.record(meta, userID, models.AuditSendSystemMessage, models.AuditTargetMessage, "all", nil,
+		bson.M{"title": title, "content": content, "about": aboutID, "total": len(users)})
 	return int64(len(users))
 }
 
@@ -162,10 +165,17 @@ func (s *messageService) SendSystemMessage(userID, aboutID primitive.ObjectID, t
 func (s *messageService) SendChatMessage(userID, targetID primitive.ObjectID, msg string) primitive.ObjectID {
 	_, err := s.cache.GetUserBaseInfo(targetID)
 	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(!s.cache.IsBlockUser(targetID, userID), "blocked", 403)
+	msg, review := GetServiceManger().Filter.CheckContent(msg)
+	msgTime := time.Now().Unix()
 	sessionID, err := s.model.AddMessage(targetID, models.MessageTypeChat, models.MessageSchema{
 		UserID:  userID,
 		Content: msg,
+		Time:    msgTime,
 	})
 	utils.AssertErr(err, "", 500)
+	if review {
+		GetServiceManger().Filter.AddReview(userID, models.ReportTargetChat, sessionID, msgTime, msg)
+	}
 	return sessionID
 }
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// violationBanCount 违规次数达到该值时，警告自动升级为封禁
+const violationBanCount = 5
+
+// ReportService 举报服务
+type ReportService interface {
+	AddReport(userID primitive.ObjectID, targetType models.ReportTargetType, targetID primitive.ObjectID,
+		reason models.ReportReason, content string, msgTime int64) primitive.ObjectID
+	GetReports(adminID primitive.ObjectID, status, targetType string, page, size int64) (count int64, reports []ReportDetail)
+	HandleReport(adminID, reportID primitive.ObjectID, action models.ReportAction, feedback string, meta models.RequestMeta)
+}
+
+// newReportService 初始化
+func newReportService() ReportService {
+	return &reportService{
+		model:        models.GetModel().Report,
+		userModel:    models.GetModel().User,
+		taskModel:    models.GetModel().Task,
+		commentModel: models.GetModel().Comment,
+		messageModel: models.GetModel().Message,
+		cache:        models.GetRedis().Cache,
+	}
+}
+
+type reportService struct {
+	model        *models.ReportModel
+	userModel    *models.UserModel
+	taskModel    *models.TaskModel
+	commentModel *models.CommentModel
+	messageModel *models.MessageModel
+	cache        *models.CacheModel
+}
+
+// ReportDetail 举报详情
+type ReportDetail struct {
+	*models.ReportSchema
+	// 额外项
+	Reporter       models.UserBaseInfo
+	Owner          models.UserBaseInfo
+	OwnerViolation int64 // 被举报用户累计违规次数
+}
+
+// reportTargetName 举报内容类型名称
+var reportTargetName = map[models.ReportTargetType]string{
+	models.ReportTargetTask:    "任务",
+	models.ReportTargetComment: "评论",
+	models.ReportTargetChat:    "聊天消息",
+	models.ReportTargetUser:    "个人资料",
+}
+
+// reportActionName 举报处理操作名称
+var reportActionName = map[models.ReportAction]string{
+	models.ReportActionHide:  "内容已被屏蔽",
+	models.ReportActionClose: "任务已被关闭",
+	models.ReportActionWarn:  "用户已被警告",
+	models.ReportActionBan:   "用户已被封禁",
+}
+
+// AddReport 举报内容
+func (s *reportService) AddReport(userID primitive.ObjectID, targetType models.ReportTargetType, targetID primitive.ObjectID,
+	reason models.ReportReason, content string, msgTime int64) primitive.ObjectID {
+	report := models.ReportSchema{
+		Reporter:   userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Content:    content,
+	}
+	switch targetType {
+	case models.ReportTargetTask:
+		task, err := s.taskModel.GetTaskByID(targetID)
+		utils.AssertErr(err, "faked_task", 403)
+		utils.Assert(task.Status != models.TaskStatusDraft, "not_allow_status", 403)
+		report.TargetOwner = task.Publisher
+		report.Snapshot = task.Title + "\n" + task.Content
+	case models.ReportTargetComment:
+		comment, err := s.commentModel.GetCommentByID(targetID)
+		utils.AssertErr(err, "faked_comment", 403)
+		utils.Assert(!comment.IsDelete, "deleted_comment", 403)
+		report.TargetOwner = comment.UserID
+		report.Snapshot = comment.Content
+	case models.ReportTargetChat:
+		session, err := s.messageModel.GetSessionByID(targetID)
+		utils.AssertErr(err, "faked_message", 403)
+		utils.Assert(session.Type == models.MessageTypeChat, "faked_message", 403)
+		if session.User1 == userID {
+			report.TargetOwner = session.User2
+		} else if session.User2 == userID {
+			report.TargetOwner = session.User1
+		} else {
+			utils.Assert(false, "permission_deny", 403)
+		}
+		message, err := s.messageModel.GetChatMessage(targetID, report.TargetOwner, msgTime)
+		utils.AssertErr(err, "faked_message", 403)
+		report.MessageTime = msgTime
+		report.Snapshot = message.Content
+	case models.ReportTargetUser:
+		user, err := s.userModel.GetUserByID(targetID)
+		utils.AssertErr(err, "faked_user", 403)
+		report.TargetOwner = user.ID
+		report.Snapshot = user.Info.Nickname + "\n" + user.Info.Bio
+	default:
+		utils.Assert(false, "invalid_type", 400)
+	}
+	utils.Assert(report.TargetOwner != userID, "not_allow_self", 403)
+	utils.Assert(!s.model.ExistWaitReport(userID, targetID), "exist_report", 403)
+
+	id, err := s.model.AddReport(report)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return id
+}
+
+// GetReports 获取举报处理队列[管理员]
+func (s *reportService) GetReports(adminID primitive.ObjectID, status, targetType string, page, size int64) (count int64, reports []ReportDetail) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+
+	var statuses []models.ReportStatus
+	for _, str := range strings.Split(status, ",") {
+		if str == "all" {
+			statuses = []models.ReportStatus{models.ReportStatusWait, models.ReportStatusDone, models.ReportStatusReject}
+			break
+		}
+		statuses = append(statuses, models.ReportStatus(str))
+	}
+	var types []models.ReportTargetType
+	for _, str := range strings.Split(targetType, ",") {
+		if str == "all" {
+			types = []models.ReportTargetType{models.ReportTargetTask, models.ReportTargetComment,
+				models.ReportTargetChat, models.ReportTargetUser}
+			break
+		}
+		types = append(types, models.ReportTargetType(str))
+	}
+
+	list, count, err := s.model.GetReports(statuses, types, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for i := range list {
+		detail := ReportDetail{
+			ReportSchema: &list[i],
+			Owner:        GetServiceManger().User.GetUserBaseInfo(list[i].TargetOwner),
+		}
+		if list[i].Reporter.IsZero() {
+			detail.Reporter = models.UserBaseInfo{Nickname: "系统检测"}
+		} else {
+			detail.Reporter = GetServiceManger().User.GetUserBaseInfo(list[i].Reporter)
+		}
+		if owner, err := s.userModel.GetUserByID(list[i].TargetOwner); err == nil {
+			detail.OwnerViolation = owner.Data.ViolationCount
+		}
+		reports = append(reports, detail)
+	}
+	return
+}
+
+// HandleReport 处理举报[管理员]
+func (s *reportService) HandleReport(adminID, reportID primitive.ObjectID, action models.ReportAction, feedback string, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionModerateContent)
+
+	report, err := s.model.GetReportByID(reportID)
+	utils.AssertErr(err, "faked_report", 403)
+	utils.Assert(report.Status == models.ReportStatusWait, "handled_report", 403)
+	if action == models.ReportActionBan {
+		owner, err := s.userModel.GetUserByID(report.TargetOwner)
+		utils.AssertErr(err, "faked_user", 403)
+		utils.Assert(owner.Data.Type != models.UserTypeAdmin && owner.Data.Type != models.UserTypeRoot, "not_allow_user", 403)
+	}
+
+	var task models.TaskSchema
+	switch action {
+	case models.ReportActionNone, models.ReportActionWarn, models.ReportActionHide, models.ReportActionBan:
+	case models.ReportActionClose:
+		utils.Assert(report.TargetType == models.ReportTargetTask, "not_allow_action", 403)
+		task, err = s.taskModel.GetTaskByID(report.TargetID)
+		utils.AssertErr(err, "faked_task", 403)
+		utils.Assert(task.Status == models.TaskStatusWait, "not_allow_status", 403)
+	default:
+		utils.Assert(false, "invalid_action", 400)
+	}
+	status := models.ReportStatusReject
+	if action != models.ReportActionNone {
+		status = models.ReportStatusDone
+	}
+
+	// 先保存处理结果再执行处理操作，避免重复处理时重复扣除信用和计数
+	err = s.model.SetReportResult(reportID, adminID, status, action, feedback)
+	utils.Assert(err != models.ErrNotExist, "handled_report", 403)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	// 同一内容的举报一并处理
+	others, err := s.model.GetWaitReportsByTarget(report.TargetID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	reports := []models.ReportSchema{report}
+	for _, r := range others {
+		err = s.model.SetReportResult(r.ID, adminID, status, action, feedback)
+		if err == models.ErrNotExist {
+			continue
+		}
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		reports = append(reports, r)
+	}
+
+	// 执行处理操作
+	switch action {
+	case models.ReportActionHide:
+		switch report.TargetType {
+		case models.ReportTargetTask:
+			err = s.taskModel.SetTaskHidden(report.TargetID, true)
+		case models.ReportTargetComment:
+			err = s.commentModel.RemoveContentByID(report.TargetID)
+		case models.ReportTargetChat:
+			err = s.messageModel.HideChatMessage(report.TargetID, report.TargetOwner, report.MessageTime, "该消息已被屏蔽")
+		case models.ReportTargetUser:
+			err = s.userModel.HideUserInfo(report.TargetOwner)
+		}
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	case models.ReportActionClose:
+		GetServiceManger().Task.closeTask(task)
+	case models.ReportActionBan:
+		GetServiceManger().Ban.banUser(report.TargetOwner, adminID, feedback, reportBanEndTime())
+	}
+
+	if status == models.ReportStatusDone {
+		// 违规计数，多次违规自动封禁
+		err = s.userModel.UpdateUserDataCount(report.TargetOwner, models.UserDataCount{
+			ViolationCount: 1,
+		})
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		GetServiceManger().Credit.changeCredit(report.TargetOwner, report.ID, CreditReportUpheld, 1)
+		owner, err := s.userModel.GetUserByID(report.TargetOwner)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		if action != models.ReportActionBan && owner.Data.ViolationCount >= violationBanCount &&
+			owner.Data.Type == models.UserTypeNormal {
+			action = models.ReportActionBan
+			GetServiceManger().Ban.banUser(report.TargetOwner, primitive.NilObjectID, "多次违规", reportBanEndTime())
+			ids := make([]primitive.ObjectID, len(reports))
+			for i := range reports {
+				ids[i] = reports[i].ID
+			}
+			err = s.model.SetReportsAction(ids, action)
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+		}
+		// 通知被举报用户
+		_, err = s.messageModel.AddMessage(report.TargetOwner, models.MessageTypeSystem, models.MessageSchema{
+			Title:   "你的" + reportTargetName[report.TargetType] + "因违规被处理：" + reportActionName[action],
+			Content: feedback,
+			About:   report.TargetID,
+		})
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+
+	// 通知举报人
+	for _, r := range reports {
+		// 系统检测产生的审核无需通知
+		if r.Reporter.IsZero() {
+			continue
+		}
+		title := "你的举报经核实不成立"
+		if status == models.ReportStatusDone {
+			title = "你的举报已被受理：" + reportActionName[action]
+		}
+		_, err = s.messageModel.AddMessage(r.Reporter, models.MessageTypeSystem, models.MessageSchema{
+			Title:   title,
+			Content: feedback,
+			About:   r.ID,
+		})
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+	GetServiceManger().Audit.record(meta, adminID, models.AuditHandleReport, models.AuditTargetReport, reportID.Hex(),
+		bson.M{"status": report.Status, "target_type": report.TargetType, "target_id": report.TargetID, "snapshot": report.Snapshot},
+		bson.M{"status": status, "action": action, "feedback": feedback, "count": len(reports)})
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoleService 角色权限服务
+type RoleService interface {
+	GetPermissions(userID primitive.ObjectID) []models.Permission
+	HasPermission(userID primitive.ObjectID, permission models.Permission) bool
+	GetRoles(rootID primitive.ObjectID) []models.RoleSchema
+	AddRole(rootID primitive.ObjectID, name, description string, permissions []models.Permission, meta models.RequestMeta) primitive.ObjectID
+	SetRole(rootID, roleID primitive.ObjectID, name, description string, permissions []models.Permission, meta models.RequestMeta)
+	RemoveRole(rootID, roleID primitive.ObjectID, meta models.RequestMeta)
+	GetRoleUsers(rootID, roleID primitive.ObjectID) []models.UserBaseInfo
+	GrantRole(rootID, roleID, userID primitive.ObjectID, meta models.RequestMeta)
+	RevokeRole(rootID, roleID, userID primitive.ObjectID, meta models.RequestMeta)
+	// 内部服务
+	checkPermission(userID primitive.ObjectID, permission models.Permission)
+}
+
+// newRoleService 初始化
+func newRoleService() RoleService {
+	return &roleService{
+		model:     models.GetModel().Role,
+		userModel: models.GetModel().User,
+		cache:     models.GetRedis().Cache,
+	}
+}
+
+type roleService struct {
+	model     *models.RoleModel
+	userModel *models.UserModel
+	cache     *models.CacheModel
+}
+
+// GetPermissions 获取用户拥有的权限
+func (s *roleService) GetPermissions(userID primitive.ObjectID) []models.Permission {
+	permissions, err := s.cache.GetUserPermissions(userID)
+	utils.AssertErr(err, "invalid_session", 401)
+	return permissions
+}
+
+// HasPermission 用户是否拥有某项权限
+func (s *roleService) HasPermission(userID primitive.ObjectID, permission models.Permission) bool {
+	permissions, err := s.cache.GetUserPermissions(userID)
+	if err != nil {
+		return false
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPermission 检查用户权限，没有权限时中断请求
+func (s *roleService) checkPermission(userID primitive.ObjectID, permission models.Permission) {
+	permissions, err := s.cache.GetUserPermissions(userID)
+	utils.AssertErr(err, "invalid_session", 401)
+	for _, p := range permissions {
+		if p == permission {
+			return
+		}
+	}
+	utils.Assert(false, "permission_deny", 403)
+}
+
+// checkRoot 检查超级管理员权限，仅超级管理员可以分配角色
+func (s *roleService) checkRoot(rootID primitive.ObjectID) {
+	root, err := s.cache.GetUserBaseInfo(rootID)
+	utils.AssertErr(err, "invalid_session", 401)
+	utils.Assert(root.Type == models.UserTypeRoot, "permission_deny", 403)
+}
+
+// checkPermissions 检查权限列表是否合法
+func checkPermissions(permissions []models.Permission) {
+	for _, p := range permissions {
+		utils.Assert(models.IsPermission(p), "invalid_permission", 400)
+	}
+}
+
+// GetRoles 获取角色列表[超级管理员]
+func (s *roleService) GetRoles(rootID primitive.ObjectID) []models.RoleSchema {
+	s.checkRoot(rootID)
+	roles, err := s.model.GetRoles()
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return roles
+}
+
+// AddRole 添加角色[超级管理员]
+func (s *roleService) AddRole(rootID primitive.ObjectID, name, description string, permissions []models.Permission, meta models.RequestMeta) primitive.ObjectID {
+	s.checkRoot(rootID)
+	checkPermissions(permissions)
+	id, err := s.model.AddRole(models.RoleSchema{
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+		Creator:     rootID,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, rootID, models.AuditSetRole, models.AuditTargetRole, id.Hex(), nil,
+		bson.M{"name": name, "description": description, "permissions": permissions})
+	return id
+}
+
+// SetRole 修改角色[超级管理员]
+func (s *roleService) SetRole(rootID, roleID primitive.ObjectID, name, description string, permissions []models.Permission, meta models.RequestMeta) {
+	s.checkRoot(rootID)
+	checkPermissions(permissions)
+	role, err := s.model.GetRoleByID(roleID)
+	utils.AssertErr(err, "faked_role", 403)
+	err = s.model.SetRole(roleID, name, description, permissions)
+	utils.AssertErr(err, "faked_role", 403)
+	s.refreshRoleUsers(roleID)
+	GetServiceManger().Audit.record(meta, rootID, models.AuditSetRole, models.AuditTargetRole, roleID.Hex(),
+		bson.M{"name": role.Name, "description": role.Description, "permissions": role.Permissions},
+		bson.M{"name": name, "description": description, "permissions": permissions})
+}
+
+// RemoveRole 删除角色，并从已授予的用户中撤销[超级管理员]
+func (s *roleService) RemoveRole(rootID, roleID primitive.ObjectID, meta models.RequestMeta) {
+	s.checkRoot(rootID)
+	role, err := s.model.GetRoleByID(roleID)
+	utils.AssertErr(err, "faked_role", 403)
+	err = s.model.RemoveRole(roleID)
+	utils.AssertErr(err, "faked_role", 403)
+	s.refreshRoleUsers(roleID)
+	err = s.userModel.RemoveRoleFromUsers(roleID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, rootID, models.AuditRemoveRole, models.AuditTargetRole, roleID.Hex(),
+		bson.M{"name": role.Name, "description": role.Description, "permissions": role.Permissions}, nil)
+}
+
+// refreshRoleUsers 角色权限变化后更新用户的权限缓存
+func (s *roleService) refreshRoleUsers(roleID primitive.ObjectID) {
+	users, err := s.userModel.GetUsersByRole(roleID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, id := range users {
+		utils.Assert(s.cache.WillUpdate(id, models.KindOfPermission) == nil, "redis_error", iris.StatusInternalServerError)
+	}
+}
+
+// GetRoleUsers 获取被授予角色的用户[超级管理员]
+func (s *roleService) GetRoleUsers(rootID, roleID primitive.ObjectID) []models.UserBaseInfo {
+	s.checkRoot(rootID)
+	_, err := s.model.GetRoleByID(roleID)
+	utils.AssertErr(err, "faked_role", 403)
+	users, err := s.userModel.GetUsersByRole(roleID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := make([]models.UserBaseInfo, 0, len(users))
+	for _, id := range users {
+		res = append(res, GetServiceManger().User.GetUserBaseInfo(id))
+	}
+	return res
+}
+
+// GrantRole 授予用户角色[超级管理员]
+func (s *roleService) GrantRole(rootID, roleID, userID primitive.ObjectID, meta models.RequestMeta) {
+	s.checkRoot(rootID)
+	role, err := s.model.GetRoleByID(roleID)
+	utils.AssertErr(err, "faked_role", 403)
+	err = s.userModel.AddUserRole(userID, roleID)
+	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(s.cache.WillUpdate(userID, models.KindOfPermission) == nil, "redis_error", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, rootID, models.AuditGrantRole, models.AuditTargetUser, userID.Hex(),
+		nil, bson.M{"role": roleID, "name": role.Name})
+}
+
+// RevokeRole 撤销用户角色[超级管理员]
+func (s *roleService) RevokeRole(rootID, roleID, userID primitive.ObjectID, meta models.RequestMeta) {
+	s.checkRoot(rootID)
+	err := s.userModel.RemoveUserRole(userID, roleID)
+	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(s.cache.WillUpdate(userID, models.KindOfPermission) == nil, "redis_error", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, rootID, models.AuditRevokeRole, models.AuditTargetUser, userID.Hex(),
+		bson.M{"role": roleID}, nil)
+}
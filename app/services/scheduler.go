@@ -0,0 +1,37 @@
+package services
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StartScheduler 启动定时任务
+func StartScheduler() {
+	go runJob("publish_scheduled_tasks", time.Minute, GetServiceManger().Task.publishScheduledTasks)
+	go runJob("clear_expired_top", time.Minute, GetServiceManger().Task.clearExpiredTop)
+	go runJob("award_top_publisher", time.Hour, GetServiceManger().Badge.awardTopPublisher)
+	go runJob("remove_expired_files", time.Hour, GetServiceManger().File.removeExpiredFiles)
+	go runJob("expire_bans", time.Minute, GetServiceManger().Ban.expireBans)
+	go runJob("remind_certifications", time.Hour, GetServiceManger().User.remindCertifications)
+	go runJob("expire_certifications", time.Hour, GetServiceManger().User.expireCertifications)
+}
+
+// runJob 按固定间隔执行定时任务
+func runJob(name string, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		runJobOnce(name, job)
+	}
+}
+
+// runJobOnce 执行一次定时任务，捕获任务中的错误，避免影响后续执行
+func runJobOnce(name string, job func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error().Interface("error", err).Msg("Failure to run job " + name)
+		}
+	}()
+	job()
+}
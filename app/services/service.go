@@ -12,6 +12,16 @@ type ServiceManger struct {
 	Comment       CommentService
 	Message       MessageService
 	Utils         UtilsService
+	Report        ReportService
+	Filter        FilterService
+	Credit        CreditService
+	Level         LevelService
+	Badge         BadgeService
+	Ban           BanService
+	Role          RoleService
+	Audit         AuditService
+	Campus        CampusService
+	Token         TokenService
 }
 
 // GetServiceManger 获取服务管理器
@@ -26,6 +36,16 @@ func GetServiceManger() *ServiceManger {
 			Comment:       newCommentService(),
 			Message:       newMessageService(),
 			Utils:         newUtilsService(),
+			Report:        newReportService(),
+			Filter:        newFilterService(),
+			Credit:        newCreditService(),
+			Level:         newLevelService(),
+			Badge:         newBadgeService(),
+			Ban:           newBanService(),
+			Role:          newRoleService(),
+			Audit:         newAuditService(),
+			Campus:        newCampusService(),
+			Token:         newTokenService(),
 		}
 	}
 	return service
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,18 +21,24 @@ type TaskService interface {
 		images, attachments []primitive.ObjectID)
 	GetTaskByID(taskID primitive.ObjectID, userID string, biref bool) (task TaskDetail)
 	GetTasks(page, size int64, sortRule, taskType,
-		status, reward, keyword, user, userID string, biref bool) (taskCount int64, tasks []TaskDetail)
+		status, reward, keyword, user, campus, userID string, biref bool) (taskCount int64, tasks []TaskDetail)
 	RemoveTask(userID, taskID primitive.ObjectID)
 	AddView(taskID primitive.ObjectID)
 	ChangeLike(taskID, userID primitive.ObjectID, like bool)
-	ChangeCollection(taskID, userID primitive.ObjectID, collect bool)
+	ChangeCollection(taskID, userID primitive.ObjectID, collect bool, folder primitive.ObjectID)
+	SetCollectionFolder(taskID, userID, folder primitive.ObjectID)
 	AddPlayer(taskID, userID primitive.ObjectID, note string) bool
 	GetTaskStatus(taskID, userID, postUserID primitive.ObjectID) (taskStatusList TaskStatus)
 	SetTaskStatusInfo(taskID, userID, postUserID primitive.ObjectID, taskStatus models.TaskStatusSchema)
 	GetTaskPlayer(taskID primitive.ObjectID, status string, page, size int64) (taskCount int64, taskStatusList []TaskStatus)
 	GetQRCode(taskID primitive.ObjectID) string
+	ExportTaskPlayer(taskID, userID primitive.ObjectID) (title string, export func(writer utils.TableWriter) error)
 	// 内部服务
 	makeTaskDetail(task models.TaskSchema, userID string, biref bool) (res TaskDetail)
+	closeTask(task models.TaskSchema)
+	closeUserTasks(userID primitive.ObjectID)
+	publishScheduledTasks()
+	clearExpiredTop()
 }
 
 func newTaskService() TaskService {
@@ -44,6 +51,9 @@ func newTaskService() TaskService {
 		taskStatusModel: models.GetModel().TaskStatus,
 		messageModel:    models.GetModel().Message,
 		logModel:        models.GetModel().Log,
+		followModel:     models.GetModel().Follow,
+		reactionModel:   models.GetModel().Reaction,
+		folderModel:     models.GetModel().Folder,
 	}
 }
 
@@ -56,6 +66,9 @@ type taskService struct {
 	taskStatusModel *models.TaskStatusModel
 	messageModel    *models.MessageModel
 	logModel        *models.LogModel
+	followModel     *models.FollowModel
+	reactionModel   *models.ReactionModel
+	folderModel     *models.FolderModel
 }
 
 // ImagesData 图片数据
@@ -86,13 +99,25 @@ func (s *taskService) AddTask(userID primitive.ObjectID, info models.TaskSchema,
 	status := models.TaskStatusDraft
 	user, err := s.userModel.GetUserByID(userID)
 	utils.AssertErr(err, "", 500)
+	if info.PublishAt != 0 {
+		// 定时发布的任务先保存为草稿
+		utils.Assert(!publish && info.PublishAt > time.Now().Unix(), "invalid_publish_at", 400)
+	}
 	if publish {
 		status = models.TaskStatusWait
 		utils.Assert(float32(user.Data.Money) > info.RewardValue*float32(info.MaxPlayer)+1, "no_money", 403)
+		utils.Assert(GetServiceManger().Credit.canPublish(user, info.RewardValue, info.MaxPlayer), "low_credit", 403)
+		utils.Assert(GetServiceManger().Level.canPublishRunning(user), "running_task_limit", 403)
 	} else {
 		utils.Assert(float32(user.Data.Money) > 1, "no_money", 403)
 	}
+	GetServiceManger().Level.checkMaxPlayer(user, info.MaxPlayer)
 	utils.Assert(float32(user.Data.Value) > 2, "no_value", 403)
+	// 未指定校区时默认发布到自己认证的校区
+	if info.Campuses == nil {
+		info.Campuses = GetServiceManger().Campus.userCampuses(user)
+	}
+	GetServiceManger().Campus.checkPublishCampuses(user, info.Campuses)
 
 	taskID := primitive.NewObjectID()
 
@@ -154,30 +179,29 @@ func (s *taskService) SetTaskInfo(userID, taskID primitive.ObjectID, info models
 	utils.AssertErr(err, "faked_task", 403)
 	utils.Assert(task.Publisher == userID, "permission_deny", 403)
 
-	// 状态修改
-	if info.Status == models.TaskStatusClose {
-		// 关闭任务
-		utils.Assert(task.Status == models.TaskStatusWait, "not_allow_status", 403)
-
-		taskStatus, _, err := s.taskStatusModel.GetTaskStatusListByTaskID(taskID, []models.PlayerStatus{}, 0, 0)
+	if info.Campuses != nil {
+		user, err := s.userModel.GetUserByID(userID)
 		utils.AssertErr(err, "", 500)
-		// 发送通知消息
-		for _, status := range taskStatus {
-			_, err = s.messageModel.AddMessage(status.Player, models.MessageTypeTask, models.MessageSchema{
-				UserID: taskID,
-				Title:  "任务已关闭",
-			})
-			utils.AssertErr(err, "", 500)
-			err = s.taskStatusModel.SetTaskStatus(status.ID, models.TaskStatusSchema{
-				Status: models.PlayerClose,
-			})
-			utils.AssertErr(err, "", 500)
+		GetServiceManger().Campus.checkPublishCampuses(user, info.Campuses)
+	}
+
+	// 定时发布
+	if info.PublishAt != 0 {
+		utils.Assert(task.Status == models.TaskStatusDraft && info.Status != models.TaskStatusWait, "not_allow_status", 403)
+		if info.PublishAt == -1 {
+			// 取消定时发布
+			err = s.model.SetTaskPublishAt(taskID, 0)
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+			info.PublishAt = 0
+		} else {
+			utils.Assert(info.PublishAt > time.Now().Unix(), "invalid_publish_at", 400)
 		}
+	}
 
-		err = s.model.SetTaskInfoByID(taskID, models.TaskSchema{
-			Status: models.TaskStatusClose,
-		})
-		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	// 状态修改
+	if info.Status == models.TaskStatusClose {
+		// 关闭任务
+		s.closeTask(task)
 		return
 	} else if info.Status == models.TaskStatusWait {
 		// 发布任务
@@ -185,8 +209,8 @@ func (s *taskService) SetTaskInfo(userID, taskID primitive.ObjectID, info models
 		info.PublishDate = time.Now().Unix()
 		user, err := s.userModel.GetUserByID(userID)
 		utils.AssertErr(err, "", 500)
-		utils.Assert(float32(user.Data.Money) > info.RewardValue*float32(info.MaxPlayer), "no_money", 403)
-		utils.Assert(float32(user.Data.Value) > 2, "no_value", 403)
+		reason := s.checkPublish(user, info.RewardValue, info.MaxPlayer)
+		utils.Assert(reason == "", reason, 403)
 	} else if info.Status == models.TaskStatusFinish {
 		// 任务已完成
 		players, _, err := s.taskStatusModel.GetTaskStatusListByTaskID(taskID, []models.PlayerStatus{}, 0, 0)
@@ -200,6 +224,19 @@ func (s *taskService) SetTaskInfo(userID, taskID primitive.ObjectID, info models
 
 	utils.Assert(info.MaxPlayer == 0 || info.MaxPlayer > task.PlayerCount, "not_allow_max_player", 403)
 
+	// 等级特权
+	if info.MaxPlayer != 0 || info.TopTime != 0 {
+		user, err := s.userModel.GetUserByID(userID)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		if info.MaxPlayer != 0 {
+			GetServiceManger().Level.checkMaxPlayer(user, info.MaxPlayer)
+		}
+		if info.TopTime != 0 {
+			utils.Assert(task.Status == models.TaskStatusWait, "not_allow_status", 403)
+			GetServiceManger().Level.checkTopTime(user, info.TopTime)
+		}
+	}
+
 	if info.Type != "" {
 		if task.Type == models.TaskTypeQuestionnaire || info.Type == models.TaskTypeQuestionnaire {
 			utils.Assert(info.Type == task.Type, "not_allow_change_type")
@@ -294,13 +331,16 @@ func (s *taskService) SetTaskInfo(userID, taskID primitive.ObjectID, info models
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
 
 	if info.Status == models.TaskStatusWait {
-		err = s.userModel.UpdateUserDataCount(userID, models.UserDataCount{
-			Money: -int64(info.RewardValue) * info.MaxPlayer,
-		})
-		logID, err := s.logModel.AddLog(userID, taskID, models.LogTypeMoney)
-		err = s.logModel.SetValue(logID, -int64(info.RewardValue)*info.MaxPlayer)
-		err = s.logModel.SetMsg(logID, "set task info")
-		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		if task.PublishAt != 0 {
+			// 手动发布后取消定时发布
+			err = s.model.SetTaskPublishAt(taskID, 0)
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+		}
+		if !s.chargePublish(userID, taskID, info.RewardValue, info.MaxPlayer, "set task info") {
+			err = s.model.UnpublishTask(taskID)
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+			utils.Assert(false, "no_money", 403)
+		}
 	} else if addMoney != 0 {
 		err = s.userModel.UpdateUserDataCount(userID, models.UserDataCount{
 			Money: -int64(addMoney),
@@ -317,17 +357,226 @@ func (s *taskService) SetTaskInfo(userID, taskID primitive.ObjectID, info models
 	}
 }
 
+// closeTask 关闭任务并通知参与者
+func (s *taskService) closeTask(task models.TaskSchema) {
+	utils.Assert(task.Status == models.TaskStatusWait, "not_allow_status", 403)
+	running := s.closeTaskPlayers(task, true)
+	// 仍有参与者进行中时关闭任务，扣除发布者信用
+	GetServiceManger().Credit.changeCredit(task.Publisher, task.ID, CreditCloseWithPlayer, running)
+}
+
+// closeTaskPlayers 关闭任务和参与者状态，notify 为 true 时通知参与者，返回进行中的参与者数
+func (s *taskService) closeTaskPlayers(task models.TaskSchema, notify bool) (running int64) {
+	taskStatus, _, err := s.taskStatusModel.GetTaskStatusListByTaskID(task.ID, []models.PlayerStatus{}, 0, 0)
+	utils.AssertErr(err, "", 500)
+	for _, status := range taskStatus {
+		if status.Status == models.PlayerRunning {
+			running++
+		}
+		if notify {
+			_, err = s.messageModel.AddMessage(status.Player, models.MessageTypeTask, models.MessageSchema{
+				UserID: task.ID,
+				Title:  "任务已关闭",
+			})
+			utils.AssertErr(err, "", 500)
+		}
+		err = s.taskStatusModel.SetTaskStatus(status.ID, models.TaskStatusSchema{
+			Status: models.PlayerClose,
+		})
+		utils.AssertErr(err, "", 500)
+	}
+
+	err = s.model.SetTaskInfoByID(task.ID, models.TaskSchema{
+		Status: models.TaskStatusClose,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return
+}
+
+// closeUserTasks 关闭用户发布的所有未结束任务（用户注销）
+// 进行中参与者的酬劳直接发放，其余托管金额退还发布者
+func (s *taskService) closeUserTasks(userID primitive.ObjectID) {
+	tasks, err := s.model.GetTasksByPublisher(userID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, task := range tasks {
+		if task.Status == models.TaskStatusDraft {
+			err = s.model.SetTaskInfoByID(task.ID, models.TaskSchema{
+				Status: models.TaskStatusClose,
+			})
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+			continue
+		} else if task.Status != models.TaskStatusWait {
+			continue
+		}
+		players, _, err := s.taskStatusModel.GetTaskStatusListByTaskID(task.ID, []models.PlayerStatus{}, 0, 0)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		paid := int64(0)
+		for _, status := range players {
+			if status.Status == models.PlayerFinish {
+				paid++
+			} else if status.Status == models.PlayerRunning {
+				paid++
+				err = s.userModel.UpdateUserDataCount(status.Player, models.UserDataCount{
+					Money: int64(task.RewardValue),
+				})
+				utils.AssertErr(err, "", iris.StatusInternalServerError)
+				logID, err := s.logModel.AddLog(status.Player, task.ID, models.LogTypeMoney)
+				err = s.logModel.SetValue(logID, int64(task.RewardValue))
+				err = s.logModel.SetMsg(logID, "release task escrow")
+				utils.AssertErr(err, "", iris.StatusInternalServerError)
+				_, err = s.messageModel.AddMessage(status.Player, models.MessageTypeTask, models.MessageSchema{
+					UserID:  task.ID,
+					Title:   "发布者已注销，任务酬劳已发放",
+					Content: task.Title,
+				})
+				utils.AssertErr(err, "", iris.StatusInternalServerError)
+			} else {
+				_, err = s.messageModel.AddMessage(status.Player, models.MessageTypeTask, models.MessageSchema{
+					UserID:  task.ID,
+					Title:   "发布者已注销，任务已关闭",
+					Content: task.Title,
+				})
+				utils.AssertErr(err, "", iris.StatusInternalServerError)
+			}
+		}
+		if refund := int64(task.RewardValue) * (task.MaxPlayer - paid); refund > 0 {
+			err = s.userModel.UpdateUserDataCount(userID, models.UserDataCount{
+				Money: refund,
+			})
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+			logID, err := s.logModel.AddLog(userID, task.ID, models.LogTypeMoney)
+			err = s.logModel.SetValue(logID, refund)
+			err = s.logModel.SetMsg(logID, "refund task escrow")
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+		}
+		// 酬劳已发放，关闭任务不再扣除信用
+		s.closeTaskPlayers(task, false)
+	}
+}
+
+// publishFailReasons 定时发布失败原因
+var publishFailReasons = map[string]string{
+	"no_money":           "闲钱币余额不足以支付任务酬劳",
+	"no_value":           "积分不足",
+	"low_credit":         "信用不足以发布高酬劳任务",
+	"running_task_limit": "进行中的任务数已达到等级上限",
+}
+
+// checkPublish 检查用户能否发布草稿任务，返回不满足条件的错误码
+func (s *taskService) checkPublish(user models.UserSchema, rewardValue float32, maxPlayer int64) string {
+	switch {
+	case float32(user.Data.Money) <= rewardValue*float32(maxPlayer)+1:
+		return "no_money"
+	case float32(user.Data.Value) <= 2:
+		return "no_value"
+	case !GetServiceManger().Credit.canPublish(user, rewardValue, maxPlayer):
+		return "low_credit"
+	case !GetServiceManger().Level.canPublishRunning(user):
+		return "running_task_limit"
+	}
+	return ""
+}
+
+// chargePublish 发布草稿任务时扣除酬劳和积分，余额不足时返回 false
+func (s *taskService) chargePublish(userID, taskID primitive.ObjectID, rewardValue float32, maxPlayer int64, msg string) bool {
+	err := s.userModel.SpendMoney(userID, int64(rewardValue)*maxPlayer)
+	if err == models.ErrNotExist {
+		return false
+	}
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.userModel.UpdateUserDataCount(userID, models.UserDataCount{
+		Value: -2,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	logID, err := s.logModel.AddLog(userID, taskID, models.LogTypeMoney)
+	err = s.logModel.SetValue(logID, -int64(rewardValue)*maxPlayer)
+	err = s.logModel.SetMsg(logID, msg)
+	logID, err = s.logModel.AddLog(userID, taskID, models.LogTypeValue)
+	err = s.logModel.SetValue(logID, -2)
+	err = s.logModel.SetMsg(logID, msg)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return true
+}
+
+// publishScheduledTasks 发布已到定时发布时间的草稿任务
+func (s *taskService) publishScheduledTasks() {
+	tasks, err := s.model.GetScheduledTasks(time.Now().Unix())
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, task := range tasks {
+		s.publishScheduledTask(task)
+	}
+}
+
+// publishScheduledTask 发布定时任务，余额不足时保留为草稿并通知发布者
+func (s *taskService) publishScheduledTask(task models.TaskSchema) {
+	user, err := s.userModel.GetUserByID(task.Publisher)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	if reason := s.checkPublish(user, task.RewardValue, task.MaxPlayer); reason != "" {
+		err = s.model.SetTaskPublishAt(task.ID, 0)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		s.notifyScheduleFailed(task, reason)
+		return
+	}
+
+	err = s.model.PublishScheduledTask(task.ID, task.PublishAt)
+	if err == models.ErrNotExist {
+		// 任务已被修改或已发布
+		return
+	}
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+
+	if !s.chargePublish(task.Publisher, task.ID, task.RewardValue, task.MaxPlayer, "publish scheduled task") {
+		// 检查后余额发生变化，恢复为草稿
+		err = s.model.UnpublishTask(task.ID)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		s.notifyScheduleFailed(task, "no_money")
+		return
+	}
+
+	// 通知粉丝
+	followers, err := s.followModel.GetFollowerIDs(task.Publisher)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, follower := range followers {
+		_, err = s.messageModel.AddMessage(follower, models.MessageTypeTask, models.MessageSchema{
+			UserID:  task.ID,
+			Title:   "你关注的用户发布了新任务",
+			Content: task.Title,
+		})
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+}
+
+// notifyScheduleFailed 通知发布者定时发布失败
+func (s *taskService) notifyScheduleFailed(task models.TaskSchema, reason string) {
+	_, err := s.messageModel.AddMessage(task.Publisher, models.MessageTypeSystem, models.MessageSchema{
+		Title:   "任务定时发布失败",
+		Content: "你的任务「" + task.Title + "」因" + publishFailReasons[reason] + "未能按时发布，已保留为草稿",
+		About:   task.ID,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// clearExpiredTop 取消已过期的任务置顶
+func (s *taskService) clearExpiredTop() {
+	err := s.model.ClearExpiredTop(time.Now().Unix())
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
 // GetTaskByID 获取任务信息
 func (s *taskService) GetTaskByID(taskID primitive.ObjectID, userID string, biref bool) (task TaskDetail) {
 	var err error
 	taskItem, err := s.model.GetTaskByID(taskID)
 	utils.AssertErr(err, "faked_task", 403)
+	utils.Assert(!taskItem.IsHidden || taskItem.Publisher.Hex() == userID, "hidden_task", 403)
+	viewer, _ := primitive.ObjectIDFromHex(userID)
+	utils.Assert(GetServiceManger().Campus.canViewTask(taskItem, viewer), "campus_task", 403)
 	return s.makeTaskDetail(taskItem, userID, biref)
 }
 
 // GetTasks 分页获取任务列表，需要按类型/状态/酬劳类型/用户类型筛选，按关键词搜索，按不同规则排序
+// campus 为空或 all 时查看所有可见任务(不限校区、开放跨校区和浏览者认证校区的任务)，否则为逗号分隔的校区 ID
 func (s *taskService) GetTasks(page, size int64, sortRule, taskType,
-	status, reward, keyword, user, userID string, biref bool) (taskCount int64, taskCards []TaskDetail) {
+	status, reward, keyword, user, campus, userID string, biref bool) (taskCount int64, taskCards []TaskDetail) {
 
 	var taskTypes []models.TaskType
 	var statuses []models.TaskStatus
@@ -377,7 +626,32 @@ func (s *taskService) GetTasks(page, size int64, sortRule, taskType,
 		sortRule = "publish_date"
 	}
 
-	tasks, taskCount, err := s.model.GetTasks(sortRule, taskIDs, taskTypes, statuses, rewards, keywords, user, (page-1)*size, size)
+	// 隐藏已屏蔽用户发布的任务
+	var blocked []primitive.ObjectID
+	if userID != "" {
+		_userID, err := primitive.ObjectIDFromHex(userID)
+		if err == nil {
+			blocked = s.setModel.GetSets(_userID, models.SetOfBlockUser).BlockUserID
+		}
+	}
+
+	// 按校区筛选，查看自己发布的任务时不限制
+	var campusFilter *models.CampusFilter
+	if user == "" || user != userID {
+		campusFilter = &models.CampusFilter{}
+		if _userID, err := primitive.ObjectIDFromHex(userID); err == nil {
+			campusFilter.Visible = GetServiceManger().Campus.GetUserCampuses(_userID)
+		}
+		if campus != "" && campus != "all" {
+			for _, str := range strings.Split(campus, ",") {
+				id, err := primitive.ObjectIDFromHex(str)
+				utils.AssertErr(err, "invalid_campus", 400)
+				campusFilter.Campuses = append(campusFilter.Campuses, id)
+			}
+		}
+	}
+
+	tasks, taskCount, err := s.model.GetTasks(sortRule, taskIDs, taskTypes, statuses, rewards, keywords, user, campusFilter, (page-1)*size, size, blocked...)
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
 
 	for _, t := range tasks {
@@ -416,8 +690,8 @@ func (s *taskService) makeTaskDetail(task models.TaskSchema, userID string, bire
 	if userID != "" {
 		id, err := primitive.ObjectIDFromHex(userID)
 		if err == nil {
-			res.Liked = s.cache.IsLikeTask(id, task.ID)
-			res.Collected = s.cache.IsCollectTask(id, task.ID)
+			res.Liked = s.reactionModel.HasReaction(id, models.ReactionLikeTask, task.ID)
+			res.Collected = s.reactionModel.HasReaction(id, models.ReactionCollectTask, task.ID)
 			status, e := s.taskStatusModel.GetTaskStatus(id, task.ID)
 			res.Played = e == nil && status.Status != models.PlayerGiveUp
 		}
@@ -453,36 +727,48 @@ func (s *taskService) ChangeLike(taskID, userID primitive.ObjectID, like bool) {
 	utils.AssertErr(err, "faked_task", 403)
 	utils.Assert(task.Status != models.TaskStatusDraft, "not_allow_status", 403)
 	if like {
-		err = s.setModel.AddToSet(userID, taskID, models.SetOfLikeTask)
-		utils.AssertErr(err, "exist_like", 403)
+		err = s.reactionModel.AddReaction(userID, models.ReactionLikeTask, taskID, primitive.NilObjectID)
+		utils.Assert(err != models.ErrExist, "exist_like", 403)
+		utils.AssertErr(err, "", 500)
 		err = s.model.InsertCount(taskID, models.LikeCount, 1)
 	} else {
-		err = s.setModel.RemoveFromSet(userID, taskID, models.SetOfLikeTask)
+		err = s.reactionModel.RemoveReaction(userID, models.ReactionLikeTask, taskID)
 		utils.AssertErr(err, "faked_like", 403)
 		err = s.model.InsertCount(taskID, models.LikeCount, -1)
 	}
 	utils.AssertErr(err, "", 500)
-	err = s.cache.WillUpdate(userID, models.KindOfLikeTask)
-	utils.AssertErr(err, "", 500)
 }
 
-// ChangeCollection 改变收藏状态
-func (s *taskService) ChangeCollection(taskID, userID primitive.ObjectID, collect bool) {
+// ChangeCollection 改变收藏状态，folder 为空时收藏到默认收藏夹
+func (s *taskService) ChangeCollection(taskID, userID primitive.ObjectID, collect bool, folder primitive.ObjectID) {
 	task, err := s.model.GetTaskByID(taskID)
 	utils.AssertErr(err, "faked_task", 403)
 	utils.Assert(task.Status != models.TaskStatusDraft, "not_allow_status", 403)
 	if collect {
-		err = s.setModel.AddToSet(userID, taskID, models.SetOfCollectTask)
-		utils.AssertErr(err, "exist_collect", 403)
+		if !folder.IsZero() {
+			_, err = s.folderModel.GetFolder(userID, folder)
+			utils.AssertErr(err, "faked_folder", 403)
+		}
+		err = s.reactionModel.AddReaction(userID, models.ReactionCollectTask, taskID, folder)
+		utils.Assert(err != models.ErrExist, "exist_collect", 403)
+		utils.AssertErr(err, "", 500)
 		err = s.model.InsertCount(taskID, models.CollectCount, 1)
 	} else {
-		err = s.setModel.RemoveFromSet(userID, taskID, models.SetOfCollectTask)
+		err = s.reactionModel.RemoveReaction(userID, models.ReactionCollectTask, taskID)
 		utils.AssertErr(err, "faked_collect", 403)
 		err = s.model.InsertCount(taskID, models.CollectCount, -1)
 	}
 	utils.AssertErr(err, "", 500)
-	err = s.cache.WillUpdate(userID, models.KindOfCollectTask)
-	utils.AssertErr(err, "", 500)
+}
+
+// SetCollectionFolder 将收藏的任务移动到收藏夹，folder 为空时移回默认收藏夹
+func (s *taskService) SetCollectionFolder(taskID, userID, folder primitive.ObjectID) {
+	if !folder.IsZero() {
+		_, err := s.folderModel.GetFolder(userID, folder)
+		utils.AssertErr(err, "faked_folder", 403)
+	}
+	err := s.reactionModel.SetReactionFolder(userID, taskID, folder)
+	utils.AssertErr(err, "faked_collect", 403)
 }
 
 // AddPlayer 增加参与人员
@@ -491,9 +777,15 @@ func (s *taskService) AddPlayer(taskID, userID primitive.ObjectID, note string)
 	utils.AssertErr(err, "faked_task", 403)
 	utils.Assert(task.Status != models.TaskStatusDraft, "not_allow_status", 403)
 	utils.Assert(task.PlayerCount < task.MaxPlayer, "max_player", 403)
+	utils.Assert(!s.cache.IsBlockUser(task.Publisher, userID), "blocked", 403)
+	utils.Assert(GetServiceManger().Campus.canViewTask(task, userID), "campus_task", 403)
 	user, err := s.userModel.GetUserByID(userID)
 	utils.AssertErr(err, "", 500)
 	utils.Assert(user.Data.Value > 1, "no_value", 403)
+	if len(task.Identities) > 0 || len(task.Schools) > 0 {
+		utils.Assert(hasCertification(user, task.Identities, task.Schools), "not_allowed_identity", 403)
+	}
+	GetServiceManger().Credit.checkAddPlayer(user)
 	taskStatus, err := s.taskStatusModel.GetTaskStatus(userID, taskID)
 
 	status := models.PlayerWait
@@ -581,6 +873,25 @@ func (s *taskService) SetTaskStatusInfo(taskID, userID, postUserID primitive.Obj
 	})
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
 
+	// 更新口碑
+	if isPublisher && (taskStatus.Degree != 0 || taskStatus.Remark != "") {
+		s.updateReputation(userID, models.ReputationPlayer, taskStatusGet.Degree, models.ReviewSchema{
+			Task:    taskID,
+			User:    postUserID,
+			Rating:  taskStatus.Degree,
+			Content: taskStatus.Remark,
+		}, taskStatusGet.Remark)
+		GetServiceManger().Badge.onEvent(userID, BadgeFiveStar)
+	} else if !isPublisher && (taskStatus.Score != 0 || taskStatus.Feedback != "") {
+		s.updateReputation(task.Publisher, models.ReputationPublisher, taskStatusGet.Score, models.ReviewSchema{
+			Task:    taskID,
+			User:    userID,
+			Rating:  taskStatus.Score,
+			Content: taskStatus.Feedback,
+		}, taskStatusGet.Feedback)
+		GetServiceManger().Badge.onEvent(task.Publisher, BadgeFiveStar)
+	}
+
 	// 发送消息
 	if taskStatus.Status == models.PlayerRefuse {
 		_, err = s.messageModel.AddMessage(userID, models.MessageTypeTask, models.MessageSchema{
@@ -615,7 +926,10 @@ func (s *taskService) SetTaskStatusInfo(taskID, userID, postUserID primitive.Obj
 		err = s.logModel.SetValue(logID, 5)
 		err = s.logModel.SetMsg(logID, "funish task")
 		utils.AssertErr(err, "", iris.StatusInternalServerError)
-	} else if taskStatus.Status == models.PlayerFinish {
+		GetServiceManger().Credit.changeCredit(userID, taskID, CreditTaskFinish, 1)
+		GetServiceManger().Level.checkLevelUp(userID)
+		GetServiceManger().Badge.onEvent(userID, BadgeTaskFinish)
+	} else if taskStatus.Status == models.PlayerFailure {
 		_, err = s.messageModel.AddMessage(userID, models.MessageTypeTask, models.MessageSchema{
 			UserID:  taskID,
 			Title:   "很遗憾，任务已失败",
@@ -630,6 +944,7 @@ func (s *taskService) SetTaskStatusInfo(taskID, userID, postUserID primitive.Obj
 		err = s.logModel.SetValue(logID, -1)
 		err = s.logModel.SetMsg(logID, "Player Finish")
 		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		GetServiceManger().Credit.changeCredit(userID, taskID, CreditTaskFailure, 1)
 	} else if taskStatus.Status == models.PlayerGiveUp {
 		user := GetServiceManger().User.GetUserBaseInfo(userID)
 		_, err = s.messageModel.AddMessage(task.Publisher, models.MessageTypeTask, models.MessageSchema{
@@ -649,7 +964,28 @@ func (s *taskService) SetTaskStatusInfo(taskID, userID, postUserID primitive.Obj
 		err = s.logModel.SetValue(logID, -3)
 		err = s.logModel.SetMsg(logID, "Player Give Up")
 		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		if taskStatusGet.Status == models.PlayerRunning {
+			GetServiceManger().Credit.changeCredit(userID, taskID, CreditTaskGiveUp, 1)
+		}
+	}
+}
+
+// updateReputation 更新被评价用户的口碑，未修改的评分或评语沿用原有内容
+func (s *taskService) updateReputation(userID primitive.ObjectID, kind models.ReputationKind, oldRating int,
+	review models.ReviewSchema, oldContent string) {
+	if review.Rating == 0 {
+		review.Rating = oldRating
+	}
+	if review.Content == "" {
+		review.Content = oldContent
 	}
+	if review.Rating == 0 {
+		// 仅有评语没有评分时不计入口碑
+		return
+	}
+	review.Time = time.Now().Unix()
+	err := s.userModel.UpdateReputation(userID, kind, oldRating, review)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
 }
 
 // SetTaskStatus 设置任务状态
@@ -694,6 +1030,88 @@ func (s *taskService) GetTaskPlayer(taskID primitive.ObjectID, status string, pa
 	return
 }
 
+// playerStatusName 参与状态名称
+var playerStatusName = map[models.PlayerStatus]string{
+	models.PlayerWait:    "等待同意",
+	models.PlayerRefuse:  "已拒绝",
+	models.PlayerClose:   "任务已关闭",
+	models.PlayerRunning: "进行中",
+	models.PlayerFinish:  "已完成",
+	models.PlayerGiveUp:  "已放弃",
+	models.PlayerFailure: "失败",
+}
+
+// genderName 性别名称
+var genderName = map[models.UserGender]string{
+	models.GenderMan:   "男",
+	models.GenderWoman: "女",
+	models.GenderOther: "其他",
+}
+
+// exportBatchSize 导出表格时每批读取的任务状态数量
+const exportBatchSize = 100
+
+// ExportTaskPlayer 导出任务参与者[发布者/管理员]
+// 权限检查在返回前完成，返回的 export 逐行写入表格，写入开始后出错只返回错误，不再中断请求
+func (s *taskService) ExportTaskPlayer(taskID, userID primitive.ObjectID) (title string, export func(writer utils.TableWriter) error) {
+	task, err := s.model.GetTaskByID(taskID)
+	utils.AssertErr(err, "faked_task", 403)
+	if task.Publisher != userID {
+		GetServiceManger().Role.checkPermission(userID, models.PermissionModerateContent)
+	}
+
+	formatTime := func(t int64) string {
+		if t == 0 {
+			return ""
+		}
+		return time.Unix(t, 0).Format("2006-01-02 15:04:05")
+	}
+	return task.Title, func(writer utils.TableWriter) error {
+		err := writer.WriteRow([]string{"用户ID", "昵称", "性别", "状态", "申请备注", "完成度", "评语", "评分", "反馈", "申请时间", "更新时间"})
+		if err != nil {
+			return err
+		}
+		err = s.taskStatusModel.ForEachTaskStatusByTaskID(taskID, exportBatchSize, func(batch []models.TaskStatusSchema) error {
+			ids := make([]primitive.ObjectID, len(batch))
+			for i, status := range batch {
+				ids[i] = status.Player
+			}
+			players, err := s.cache.GetUsersBaseInfo(ids)
+			if err != nil {
+				return err
+			}
+			for _, status := range batch {
+				player := players[status.Player]
+				applyTime := status.Time
+				if applyTime == 0 {
+					// 旧数据无申请时间，使用 ID 中的时间
+					applyTime = status.ID.Timestamp().Unix()
+				}
+				if err = writer.WriteRow([]string{
+					status.Player.Hex(),
+					player.Nickname,
+					genderName[player.Gender],
+					playerStatusName[status.Status],
+					status.Note,
+					strconv.Itoa(status.Degree),
+					status.Remark,
+					strconv.Itoa(status.Score),
+					status.Feedback,
+					formatTime(applyTime),
+					formatTime(status.UpdateTime),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	}
+}
+
 // 获取小程序码
 func (s *taskService) GetQRCode(taskID primitive.ObjectID) string {
 	_, err := s.model.GetTaskByID(taskID)
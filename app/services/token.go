@@ -0,0 +1,462 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TimeForCoin/Server/app/models"
+	"github.com/TimeForCoin/Server/app/utils"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenService 令牌与登陆会话服务
+type TokenService interface {
+	AddSession(userID primitive.ObjectID, method string, meta models.RequestMeta) string
+	CheckSession(sessionID string, userID primitive.ObjectID, meta models.RequestMeta) bool
+	GetSessions(userID primitive.ObjectID) []models.LoginSession
+	RemoveSession(userID primitive.ObjectID, sessionID string)
+	RemoveAllSessions(userID primitive.ObjectID)
+	IssueTokens(userID primitive.ObjectID, method string, meta models.RequestMeta) TokenPair
+	RefreshTokens(refreshToken string) TokenPair
+	RevokeSession(sessionID string)
+	Authenticate(token string, meta models.RequestMeta) (TokenAuth, bool)
+	AddQRLogin(pcSession, previous string, meta models.RequestMeta) (nonce, code string)
+	ScanQRLogin(userID primitive.ObjectID, code string) models.QRLogin
+	ConfirmQRLogin(userID primitive.ObjectID, code string, confirm bool)
+	WatchQRLogin(nonce, ip string) (events <-chan models.QRLoginState, cancel func())
+	FinishQRLogin(nonce, pcSession string) primitive.ObjectID
+	GetAPITokens(userID primitive.ObjectID) []models.APITokenSchema
+	AddAPIToken(userID primitive.ObjectID, name string, scopes []models.APIScope, days int64) APITokenDetail
+	RemoveAPIToken(userID, id primitive.ObjectID)
+	// 内部服务
+	removeUserTokens(userID primitive.ObjectID)
+}
+
+// newTokenService 初始化
+func newTokenService() TokenService {
+	return &tokenService{
+		model:        models.GetModel().APIToken,
+		logModel:     models.GetModel().Log,
+		sessionModel: models.GetRedis().Session,
+		qrLoginModel: models.GetRedis().QRLogin,
+	}
+}
+
+type tokenService struct {
+	model        *models.APITokenModel
+	logModel     *models.LogModel
+	sessionModel *models.SessionModel
+	qrLoginModel *models.QRLoginModel
+}
+
+// 默认令牌有效期
+const (
+	defaultAccessExpires  = 120 // 访问令牌有效分钟数
+	defaultRefreshExpires = 30  // 刷新令牌有效天数
+	defaultMaxAPITokens   = 10  // 每个用户最多可创建的个人 API 令牌数量
+)
+
+// sessionTouchInterval 登陆会话最后访问时间的更新间隔(秒)
+const sessionTouchInterval = 60
+
+// qrLoginExpires 扫码登陆二维码有效期
+const qrLoginExpires = 5 * time.Minute
+
+// qrWatchLimit 同一 IP 同时订阅扫码登陆状态的连接数上限
+const qrWatchLimit = 10
+
+// TokenPair 登陆令牌
+type TokenPair struct {
+	Session      string // 登陆会话 ID
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // 访问令牌有效秒数
+}
+
+// TokenAuth 令牌认证结果
+type TokenAuth struct {
+	User    primitive.ObjectID
+	Session string
+	Type    utils.TokenType
+	Scopes  []models.APIScope
+}
+
+// HasScope 令牌是否拥有权限范围，登陆令牌拥有全部权限范围
+func (a TokenAuth) HasScope(scope models.APIScope) bool {
+	if a.Type != utils.TokenAPI {
+		return true
+	}
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APITokenDetail 个人 API 令牌详情，Token 仅在创建时返回
+type APITokenDetail struct {
+	models.APITokenSchema
+	Token string
+}
+
+// tokenExpires 获取访问令牌和刷新令牌的有效期
+func tokenExpires() (access, refresh time.Duration) {
+	accessMinutes, refreshDays := int64(defaultAccessExpires), int64(defaultRefreshExpires)
+	if conf := utils.GetConf(); conf != nil {
+		if conf.Token.AccessExpires > 0 {
+			accessMinutes = conf.Token.AccessExpires
+		}
+		if conf.Token.RefreshExpires > 0 {
+			refreshDays = conf.Token.RefreshExpires
+		}
+	}
+	return time.Duration(accessMinutes) * time.Minute, time.Duration(refreshDays) * time.Hour * 24
+}
+
+// addSession 记录新的登陆会话
+func (s *tokenService) addSession(userID primitive.ObjectID, method, refreshID string, meta models.RequestMeta) string {
+	sessionID := utils.NewTokenID()
+	_, refreshExpires := tokenExpires()
+	now := time.Now().Unix()
+	err := s.sessionModel.AddSession(models.LoginSession{
+		ID:        sessionID,
+		User:      userID,
+		Method:    method,
+		Refresh:   refreshID,
+		Device:    utils.DeviceName(meta.UserAgent),
+		UserAgent: meta.UserAgent,
+		IP:        meta.IP,
+		Time:      now,
+		LastSeen:  now,
+	}, refreshExpires)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	return sessionID
+}
+
+// AddSession 记录使用 Cookie 保持登陆状态的登陆会话
+func (s *tokenService) AddSession(userID primitive.ObjectID, method string, meta models.RequestMeta) string {
+	return s.addSession(userID, method, "", meta)
+}
+
+// CheckSession 检查登陆会话是否有效，并更新最后访问时间
+func (s *tokenService) CheckSession(sessionID string, userID primitive.ObjectID, meta models.RequestMeta) bool {
+	session, err := s.sessionModel.GetSession(sessionID)
+	if err != nil || session.User != userID {
+		return false
+	}
+	now := time.Now().Unix()
+	if now-session.LastSeen >= sessionTouchInterval || session.IP != meta.IP {
+		_, refreshExpires := tokenExpires()
+		_ = s.sessionModel.TouchSession(sessionID, userID, meta.IP, now, refreshExpires)
+	}
+	return true
+}
+
+// GetSessions 获取用户的全部登陆会话（按最后访问时间倒序）
+func (s *tokenService) GetSessions(userID primitive.ObjectID) []models.LoginSession {
+	sessions, err := s.sessionModel.GetUserSessions(userID)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen > sessions[j].LastSeen
+	})
+	return sessions
+}
+
+// RemoveSession 注销用户的某个登陆会话
+func (s *tokenService) RemoveSession(userID primitive.ObjectID, sessionID string) {
+	session, err := s.sessionModel.GetSession(sessionID)
+	utils.Assert(err == nil && session.User == userID, "faked_session", 403)
+	s.RevokeSession(sessionID)
+}
+
+// RemoveAllSessions 注销用户的全部登陆会话，个人 API 令牌不受影响
+func (s *tokenService) RemoveAllSessions(userID primitive.ObjectID) {
+	err := s.sessionModel.RemoveUserSessions(userID)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+}
+
+// IssueTokens 登陆成功后创建登陆会话并签发令牌
+func (s *tokenService) IssueTokens(userID primitive.ObjectID, method string, meta models.RequestMeta) TokenPair {
+	refreshID := utils.NewTokenID()
+	sessionID := s.addSession(userID, method, refreshID, meta)
+	return s.signTokens(userID, sessionID, refreshID)
+}
+
+// signTokens 签发会话的访问令牌和刷新令牌
+func (s *tokenService) signTokens(userID primitive.ObjectID, sessionID, refreshID string) TokenPair {
+	accessExpires, refreshExpires := tokenExpires()
+	now := time.Now()
+	access, err := utils.SignToken(utils.TokenClaims{
+		ID:      utils.NewTokenID(),
+		Session: sessionID,
+		User:    userID.Hex(),
+		Type:    utils.TokenAccess,
+		Expire:  now.Add(accessExpires).Unix(),
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	refresh, err := utils.SignToken(utils.TokenClaims{
+		ID:      refreshID,
+		Session: sessionID,
+		User:    userID.Hex(),
+		Type:    utils.TokenRefresh,
+		Expire:  now.Add(refreshExpires).Unix(),
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return TokenPair{
+		Session:      sessionID,
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(accessExpires / time.Second),
+	}
+}
+
+// RefreshTokens 使用刷新令牌换取新的令牌，旧的刷新令牌随即失效
+func (s *tokenService) RefreshTokens(refreshToken string) TokenPair {
+	claims, err := utils.ParseToken(refreshToken)
+	utils.Assert(err == nil && claims.Type == utils.TokenRefresh, "invalid_token", 401)
+	userID, err := primitive.ObjectIDFromHex(claims.User)
+	utils.AssertErr(err, "invalid_token", 401)
+
+	refreshID := utils.NewTokenID()
+	_, refreshExpires := tokenExpires()
+	err = s.sessionModel.RotateRefresh(claims.Session, userID, claims.ID, refreshID, refreshExpires)
+	utils.Assert(err != models.ErrNotExist, "invalid_token", 401)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	return s.signTokens(userID, claims.Session, refreshID)
+}
+
+// RevokeSession 注销登陆会话，该会话签发的令牌随即失效
+func (s *tokenService) RevokeSession(sessionID string) {
+	err := s.sessionModel.RemoveSession(sessionID)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+}
+
+// Authenticate 校验访问令牌或个人 API 令牌
+func (s *tokenService) Authenticate(token string, meta models.RequestMeta) (TokenAuth, bool) {
+	auth := TokenAuth{}
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		return auth, false
+	}
+	auth.User, err = primitive.ObjectIDFromHex(claims.User)
+	if err != nil {
+		return auth, false
+	}
+	auth.Type = claims.Type
+	switch claims.Type {
+	case utils.TokenAccess:
+		if !s.CheckSession(claims.Session, auth.User, meta) {
+			return auth, false
+		}
+		auth.Session = claims.Session
+	case utils.TokenAPI:
+		id, err := primitive.ObjectIDFromHex(claims.ID)
+		if err != nil {
+			return auth, false
+		}
+		apiToken, err := s.model.GetToken(id)
+		if err != nil || apiToken.User != auth.User {
+			return auth, false
+		}
+		auth.Scopes = apiToken.Scopes
+		_ = s.model.SetLastUsed(id, time.Now().Unix())
+	default:
+		return auth, false
+	}
+	return auth, true
+}
+
+// GetAPITokens 获取用户的个人 API 令牌列表
+func (s *tokenService) GetAPITokens(userID primitive.ObjectID) []models.APITokenSchema {
+	tokens, err := s.model.GetTokens(userID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return tokens
+}
+
+// AddAPIToken 创建个人 API 令牌，days 为有效天数，0 为永不过期
+func (s *tokenService) AddAPIToken(userID primitive.ObjectID, name string, scopes []models.APIScope, days int64) APITokenDetail {
+	utils.Assert(name != "" && len([]rune(name)) <= 32, "invalid_name", 400)
+	utils.Assert(len(scopes) > 0, "invalid_scope", 400)
+	for _, scope := range scopes {
+		valid := false
+		for _, s := range models.AllScopes {
+			if scope == s {
+				valid = true
+				break
+			}
+		}
+		utils.Assert(valid, "invalid_scope", 400)
+	}
+	utils.Assert(days >= 0, "invalid_days", 400)
+
+	maxTokens := int64(defaultMaxAPITokens)
+	if conf := utils.GetConf(); conf != nil && conf.Token.MaxAPITokens > 0 {
+		maxTokens = conf.Token.MaxAPITokens
+	}
+	count, err := s.model.CountTokens(userID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	utils.Assert(count < maxTokens, "max_token", 403)
+
+	var expireTime int64
+	if days > 0 {
+		expireTime = time.Now().Unix() + days*24*60*60
+	}
+	apiToken, err := s.model.AddToken(userID, name, scopes, expireTime)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	token, err := utils.SignToken(utils.TokenClaims{
+		ID:     apiToken.ID.Hex(),
+		User:   userID.Hex(),
+		Type:   utils.TokenAPI,
+		Expire: expireTime,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return APITokenDetail{
+		APITokenSchema: apiToken,
+		Token:          token,
+	}
+}
+
+// RemoveAPIToken 删除个人 API 令牌
+func (s *tokenService) RemoveAPIToken(userID, id primitive.ObjectID) {
+	err := s.model.RemoveToken(userID, id)
+	utils.AssertErr(err, "faked_token", 403)
+}
+
+// removeUserTokens 删除用户的全部个人 API 令牌
+func (s *tokenService) removeUserTokens(userID primitive.ObjectID) {
+	err := s.model.RemoveUserTokens(userID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// AddQRLogin 为 PC 端会话创建扫码登陆二维码内容，同时作废该会话之前的二维码
+func (s *tokenService) AddQRLogin(pcSession, previous string, meta models.RequestMeta) (nonce, code string) {
+	if previous != "" {
+		err := s.qrLoginModel.RemoveQRLogin(previous)
+		utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	}
+	now := time.Now()
+	nonce = utils.NewTokenID()
+	err := s.qrLoginModel.AddQRLogin(models.QRLogin{
+		ID:        nonce,
+		Session:   pcSession,
+		IP:        meta.IP,
+		Device:    utils.DeviceName(meta.UserAgent),
+		UserAgent: meta.UserAgent,
+		Time:      now.Unix(),
+	}, qrLoginExpires)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	// 二维码中只包含 nonce，PC 端会话 ID 仅保存在服务端
+	code, err = utils.SignToken(utils.TokenClaims{
+		ID:     nonce,
+		Type:   utils.TokenQRLogin,
+		Expire: now.Add(qrLoginExpires).Unix(),
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return
+}
+
+// parseQRCode 校验二维码内容并返回 nonce
+func parseQRCode(code string) string {
+	claims, err := utils.ParseToken(code)
+	utils.Assert(err == nil && claims.Type == utils.TokenQRLogin, "invalid_code", 400)
+	return claims.ID
+}
+
+// ScanQRLogin 手机扫码，返回 PC 端信息供用户确认
+func (s *tokenService) ScanQRLogin(userID primitive.ObjectID, code string) models.QRLogin {
+	nonce := parseQRCode(code)
+	err := s.qrLoginModel.ScanQRLogin(nonce, userID)
+	utils.Assert(err != models.ErrNotExist, "invalid_code", 403)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	login, err := s.qrLoginModel.GetQRLogin(nonce)
+	utils.Assert(err != models.ErrNotExist, "invalid_code", 403)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	return login
+}
+
+// ConfirmQRLogin 手机确认或拒绝 PC 端登陆
+func (s *tokenService) ConfirmQRLogin(userID primitive.ObjectID, code string, confirm bool) {
+	err := s.qrLoginModel.ConfirmQRLogin(parseQRCode(code), userID, confirm)
+	utils.Assert(err != models.ErrNotExist, "invalid_code", 403)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	if confirm {
+		logID, err := s.logModel.AddLog(userID, userID, models.LogTypeLogin)
+		err = s.logModel.SetMsg(logID, "Login By Wechat On PC")
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+}
+
+// WatchQRLogin 监听扫码登陆状态，先发送当前状态，到达 confirmed、rejected 或 expired 后关闭
+// 同一 IP 同时监听的连接数有上限
+func (s *tokenService) WatchQRLogin(nonce, ip string) (<-chan models.QRLoginState, func()) {
+	ok, err := s.qrLoginModel.AcquireWatch(ip, qrWatchLimit, qrLoginExpires)
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	utils.Assert(ok, "too_many_watch", iris.StatusTooManyRequests)
+	sub := s.qrLoginModel.Subscribe(nonce)
+	release := func() {
+		_ = sub.Close()
+		_ = s.qrLoginModel.ReleaseWatch(ip)
+	}
+	// 确认订阅成功后再读取当前状态，避免错过状态变化
+	_, err = sub.Receive()
+	if err != nil {
+		release()
+		utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	}
+	login, err := s.qrLoginModel.GetQRLogin(nonce)
+	if err == models.ErrNotExist {
+		login.State = models.QRLoginExpired
+	} else if err != nil {
+		release()
+		utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	}
+
+	events := make(chan models.QRLoginState)
+	done := make(chan struct{})
+	go func() {
+		defer close(events)
+		defer release()
+		timeout := time.NewTimer(time.Until(time.Unix(login.Time, 0).Add(qrLoginExpires)))
+		defer timeout.Stop()
+		messages := sub.Channel()
+		state := login.State
+		for {
+			select {
+			case events <- state:
+			case <-done:
+				return
+			}
+			if state != models.QRLoginPending && state != models.QRLoginScanned {
+				return
+			}
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				state = models.QRLoginState(msg.Payload)
+			case <-timeout.C:
+				state = models.QRLoginExpired
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return events, func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// FinishQRLogin 完成扫码登陆，只有创建二维码的 PC 端会话可以使用，返回登陆用户，失败时返回空 ID
+func (s *tokenService) FinishQRLogin(nonce, pcSession string) primitive.ObjectID {
+	userID, err := s.qrLoginModel.FinishQRLogin(nonce, pcSession)
+	if err == models.ErrNotExist {
+		return primitive.NilObjectID
+	}
+	utils.AssertErr(err, "redis_error", iris.StatusInternalServerError)
+	return userID
+}
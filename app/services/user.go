@@ -1,58 +1,96 @@
 package services
 
 import (
+	"archive/zip"
+	"io"
 	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/TimeForCoin/Server/app/utils"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"gopkg.in/xmatrixstudio/violet.sdk.go.v3"
 
 	"github.com/TimeForCoin/Server/app/libs"
 	"github.com/TimeForCoin/Server/app/models"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/kataras/iris/v12"
 )
 
+// maxCollectFolder 每个用户最多可创建的收藏夹数量
+const maxCollectFolder = 50
+
+// inviteCodeRetry 邀请码重复时的最大生成次数
+const inviteCodeRetry = 3
+
 // UserService 用户逻辑
 type UserService interface {
 	GetLoginURL() (url, state string)
-	GetUser(id primitive.ObjectID, isMe bool) UserDetail
+	GetUser(id, viewerID primitive.ObjectID) UserDetail
 	GetUserBaseInfo(id primitive.ObjectID) models.UserBaseInfo
-	UserAttend(id primitive.ObjectID)
+	UserAttend(id primitive.ObjectID) AttendanceResult
 	UserPay(id primitive.ObjectID)
 	SetUserInfo(id primitive.ObjectID, info models.UserInfoSchema)
 	LoginByViolet(code string) (id string, new bool)
 	LoginByWechat(code string) (id string, new bool)
-	LoginByWechatOnPC(userID, sessionID primitive.ObjectID)
-	GetSessionUser(sessionID primitive.ObjectID) primitive.ObjectID
-	SetUserType(admin primitive.ObjectID, id primitive.ObjectID, userType models.UserType)
-	SearchUser(key string, page, size int64) []UserDetail
-	GetUserCollections(id primitive.ObjectID, page, size int64, sortRule string, taskType string,
+	BindViolet(userID primitive.ObjectID, code string, merge bool) (id string)
+	BindWechat(userID primitive.ObjectID, code string, merge bool) (id string)
+	SetUserType(admin primitive.ObjectID, id primitive.ObjectID, userType models.UserType, meta models.RequestMeta)
+	SearchUser(search models.UserSearchFilter, sort models.UserSearchSort, viewerID primitive.ObjectID, page, size int64) (int64, []UserDetail)
+	GetUserCollections(id primitive.ObjectID, page, size int64, folder string, taskType string,
 		status string, reward string) (taskCount int64, taskCards []TaskDetail)
 	GetUserParticipate(id primitive.ObjectID, page, size int64, status string) (taskStatusCount int64, taskStatusDetailList []TaskStatusDetail)
+	// 账号数据
+	ExportUserData(id primitive.ObjectID) (export func(w io.Writer))
+	DeleteUser(id primitive.ObjectID)
+	// 签到相关
+	MakeUpAttend(id primitive.ObjectID, date string) AttendanceResult
+	GetAttendanceCalendar(id primitive.ObjectID, month string) AttendanceCalendar
 	// 搜索相关
 	GetSearchHistory(id primitive.ObjectID) []string
 	ClearSearchHistory(id primitive.ObjectID)
+	// 隐私相关
+	GetPrivacy(id primitive.ObjectID) models.UserPrivacySchema
+	SetPrivacy(id primitive.ObjectID, privacy models.UserPrivacySchema)
 	// 认证相关
-	CancelCertification(id primitive.ObjectID)
-	UpdateCertification(id primitive.ObjectID, operate, data string)
+	GetIdentities() []utils.IdentityConfig
+	CancelCertification(id primitive.ObjectID, identity models.UserIdentity)
+	UpdateCertification(adminID, id primitive.ObjectID, identity models.UserIdentity, operate, data string, meta models.RequestMeta)
 	CheckCertification(id primitive.ObjectID, code string) string
 	SendCertificationEmail(id primitive.ObjectID, email string)
 	AddEmailCertification(identity models.UserIdentity, id primitive.ObjectID, data, email string)
 	AddMaterialCertification(identity models.UserIdentity, id primitive.ObjectID, data string, attachment []primitive.ObjectID)
+	AddInviteCertification(id primitive.ObjectID, code string)
 	GetCertificationList(userID primitive.ObjectID, types []models.CertificationStatus, page, size int64) (users []UserDetail)
 	GetAutoCertification(userID primitive.ObjectID, page, size int64) (keys []models.SystemSchemas)
-	AddAutoCertification(userID primitive.ObjectID, key, data string)
-	RemoveAutoCertification(userID primitive.ObjectID, key string)
+	AddAutoCertification(userID primitive.ObjectID, key, data string, identities []models.UserIdentity, validDays int64, meta models.RequestMeta)
+	RemoveAutoCertification(userID primitive.ObjectID, key string, meta models.RequestMeta)
+	GetInvites(userID primitive.ObjectID, page, size int64) (int64, []models.InviteSchema)
+	AddInvite(userID primitive.ObjectID, identity models.UserIdentity, data string, maxUse, days int64, meta models.RequestMeta) string
+	RemoveInvite(userID, id primitive.ObjectID, meta models.RequestMeta)
+	// 收藏夹相关
+	GetCollectFolders(id primitive.ObjectID) []models.FolderSchema
+	AddCollectFolder(id primitive.ObjectID, name string) primitive.ObjectID
+	SetCollectFolder(id, folderID primitive.ObjectID, name string)
+	RemoveCollectFolder(id, folderID primitive.ObjectID)
 	// 关注相关
-	GetFollowing(id primitive.ObjectID, page, size int64) ([]models.UserBaseInfo, int64)
-	GetFollower(id primitive.ObjectID, page, size int64) ([]models.UserBaseInfo, int64)
+	GetFollowing(id primitive.ObjectID, page, size int64) ([]FollowUser, int64)
+	GetFollower(id primitive.ObjectID, page, size int64) ([]FollowUser, int64)
+	GetMutualFollow(id primitive.ObjectID, page, size int64) ([]FollowUser, int64)
+	GetFollowSuggestions(id primitive.ObjectID, size int64) []FollowSuggestion
 	FollowUser(userID, followID primitive.ObjectID)
 	UnFollowUser(userID, followID primitive.ObjectID)
 	IsFollower(userID, followID primitive.ObjectID) bool
 	IsFollowing(userID, followID primitive.ObjectID) bool
+	// 屏蔽相关
+	GetBlockList(id primitive.ObjectID, page, size int64) ([]models.UserBaseInfo, int64)
+	BlockUser(userID, blockID primitive.ObjectID)
+	UnBlockUser(userID, blockID primitive.ObjectID)
+	// 内部服务
+	remindCertifications()
+	expireCertifications()
 }
 
 // NewUserService 初始化
@@ -67,6 +105,15 @@ func newUserService() UserService {
 		fileModel:       models.GetModel().File,
 		taskStatusModel: models.GetModel().TaskStatus,
 		logModel:        models.GetModel().Log,
+		attendanceModel: models.GetModel().Attendance,
+		messageModel:    models.GetModel().Message,
+		commentModel:    models.GetModel().Comment,
+		questionModel:   models.GetModel().Questionnaire,
+		inviteModel:     models.GetModel().Invite,
+		followModel:     models.GetModel().Follow,
+		reactionModel:   models.GetModel().Reaction,
+		folderModel:     models.GetModel().Folder,
+		reportModel:     models.GetModel().Report,
 	}
 }
 
@@ -80,18 +127,52 @@ type userService struct {
 	fileModel       *models.FileModel
 	taskStatusModel *models.TaskStatusModel
 	logModel        *models.LogModel
+	attendanceModel *models.AttendanceModel
+	messageModel    *models.MessageModel
+	commentModel    *models.CommentModel
+	questionModel   *models.QuestionnaireModel
+	inviteModel     *models.InviteModel
+	followModel     *models.FollowModel
+	reactionModel   *models.ReactionModel
+	folderModel     *models.FolderModel
+	reportModel     *models.ReportModel
 }
 
 // UserDetail 用户详细信息
 type UserDetail struct {
-	UserID        primitive.ObjectID `json:"-"`
-	ID            string             `json:"id"`
-	VioletName    string             `json:"violet_name,omitempty"`
-	WechatName    string             `json:"wechat_name,omitempty"`
-	RegisterTime  int64
-	Info          models.UserInfoSchema
-	Data          *UserDataRes
-	Certification *UserCertification
+	UserID         primitive.ObjectID `json:"-"`
+	ID             string             `json:"id"`
+	VioletName     string             `json:"violet_name,omitempty"`
+	WechatName     string             `json:"wechat_name,omitempty"`
+	RegisterTime   int64
+	Info           models.UserInfoSchema
+	Data           *UserDataRes
+	Certifications []UserCertification
+	Reputation     *UserReputation
+	Badges         []BadgeDetail
+}
+
+// UserReputation 用户口碑
+type UserReputation struct {
+	Player    RatingDetail // 作为参与者
+	Publisher RatingDetail // 作为发布者
+}
+
+// RatingDetail 评价统计详情
+type RatingDetail struct {
+	*models.RatingSchema
+	// 额外项
+	Reviews []ReviewDetail // 最近评价
+	// 排除项
+	Total  omit `json:"total,omitempty"`
+	Recent omit `json:"recent,omitempty"`
+}
+
+// ReviewDetail 评价详情
+type ReviewDetail struct {
+	*models.ReviewSchema
+	// 额外项
+	User models.UserBaseInfo
 }
 
 // UserDataRes 用户数据返回值
@@ -111,11 +192,15 @@ type UserDataRes struct {
 // UserCertification 用户认证信息
 type UserCertification struct {
 	Type     models.UserIdentity
+	Method   models.CertificationMethod
 	Status   models.CertificationStatus
-	Email    string
+	Email    string `json:"email,omitempty"`
 	Data     string
 	Date     int64
-	Material []models.FileSchema `json:"material,omitempty"`
+	Feedback string `json:"feedback,omitempty"`
+
+	ExpireTime int64               // 过期时间，0 为永不过期
+	Material   []models.FileSchema `json:"material,omitempty"`
 }
 
 // TaskStatus 任务参与情况
@@ -162,7 +247,6 @@ func (s *userService) GetLoginURL() (url, state string) {
 // LoginByViolet 使用 Violet 授权登陆
 func (s *userService) LoginByViolet(code string) (id string, new bool) {
 	res, err := s.oAuth.API.GetToken(code)
-	// TODO 检测是否绑定微信
 	if err != nil {
 		return "", false
 	}
@@ -215,21 +299,111 @@ func (s *userService) LoginByViolet(code string) (id string, new bool) {
 	return userID.Hex(), true
 }
 
-// 微信扫码登陆
-func (s *userService) LoginByWechatOnPC(userID, sessionID primitive.ObjectID) {
-	err := s.cache.SetSessionUser(sessionID.Hex(), userID)
-	utils.AssertErr(err, "", 500)
-	logID, err := s.logModel.AddLog(userID, userID, models.LogTypeLogin)
-	err = s.logModel.SetMsg(logID, "Login By Wechat On PC")
-	utils.AssertErr(err, "", iris.StatusInternalServerError)
+// BindViolet 已登录用户绑定 Violet 账号
+// 若该 Violet 账号已属于其他用户，merge 为 true 时合并两个账号，返回合并后的用户 ID
+func (s *userService) BindViolet(userID primitive.ObjectID, code string, merge bool) (id string) {
+	res, err := s.oAuth.API.GetToken(code)
+	utils.AssertErr(err, "invalid_code", 403)
+	user, err := s.model.GetUserByID(userID)
+	utils.AssertErr(err, "invalid_session", 401)
+	utils.Assert(user.VioletID == "", "exist_bind", 403)
+	other, err := s.model.GetUserByViolet(res.UserID)
+	if err != nil {
+		err = s.model.SetUserBind(userID, res.UserID, "")
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		logID, err := s.logModel.AddLog(userID, userID, models.LogTypeLogin)
+		err = s.logModel.SetMsg(logID, "Bind Violet")
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		return userID.Hex()
+	}
+	utils.Assert(merge, "exist_account", 403)
+	return s.mergeUser(user, other).Hex()
 }
 
-func (s *userService) GetSessionUser(session primitive.ObjectID) primitive.ObjectID {
-	id, err := s.cache.GetSessionUser(session.Hex())
+// BindWechat 已登录用户绑定微信账号
+// 若该微信账号已属于其他用户，merge 为 true 时合并两个账号，返回合并后的用户 ID
+func (s *userService) BindWechat(userID primitive.ObjectID, code string, merge bool) (id string) {
+	openID, err := libs.GetWeChat().GetOpenID(code)
+	utils.AssertErr(err, "invalid_code", 403)
+	user, err := s.model.GetUserByID(userID)
+	utils.AssertErr(err, "invalid_session", 401)
+	utils.Assert(user.WechatID == "", "exist_bind", 403)
+	other, err := s.model.GetUserByWechat(openID)
 	if err != nil {
-		return primitive.NilObjectID
+		err = s.model.SetUserBind(userID, "", openID)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		logID, err := s.logModel.AddLog(userID, userID, models.LogTypeLogin)
+		err = s.logModel.SetMsg(logID, "Bind Wechat")
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		return userID.Hex()
 	}
-	return id
+	utils.Assert(merge, "exist_account", 403)
+	return s.mergeUser(user, other).Hex()
+}
+
+// mergeUser 合并两个账号，保留注册较早的账号，另一账号的数据转移后删除
+func (s *userService) mergeUser(a, b models.UserSchema) primitive.ObjectID {
+	utils.Assert(a.ID != b.ID, "exist_bind", 403)
+	to, from := a, b
+	if b.RegisterTime < a.RegisterTime {
+		to, from = b, a
+	}
+	// 同一平台只能绑定一个账号
+	utils.Assert(to.VioletID == "" || from.VioletID == "", "bind_conflict", 403)
+	utils.Assert(to.WechatID == "" || from.WechatID == "", "bind_conflict", 403)
+
+	// 先转移数据，最后合并账号并删除 from，中途失败时可以重新合并
+	err := s.taskModel.TransferPublisher(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.taskStatusModel.TransferPlayer(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.fileModel.TransferOwner(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.messageModel.TransferUser(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.logModel.TransferUser(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.commentModel.TransferUser(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.reportModel.TransferUser(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.attendanceModel.MergeAttendances(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+
+	// 合并集合、点赞收藏和关注关系，并重新统计关注数据
+	err = s.setModel.MergeSets(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.reactionModel.MergeReactions(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.folderModel.TransferFolders(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	related, err := s.followModel.MergeFollows(from.ID, to.ID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, id := range append(related, to.ID) {
+		following, follower, err := s.followModel.CountFollows(id)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		err = s.model.SetFollowCount(id, following, follower)
+		utils.Assert(err == nil || err == models.ErrNotExist, "", iris.StatusInternalServerError)
+	}
+
+	err = s.model.MergeUser(to.ID, from)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	// 注销 from 的登陆会话和个人 API 令牌
+	GetServiceManger().Token.RemoveAllSessions(from.ID)
+	GetServiceManger().Token.removeUserTokens(from.ID)
+
+	// 清除缓存
+	for _, id := range []primitive.ObjectID{from.ID, to.ID} {
+		for _, kind := range []models.DataKind{models.KindOfBaseInfo, models.KindOfBlock} {
+			utils.Assert(s.cache.WillUpdate(id, kind) == nil, "redis_error", iris.StatusInternalServerError)
+		}
+	}
+
+	logID, err := s.logModel.AddLog(to.ID, from.ID, models.LogTypeLogin)
+	err = s.logModel.SetMsg(logID, "Merge User")
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Level.checkLevelUp(to.ID)
+	return to.ID
 }
 
 // LoginByWechat 使用微信登陆
@@ -253,19 +427,61 @@ func (s *userService) LoginByWechat(code string) (id string, new bool) {
 }
 
 // GetUser 获取用户数据
-func (s *userService) GetUser(id primitive.ObjectID, isMe bool) UserDetail {
+func (s *userService) GetUser(id, viewerID primitive.ObjectID) UserDetail {
 	user, err := s.model.GetUserByID(id)
 	utils.AssertErr(err, "faked_users", 403)
-	return s.makeUserRes(user, isMe)
+	return s.makeUserRes(user, viewerID, id == viewerID)
 }
 
-// SearchUser 搜索用户
-func (s *userService) SearchUser(key string, page, size int64) (res []UserDetail) {
-	users := s.model.GetUsers(key, page, size)
+// SearchUser 按条件搜索用户
+func (s *userService) SearchUser(search models.UserSearchFilter, sort models.UserSearchSort,
+	viewerID primitive.ObjectID, page, size int64) (int64, []UserDetail) {
+	utils.Assert(sort == models.UserSortRelevance || sort == models.UserSortFollower || sort == models.UserSortRating,
+		"invalid_sort", 400)
+	utils.Assert(search.Gender == "" || search.Gender == models.GenderMan || search.Gender == models.GenderWoman ||
+		search.Gender == models.GenderOther, "invalid_gender", 400)
+	utils.Assert(search.Identity == "" || utils.IsIdentity(string(search.Identity)), "invalid_identity", 400)
+	utils.Assert(search.MinCredit >= 0 && search.MinLevel >= 0, "invalid_value", 400)
+	users, count, err := s.model.SearchUsers(search, sort, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := []UserDetail{}
 	for i := range users {
-		res = append(res, s.makeUserRes(users[i], false))
+		res = append(res, s.makeUserRes(users[i], viewerID, false))
 	}
-	return res
+	return count, res
+}
+
+// GetPrivacy 获取个人信息隐私设置
+func (s *userService) GetPrivacy(id primitive.ObjectID) models.UserPrivacySchema {
+	user, err := s.model.GetUserByID(id)
+	utils.AssertErr(err, "invalid_session", 401)
+	return fillPrivacy(user.Privacy)
+}
+
+// SetPrivacy 修改个人信息隐私设置，为空的字段不修改
+func (s *userService) SetPrivacy(id primitive.ObjectID, privacy models.UserPrivacySchema) {
+	current := s.GetPrivacy(id)
+	for _, level := range []*models.PrivacyLevel{&privacy.Email, &privacy.Phone, &privacy.School, &privacy.Location, &privacy.Birthday} {
+		utils.Assert(*level == "" || *level == models.PrivacyPublic || *level == models.PrivacyFollowers ||
+			*level == models.PrivacyPartners || *level == models.PrivacyPrivate, "invalid_privacy", 400)
+	}
+	if privacy.Email != "" {
+		current.Email = privacy.Email
+	}
+	if privacy.Phone != "" {
+		current.Phone = privacy.Phone
+	}
+	if privacy.School != "" {
+		current.School = privacy.School
+	}
+	if privacy.Location != "" {
+		current.Location = privacy.Location
+	}
+	if privacy.Birthday != "" {
+		current.Birthday = privacy.Birthday
+	}
+	err := s.model.SetUserPrivacy(id, current)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
 }
 
 func (s *userService) UserPay(id primitive.ObjectID) {
@@ -279,88 +495,372 @@ func (s *userService) UserPay(id primitive.ObjectID) {
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
 }
 
+// ExportUserData 导出用户个人数据，返回将数据以 JSON 文件打包写入 zip 压缩包的函数
+func (s *userService) ExportUserData(id primitive.ObjectID) (export func(w io.Writer)) {
+	user, err := s.model.GetUserByID(id)
+	utils.AssertErr(err, "invalid_session", 401)
+	logs, _, err := s.logModel.GetLog(id, []models.LogType{models.LogTypeMoney, models.LogTypeValue,
+		models.LogTypeCredit, models.LogTypeLogin}, 0, 0, 0, 0)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	tasks, err := s.taskModel.GetTasksByPublisher(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	taskStatus, _, err := s.taskStatusModel.GetTaskStatusListByUserID(id, []models.PlayerStatus{
+		models.PlayerWait, models.PlayerRefuse, models.PlayerClose, models.PlayerRunning,
+		models.PlayerFinish, models.PlayerGiveUp, models.PlayerFailure}, 0, 0)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	comments, err := s.commentModel.GetCommentsByUser(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	messages, err := s.messageModel.GetAllSessionsByUser(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	answers, err := s.questionModel.GetAnswersByUser(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	files, err := s.fileModel.GetFileByContent(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+
+	data := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile", user},
+		{"logs", logs},
+		{"tasks", tasks},
+		{"task_status", taskStatus},
+		{"comments", comments},
+		{"messages", messages},
+		{"questionnaire_answers", answers},
+		{"files", files},
+	}
+	return func(w io.Writer) {
+		archive := zip.NewWriter(w)
+		for _, d := range data {
+			file, err := archive.Create(d.name + ".json")
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+			b, err := jsoniter.MarshalIndent(d.value, "", "  ")
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+			_, err = file.Write(b)
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+		}
+		utils.AssertErr(archive.Close(), "", iris.StatusInternalServerError)
+	}
+}
+
+// DeleteUser 注销账号
+// 关闭发布的任务并结算托管金额，清除个人信息，私有文件在保留期后删除
+func (s *userService) DeleteUser(id primitive.ObjectID) {
+	user, err := s.model.GetUserByID(id)
+	utils.AssertErr(err, "invalid_session", 401)
+	utils.Assert(user.DeleteTime == 0, "invalid_session", 401)
+	utils.Assert(user.Data.Type != models.UserTypeRoot, "permission_deny", 403)
+
+	GetServiceManger().Task.closeUserTasks(id)
+	GetServiceManger().Token.removeUserTokens(id)
+	GetServiceManger().Token.RemoveAllSessions(id)
+
+	var graceDays int64
+	if conf := utils.GetConf(); conf != nil {
+		graceDays = conf.DeleteGraceDays
+	}
+	err = s.fileModel.SetUserFilesExpire(id, time.Now().Unix()+graceDays*24*60*60)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	err = s.model.AnonymizeUser(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	utils.Assert(s.cache.WillUpdate(id, models.KindOfBaseInfo) == nil, "redis_error", iris.StatusInternalServerError)
+
+	logID, err := s.logModel.AddLog(id, id, models.LogTypeLogin)
+	err = s.logModel.SetMsg(logID, "Delete User")
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+}
+
+// AttendanceResult 签到结果
+type AttendanceResult struct {
+	Date   string // 签到日期
+	Reward int64  // 获得积分
+	Cost   int64  // 补签花费的金币
+	Streak int64  // 当前连续签到天数
+}
+
+// AttendanceCalendar 签到日历
+type AttendanceCalendar struct {
+	Month         string                    // 月份 (2006-01)
+	Days          []models.AttendanceSchema // 当月签到记录
+	Streak        int64                     // 当前连续签到天数
+	LongestStreak int64                     // 最长连续签到天数
+	MakeUpCost    int64                     // 补签花费的金币
+	MakeUpLeft    int64                     // 本月剩余补签次数
+	MakeUpDays    int64                     // 最多补签多少天前的日期，0 为不限制
+}
+
+// attendanceConf 获取签到配置
+func (s *userService) attendanceConf() utils.AttendanceConfig {
+	if conf := utils.GetConf(); conf != nil {
+		return conf.Attendance
+	}
+	return utils.AttendanceConfig{}
+}
+
+// attendanceReward 根据连续签到天数计算奖励积分
+func (s *userService) attendanceReward(streak int64) (value int64) {
+	for _, reward := range s.attendanceConf().Rewards {
+		if streak < reward.Streak {
+			break
+		}
+		value = reward.Value
+	}
+	return
+}
+
+// currentStreak 当前连续签到天数，昨天和今天都未签到时为 0
+func (s *userService) currentStreak(data models.UserDataSchema) int64 {
+	attendanceTime := time.Unix(data.AttendanceDate, 0).Format(models.AttendanceDateLayout)
+	now := time.Now()
+	if attendanceTime != now.Format(models.AttendanceDateLayout) &&
+		attendanceTime != now.AddDate(0, 0, -1).Format(models.AttendanceDateLayout) {
+		return 0
+	}
+	return data.AttendanceStreak
+}
+
 // UserAttend 用户签到
-func (s *userService) UserAttend(id primitive.ObjectID) {
+func (s *userService) UserAttend(id primitive.ObjectID) AttendanceResult {
 	user, err := s.model.GetUserByID(id)
 	utils.AssertErr(err, "invalid_session", 401)
-	lastAttend := time.Unix(user.Data.AttendanceDate, 0)
-	nowDate := time.Now()
-	if lastAttend.Add(time.Hour*24).After(nowDate) && lastAttend.YearDay() == nowDate.YearDay() {
-		utils.Assert(false, "already_attend", 403)
+	now := time.Now()
+	today := now.Format(models.AttendanceDateLayout)
+	utils.Assert(time.Unix(user.Data.AttendanceDate, 0).Format(models.AttendanceDateLayout) != today, "already_attend", 403)
+
+	streak, err := s.attendanceModel.GetAttendanceStreak(id, now.AddDate(0, 0, -1).Format(models.AttendanceDateLayout))
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	streak++
+	reward := s.attendanceReward(streak)
+	added, err := s.attendanceModel.AddAttendance(models.AttendanceSchema{
+		User:   id,
+		Date:   today,
+		Reward: reward,
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	utils.Assert(added, "already_attend", 403)
+
+	if reward > 0 {
+		err = s.model.UpdateUserDataCount(id, models.UserDataCount{
+			Value: reward,
+		})
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		logID, err := s.logModel.AddLog(id, id, models.LogTypeValue)
+		err = s.logModel.SetValue(logID, reward)
+		err = s.logModel.SetMsg(logID, "user attend")
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+	err = s.model.SetUserAttend(id, streak)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Level.checkLevelUp(id)
+	GetServiceManger().Badge.onEvent(id, BadgeAttendance)
+	return AttendanceResult{
+		Date:   today,
+		Reward: reward,
+		Streak: streak,
+	}
+}
+
+// MakeUpAttend 花费金币补签
+func (s *userService) MakeUpAttend(id primitive.ObjectID, date string) AttendanceResult {
+	conf := s.attendanceConf()
+	utils.Assert(conf.MakeUpLimit > 0, "make_up_disabled", 403)
+	day, err := time.ParseInLocation(models.AttendanceDateLayout, date, time.Local)
+	utils.AssertErr(err, "invalid_date", 400)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	utils.Assert(day.Before(today), "invalid_date", 400)
+	utils.Assert(conf.MakeUpDays == 0 || !day.Before(today.AddDate(0, 0, -int(conf.MakeUpDays))), "invalid_date", 400)
+
+	user, err := s.model.GetUserByID(id)
+	utils.AssertErr(err, "invalid_session", 401)
+	utils.Assert(date >= time.Unix(user.RegisterTime, 0).Format(models.AttendanceDateLayout), "invalid_date", 400)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	count, err := s.attendanceModel.CountMakeUp(id, monthStart.Unix(), monthStart.AddDate(0, 1, 0).Unix())
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	utils.Assert(count < conf.MakeUpLimit, "make_up_limit", 403)
+
+	// 先扣除金币，余额不足时不会扣成负数
+	if conf.MakeUpCost > 0 {
+		err = s.model.SpendMoney(id, conf.MakeUpCost)
+		utils.Assert(err != models.ErrNotExist, "no_money", 403)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
 	}
-	err = s.model.UpdateUserDataCount(id, models.UserDataCount{
-		Value: rand.Int63n(20),
+	added, err := s.attendanceModel.AddAttendance(models.AttendanceSchema{
+		User:   id,
+		Date:   date,
+		MakeUp: true,
+		Cost:   conf.MakeUpCost,
 	})
+	if (err != nil || !added) && conf.MakeUpCost > 0 {
+		// 补签失败，退回金币
+		_ = s.model.UpdateUserDataCount(id, models.UserDataCount{
+			Money: conf.MakeUpCost,
+		})
+	}
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	utils.Assert(added, "already_attend", 403)
+	if conf.MakeUpCost > 0 {
+		logID, err := s.logModel.AddLog(id, id, models.LogTypeMoney)
+		err = s.logModel.SetValue(logID, -conf.MakeUpCost)
+		err = s.logModel.SetMsg(logID, "user attend make up")
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
 
-	// FIX:
-	// app\services\user.go:295:34: undefined: userID
-	// app\services\user.go:295:42: undefined: taskID
-	logID, err := s.logModel.AddLog(userID, taskID, models.LogTypeValue)
-	err = s.logModel.SetValue(logID, rand.Int63n(20))
-	err = s.logModel.SetMsg(logID, "user attend")
+	// 补签可能连接前后的签到，重新计算连续天数
+	streak, err := s.attendanceModel.GetAttendanceStreak(id, today.Format(models.AttendanceDateLayout))
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
-	err = s.model.SetUserAttend(id)
+	if streak == 0 {
+		streak, err = s.attendanceModel.GetAttendanceStreak(id, today.AddDate(0, 0, -1).Format(models.AttendanceDateLayout))
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+	}
+	if streak > 0 {
+		err = s.model.SetAttendanceStreak(id, streak)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		GetServiceManger().Badge.onEvent(id, BadgeAttendance)
+	}
+	return AttendanceResult{
+		Date:   date,
+		Cost:   conf.MakeUpCost,
+		Streak: streak,
+	}
+}
+
+// GetAttendanceCalendar 获取某月签到日历
+func (s *userService) GetAttendanceCalendar(id primitive.ObjectID, month string) AttendanceCalendar {
+	now := time.Now()
+	if month == "" {
+		month = now.Format("2006-01")
+	}
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	utils.AssertErr(err, "invalid_month", 400)
+	user, err := s.model.GetUserByID(id)
+	utils.AssertErr(err, "invalid_session", 401)
+	days, err := s.attendanceModel.GetAttendances(id, start.Format(models.AttendanceDateLayout),
+		start.AddDate(0, 1, -1).Format(models.AttendanceDateLayout))
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+
+	conf := s.attendanceConf()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	count, err := s.attendanceModel.CountMakeUp(id, monthStart.Unix(), monthStart.AddDate(0, 1, 0).Unix())
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	left := conf.MakeUpLimit - count
+	if left < 0 {
+		left = 0
+	}
+	return AttendanceCalendar{
+		Month:         month,
+		Days:          days,
+		Streak:        s.currentStreak(user.Data),
+		LongestStreak: user.Data.LongestStreak,
+		MakeUpCost:    conf.MakeUpCost,
+		MakeUpLeft:    left,
+		MakeUpDays:    conf.MakeUpDays,
+	}
 }
 
 // SetUserInfo 设置用户信息
 func (s *userService) SetUserInfo(id primitive.ObjectID, info models.UserInfoSchema) {
+	var nicknameReview, bioReview bool
+	info.Nickname, nicknameReview = GetServiceManger().Filter.CheckContent(info.Nickname)
+	info.Bio, bioReview = GetServiceManger().Filter.CheckContent(info.Bio)
 	utils.Assert(s.model.SetUserInfoByID(id, info) == nil, "invalid_session", 401)
 	utils.Assert(models.GetRedis().Cache.WillUpdate(id, models.KindOfBaseInfo) == nil, "redis_error", iris.StatusInternalServerError)
+	if nicknameReview || bioReview {
+		user, err := s.model.GetUserByID(id)
+		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		GetServiceManger().Filter.AddReview(id, models.ReportTargetUser, id, 0, user.Info.Nickname+"\n"+user.Info.Bio)
+	}
 }
 
 // SetUserType 设置用户类型
-func (s *userService) SetUserType(admin primitive.ObjectID, id primitive.ObjectID, userType models.UserType) {
-	adminInfo, err := models.GetRedis().Cache.GetUserBaseInfo(admin)
+func (s *userService) SetUserType(admin primitive.ObjectID, id primitive.ObjectID, userType models.UserType, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(admin, models.PermissionManageUsers)
+	adminInfo, err := s.cache.GetUserBaseInfo(admin)
 	utils.AssertErr(err, "invalid_session", 401)
-	utils.Assert(adminInfo.Type == models.UserTypeAdmin ||
-		adminInfo.Type == models.UserTypeRoot, "permission_deny", 403)
-	err = s.model.SetUserType(id, userType)
+	userInfo, err := s.cache.GetUserBaseInfo(id)
 	utils.Assert(err == nil, "faked_users", 403)
-	utils.Assert(models.GetRedis().Cache.WillUpdate(id, models.KindOfBaseInfo) == nil, "redis_error", iris.StatusInternalServerError)
+	// 被授予角色的普通用户不能任免管理员
+	if adminInfo.Type == models.UserTypeNormal {
+		utils.Assert(userType != models.UserTypeAdmin && userInfo.Type != models.UserTypeAdmin &&
+			userInfo.Type != models.UserTypeRoot, "permission_deny", 403)
+	}
+	// 封禁与解封需要留下记录并通知用户
+	if userType == models.UserTypeBan {
+		GetServiceManger().Ban.banUser(id, admin, "", 0)
+	} else {
+		// 解封会恢复封禁前的类型，与指定类型不同时再修改
+		if userInfo.Type != models.UserTypeBan || GetServiceManger().Ban.liftBans(id, admin) != userType {
+			err = s.model.SetUserType(id, userType)
+			utils.Assert(err == nil, "faked_users", 403)
+			utils.Assert(models.GetRedis().Cache.WillUpdate(id, models.KindOfBaseInfo) == nil, "redis_error", iris.StatusInternalServerError)
+			utils.Assert(models.GetRedis().Cache.WillUpdate(id, models.KindOfPermission) == nil, "redis_error", iris.StatusInternalServerError)
+		}
+	}
+	// 操作成功后再记录
+	GetServiceManger().Audit.record(meta, admin, models.AuditSetUserType, models.AuditTargetUser, id.Hex(),
+		bson.M{"type": userInfo.Type}, bson.M{"type": userType})
+}
+
+// GetIdentities 获取可认证的身份类型
+func (s *userService) GetIdentities() []utils.IdentityConfig {
+	return utils.GetIdentities()
 }
 
 // CancelCertification 取消认证
-func (s *userService) CancelCertification(id primitive.ObjectID) {
+func (s *userService) CancelCertification(id primitive.ObjectID, identity models.UserIdentity) {
 	user, err := s.model.GetUserByID(id)
 	utils.AssertErr(err, "invalid_session", 401)
-	utils.Assert(user.Certification.Identity != models.IdentityNone, "faked_certification")
-	user.Certification.Status = models.CertificationCancel
-	err = s.model.SetUserCertification(id, user.Certification)
+	certification := findCertification(user, identity)
+	utils.Assert(certification != nil, "faked_certification", 403)
+	certification.Status = models.CertificationCancel
+	err = s.model.SetUserCertification(id, *certification)
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
 }
 
 // UpdateCertification 更新认证[管理员]
-func (s *userService) UpdateCertification(id primitive.ObjectID, operate, data string) {
+func (s *userService) UpdateCertification(adminID, id primitive.ObjectID, identity models.UserIdentity, operate, data string, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(adminID, models.PermissionReviewCertification)
 	user, err := s.model.GetUserByID(id)
 	utils.AssertErr(err, "faked_user", 401)
-	utils.Assert(user.Certification.Identity != models.IdentityNone, "faked_certification")
+	certification := findCertification(user, identity)
+	utils.Assert(certification != nil, "faked_certification", 403)
+	before := bson.M{"identity": identity, "status": certification.Status, "data": certification.Data, "expire_time": certification.ExpireTime}
 	if operate == "true" {
 		if data != "" {
-			user.Certification.Data = data
+			certification.Data = data
 		}
-		user.Certification.Date = time.Now().Unix()
-		user.Certification.Status = models.CertificationTrue
-		err = s.model.SetUserCertification(id, user.Certification)
+		certification.Date = time.Now().Unix()
+		certification.Status = models.CertificationTrue
+		certification.ExpireTime = s.certificationExpireTime(certification.Email)
+		certification.RemindTime = 0
+		certification.Campus = GetServiceManger().Campus.matchCampus(certification.Email, certification.Data)
+		err = s.model.SetUserCertification(id, *certification)
 		utils.AssertErr(err, "", iris.StatusInternalServerError)
+		GetServiceManger().Badge.onEvent(id, BadgeCertified)
 	} else if operate == "false" {
-		user.Certification.Status = models.CertificationFalse
-		user.Certification.Feedback = data
-		err = s.model.SetUserCertification(id, user.Certification)
+		certification.Status = models.CertificationFalse
+		certification.Feedback = data
+		err = s.model.SetUserCertification(id, *certification)
 		utils.AssertErr(err, "", iris.StatusInternalServerError)
 	}
+	GetServiceManger().Audit.record(meta, adminID, models.AuditUpdateCertification, models.AuditTargetUser, id.Hex(),
+		before, bson.M{"identity": identity, "status": certification.Status, "data": certification.Data,
+			"feedback": certification.Feedback, "expire_time": certification.ExpireTime})
 }
 
 // AddEmailCertification 添加认证
 func (s *userService) AddEmailCertification(identity models.UserIdentity, id primitive.ObjectID, data, email string) {
+	checkCertificationMethod(identity, models.CertificationByEmail)
 	user, err := s.model.GetUserByID(id)
 	utils.AssertErr(err, "invalid_session", 401)
-	utils.Assert(user.Certification.Identity == models.IdentityNone ||
-		user.Certification.Status == models.CertificationCancel, "exist_certification", 403)
+	certification := findCertification(user, identity)
+	utils.Assert(certification == nil || certification.Status == models.CertificationCancel ||
+		certification.Status == models.CertificationExpired, "exist_certification", 403)
 	utils.Assert(s.model.CheckCertificationEmail(email), "exist_email", 403)
 	err = s.model.SetUserCertification(id, models.UserCertificationSchema{
 		Identity: identity,
+		Method:   models.CertificationByEmail,
 		Data:     data,
 		Status:   models.CertificationCheckEmail,
 		Date:     time.Now().Unix(),
@@ -372,13 +872,16 @@ func (s *userService) AddEmailCertification(identity models.UserIdentity, id pri
 
 // AddMaterialCertification 添加材料认证
 func (s *userService) AddMaterialCertification(identity models.UserIdentity, id primitive.ObjectID, data string, attachment []primitive.ObjectID) {
+	checkCertificationMethod(identity, models.CertificationByMaterial)
 	user, err := s.model.GetUserByID(id)
 	utils.AssertErr(err, "invalid_session", 401)
-	utils.Assert(user.Certification.Identity == models.IdentityNone ||
-		user.Certification.Status == models.CertificationCancel, "exist_certification", 403)
+	certification := findCertification(user, identity)
+	utils.Assert(certification == nil || certification.Status == models.CertificationCancel ||
+		certification.Status == models.CertificationExpired, "exist_certification", 403)
 	GetServiceManger().File.BindFilesToUser(id, attachment)
 	err = s.model.SetUserCertification(id, models.UserCertificationSchema{
 		Identity: identity,
+		Method:   models.CertificationByMaterial,
 		Data:     data,
 		Status:   models.CertificationWait,
 		Date:     time.Now().Unix(),
@@ -387,15 +890,43 @@ func (s *userService) AddMaterialCertification(identity models.UserIdentity, id
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
 }
 
+// AddInviteCertification 使用邀请码认证，邀请码决定认证身份和认证内容
+func (s *userService) AddInviteCertification(id primitive.ObjectID, code string) {
+	user, err := s.model.GetUserByID(id)
+	utils.AssertErr(err, "invalid_session", 401)
+	invite, err := s.inviteModel.GetInviteByCode(code)
+	utils.AssertErr(err, "faked_invite", 403)
+	checkCertificationMethod(invite.Identity, models.CertificationByInvite)
+	certification := findCertification(user, invite.Identity)
+	utils.Assert(certification == nil || certification.Status != models.CertificationTrue, "exist_certification", 403)
+	// 使用时再次检查有效期和次数
+	_, err = s.inviteModel.UseInvite(code)
+	utils.AssertErr(err, "faked_invite", 403)
+	err = s.model.SetUserCertification(id, models.UserCertificationSchema{
+		Identity:   invite.Identity,
+		Method:     models.CertificationByInvite,
+		Data:       invite.Data,
+		Status:     models.CertificationTrue,
+		Date:       time.Now().Unix(),
+		ExpireTime: s.certificationExpireTime(""),
+		Campus:     GetServiceManger().Campus.matchCampus("", invite.Data),
+	})
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Badge.onEvent(id, BadgeCertified)
+}
+
 // SendCertificationEmail 发送认证邮件
 func (s *userService) SendCertificationEmail(id primitive.ObjectID, email string) {
 	if email == "" {
 		user, err := s.model.GetUserByID(id)
 		utils.AssertErr(err, "invalid_session", 401)
-		utils.Assert(user.Certification.Identity != models.IdentityNone &&
-			user.Certification.Status == models.CertificationCheckEmail, "faked_certification", 403)
-		utils.Assert(user.Certification.Email != "", "faked_email", 403)
-		email = user.Certification.Email
+		for _, certification := range user.Certifications {
+			if certification.Status == models.CertificationCheckEmail {
+				email = certification.Email
+				break
+			}
+		}
+		utils.Assert(email != "", "faked_certification", 403)
 	}
 	exist, _ := models.GetRedis().Cache.CheckCertification(id, "", "", false)
 	utils.Assert(!exist, "limit_email", 403)
@@ -413,49 +944,58 @@ func (s *userService) CheckCertification(id primitive.ObjectID, code string) str
 	if err != nil {
 		return "无效的认证链接"
 	}
-	// 是否存在认证
-	utils.Assert(user.Certification.Identity != models.IdentityNone, "error_code", iris.StatusInternalServerError)
 	// 检查认证邮箱和 code 的正确性
-	exist, right := models.GetRedis().Cache.CheckCertification(id, user.Certification.Email, code, true)
-	if !exist || !right || user.Certification.Status != models.CertificationCheckEmail {
+	var certification *models.UserCertificationSchema
+	for i := range user.Certifications {
+		if user.Certifications[i].Status != models.CertificationCheckEmail {
+			continue
+		}
+		if _, right := models.GetRedis().Cache.CheckCertification(id, user.Certifications[i].Email, code, true); right {
+			certification = &user.Certifications[i]
+			break
+		}
+	}
+	if certification == nil {
 		return "无效的认证链接"
 	}
 	// 是否自动通过认证
-	emailParts := strings.Split(user.Certification.Email, "@")
+	emailParts := strings.Split(certification.Email, "@")
 	if len(emailParts) != 2 {
 		return "无效的认证邮箱"
 	}
-	content := s.system.ExistAutoEmail(emailParts[1])
-	if content == "" {
-		user.Certification.Status = models.CertificationWaitEmail
-		err = s.model.SetUserCertification(id, user.Certification)
+	auto, err := s.system.GetAutoEmailByDomain(emailParts[1])
+	if err != nil || !autoEmailIdentity(auto, certification.Identity) {
+		certification.Status = models.CertificationWaitEmail
+		err = s.model.SetUserCertification(id, *certification)
 		utils.AssertErr(err, "", iris.StatusInternalServerError)
 		return "认证邮箱成功，待审核通过"
 	}
-	user.Certification.Status = models.CertificationTrue
-	user.Certification.Data = content
-	err = s.model.SetUserCertification(id, user.Certification)
+	certification.Status = models.CertificationTrue
+	certification.Data = auto.Value
+	certification.ExpireTime = expireTimeAfter(auto.ValidDays)
+	certification.RemindTime = 0
+	certification.Campus = GetServiceManger().Campus.matchCampus(certification.Email, certification.Data)
+	err = s.model.SetUserCertification(id, *certification)
 	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Badge.onEvent(id, BadgeCertified)
 	return "认证已通过"
 }
 
 // GetCertificationList 获取待审核认证列表
 func (s *userService) GetCertificationList(userID primitive.ObjectID, types []models.CertificationStatus, page, size int64) (users []UserDetail) {
-	admin := s.GetUserBaseInfo(userID)
-	utils.Assert(admin.Type == models.UserTypeAdmin || admin.Type == models.UserTypeRoot, "permission_deny", 403)
+	GetServiceManger().Role.checkPermission(userID, models.PermissionReviewCertification)
 
 	usersData, err := s.model.GetCertification(types, page, size)
 	utils.AssertErr(err, "", 500)
 	for i := range usersData {
-		users = append(users, s.makeUserRes(usersData[i], true))
+		users = append(users, s.makeUserRes(usersData[i], userID, true))
 	}
 	return users
 }
 
 // GetAutoCertification 获取自动认证后缀
 func (s *userService) GetAutoCertification(userID primitive.ObjectID, page, size int64) (keys []models.SystemSchemas) {
-	admin := s.GetUserBaseInfo(userID)
-	utils.Assert(admin.Type == models.UserTypeAdmin || admin.Type == models.UserTypeRoot, "permission_deny", 403)
+	GetServiceManger().Role.checkPermission(userID, models.PermissionReviewCertification)
 	keys, err := s.system.GetAutoEmail(page, size)
 	utils.AssertErr(err, "", 500)
 	for i := range keys {
@@ -465,28 +1005,238 @@ func (s *userService) GetAutoCertification(userID primitive.ObjectID, page, size
 }
 
 // AddAutoCertification 添加自动认证后缀
-func (s *userService) AddAutoCertification(userID primitive.ObjectID, key, data string) {
-	admin := s.GetUserBaseInfo(userID)
-	utils.Assert(admin.Type == models.UserTypeAdmin || admin.Type == models.UserTypeRoot, "permission_deny", 403)
-	err := s.system.AddAutoEmail(key, data)
+func (s *userService) AddAutoCertification(userID primitive.ObjectID, key, data string, identities []models.UserIdentity, validDays int64, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionReviewCertification)
+	for _, identity := range identities {
+		checkCertificationMethod(identity, models.CertificationByEmail)
+	}
+	before, exist := s.system.GetAutoEmailByDomain(key)
+	err := s.system.AddAutoEmail(key, data, identities, validDays)
 	utils.AssertErr(err, "", 500)
+	var beforeState bson.M
+	if exist == nil {
+		beforeState = bson.M{"data": before.Value, "identities": before.Identities, "valid_days": before.ValidDays}
+	}
+	GetServiceManger().Audit.record(meta, userID, models.AuditAddAutoCertification, models.AuditTargetEmail, key,
+		beforeState, bson.M{"data": data, "identities": identities, "valid_days": validDays})
 }
 
 // RemoveAutoCertification 移除自动认证后缀
-func (s *userService) RemoveAutoCertification(userID primitive.ObjectID, key string) {
-	admin := s.GetUserBaseInfo(userID)
-	utils.Assert(admin.Type == models.UserTypeAdmin || admin.Type == models.UserTypeRoot, "permission_deny", 403)
+func (s *userService) RemoveAutoCertification(userID primitive.ObjectID, key string, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionReviewCertification)
+	before := s.system.ExistAutoEmail(key)
 	err := s.system.RemoveAutoEmail(key)
 	utils.AssertErr(err, "faked_email", 403)
+	GetServiceManger().Audit.record(meta, userID, models.AuditRemoveAutoCertification, models.AuditTargetEmail, key,
+		bson.M{"data": before}, nil)
+}
+
+// GetInvites 获取认证邀请码列表
+func (s *userService) GetInvites(userID primitive.ObjectID, page, size int64) (int64, []models.InviteSchema) {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionReviewCertification)
+	invites, count, err := s.inviteModel.GetInvites((page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return count, invites
+}
+
+// AddInvite 发放认证邀请码，返回邀请码
+func (s *userService) AddInvite(userID primitive.ObjectID, identity models.UserIdentity, data string, maxUse, days int64, meta models.RequestMeta) string {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionReviewCertification)
+	checkCertificationMethod(identity, models.CertificationByInvite)
+	invite := models.InviteSchema{
+		Identity: identity,
+		Data:     data,
+		Creator:  userID,
+		MaxUse:   maxUse,
+	}
+	if days > 0 {
+		invite.ExpireTime = time.Now().Add(time.Duration(days) * 24 * time.Hour).Unix()
+	}
+	var id primitive.ObjectID
+	err := models.ErrNotExist
+	// 邀请码重复时重新生成
+	for i := 0; i < inviteCodeRetry && err == models.ErrNotExist; i++ {
+		invite.Code = utils.GetRandomString(12)
+		id, err = s.inviteModel.AddInvite(invite)
+	}
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	GetServiceManger().Audit.record(meta, userID, models.AuditAddInvite, models.AuditTargetInvite, id.Hex(), nil, bson.M{
+		"identity":    identity,
+		"data":        data,
+		"max_use":     maxUse,
+		"expire_time": invite.ExpireTime,
+	})
+	return invite.Code
+}
+
+// RemoveInvite 删除认证邀请码，已通过的认证不受影响
+func (s *userService) RemoveInvite(userID, id primitive.ObjectID, meta models.RequestMeta) {
+	GetServiceManger().Role.checkPermission(userID, models.PermissionReviewCertification)
+	invite, err := s.inviteModel.RemoveInvite(id)
+	utils.AssertErr(err, "faked_invite", 403)
+	GetServiceManger().Audit.record(meta, userID, models.AuditRemoveInvite, models.AuditTargetInvite, id.Hex(), bson.M{
+		"identity":  invite.Identity,
+		"data":      invite.Data,
+		"use_count": invite.UseCount,
+	}, nil)
+}
+
+// remindCertifications 提醒即将过期的认证，有认证邮箱时发送邮件，否则发送系统消息
+func (s *userService) remindCertifications() {
+	conf := utils.GetConf()
+	if conf == nil || conf.Certification.RemindDays <= 0 {
+		return
+	}
+	now := time.Now()
+	deadline := now.Add(time.Duration(conf.Certification.RemindDays) * 24 * time.Hour).Unix()
+	users, err := s.model.GetExpiringCertifications(deadline)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, user := range users {
+		for _, certification := range user.Certifications {
+			if certification.Status != models.CertificationTrue || certification.ExpireTime == 0 ||
+				certification.ExpireTime > deadline || certification.RemindTime != 0 {
+				continue
+			}
+			name := identityName(certification.Identity)
+			if certification.Email != "" {
+				err = libs.GetEmail().SendCertificationRemindEmail(certification.Email, name, certification.ExpireTime)
+			} else {
+				_, err = s.messageModel.AddMessage(user.ID, models.MessageTypeSystem, models.MessageSchema{
+					Title:   "你的「" + name + "」认证即将过期",
+					Content: "认证将于 " + time.Unix(certification.ExpireTime, 0).Format("2006-01-02") + " 过期，请及时重新认证",
+				})
+			}
+			if err != nil {
+				continue
+			}
+			certification.RemindTime = now.Unix()
+			err = s.model.SetUserCertification(user.ID, certification)
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+		}
+	}
+}
+
+// expireCertifications 将已到期的认证降级为过期状态
+func (s *userService) expireCertifications() {
+	now := time.Now().Unix()
+	users, err := s.model.GetExpiredCertifications(now)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	for _, user := range users {
+		for _, certification := range user.Certifications {
+			if certification.Status != models.CertificationTrue || certification.ExpireTime == 0 || certification.ExpireTime > now {
+				continue
+			}
+			certification.Status = models.CertificationExpired
+			err = s.model.SetUserCertification(user.ID, certification)
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+			_, err = s.messageModel.AddMessage(user.ID, models.MessageTypeSystem, models.MessageSchema{
+				Title:   "你的「" + identityName(certification.Identity) + "」认证已过期",
+				Content: "部分仅限认证用户参与的任务将无法领取，请重新提交认证",
+			})
+			utils.AssertErr(err, "", iris.StatusInternalServerError)
+		}
+	}
+}
+
+// certificationExpireTime 计算认证通过后的过期时间，邮箱后缀设置了有效天数时优先使用
+func (s *userService) certificationExpireTime(email string) int64 {
+	if emailParts := strings.Split(email, "@"); len(emailParts) == 2 {
+		if auto, err := s.system.GetAutoEmailByDomain(emailParts[1]); err == nil && auto.ValidDays > 0 {
+			return expireTimeAfter(auto.ValidDays)
+		}
+	}
+	return expireTimeAfter(0)
+}
+
+// expireTimeAfter 获取 days 天后的过期时间，days 为 0 时使用默认有效天数，均为 0 时永不过期
+func expireTimeAfter(days int64) int64 {
+	if days <= 0 {
+		if conf := utils.GetConf(); conf != nil {
+			days = conf.Certification.ValidDays
+		}
+	}
+	if days <= 0 {
+		return 0
+	}
+	return time.Now().Add(time.Duration(days) * 24 * time.Hour).Unix()
+}
+
+// identityName 获取认证身份名称
+func identityName(identity models.UserIdentity) string {
+	if conf, ok := utils.GetIdentityConf(string(identity)); ok {
+		return conf.Name
+	}
+	return string(identity)
 }
 
-// GetUserCollections 获取用户收藏
-func (s *userService) GetUserCollections(id primitive.ObjectID, page, size int64, sortRule string, taskType string,
+// findCertification 获取用户指定身份的认证，不存在时返回 nil
+func findCertification(user models.UserSchema, identity models.UserIdentity) *models.UserCertificationSchema {
+	for i := range user.Certifications {
+		if user.Certifications[i].Identity == identity {
+			return &user.Certifications[i]
+		}
+	}
+	return nil
+}
+
+// hasCertification 用户是否持有符合条件的有效认证，条件为空时不限制
+func hasCertification(user models.UserSchema, identities []models.UserIdentity, schools []string) bool {
+	for _, certification := range user.Certifications {
+		if certification.Status != models.CertificationTrue {
+			continue
+		}
+		matchIdentity := len(identities) == 0
+		for _, identity := range identities {
+			if certification.Identity == identity {
+				matchIdentity = true
+				break
+			}
+		}
+		matchSchool := len(schools) == 0
+		for _, school := range schools {
+			if certification.Data == school {
+				matchSchool = true
+				break
+			}
+		}
+		if matchIdentity && matchSchool {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCertificationMethod 检查身份是否支持该认证方式
+func checkCertificationMethod(identity models.UserIdentity, method models.CertificationMethod) {
+	conf, ok := utils.GetIdentityConf(string(identity))
+	utils.Assert(ok, "invalid_identity", 400)
+	for _, m := range conf.Methods {
+		if models.CertificationMethod(m) == method {
+			return
+		}
+	}
+	utils.Assert(false, "invalid_method", 403)
+}
+
+// autoEmailIdentity 自动认证后缀是否适用该身份，未指定身份的后缀仅适用学生
+func autoEmailIdentity(auto models.SystemSchemas, identity models.UserIdentity) bool {
+	if len(auto.Identities) == 0 {
+		return identity == models.IdentityStudent
+	}
+	for _, i := range auto.Identities {
+		if i == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserCollections 按收藏时间倒序获取用户收藏，folder 为 all 时获取全部收藏，default 时获取默认收藏夹
+func (s *userService) GetUserCollections(id primitive.ObjectID, page, size int64, folder string, taskType string,
 	status string, reward string) (taskCount int64, taskCards []TaskDetail) {
 	var taskTypes []models.TaskType
 	var statuses []models.TaskStatus
 	var rewards []models.RewardType
-	var keywords []string
 	split := strings.Split(taskType, ",")
 	for _, str := range split {
 		if str == "all" {
@@ -512,20 +1262,53 @@ func (s *userService) GetUserCollections(id primitive.ObjectID, page, size int64
 		rewards = append(rewards, models.RewardType(str))
 	}
 
-	if sortRule == "new" {
-		sortRule = "publish_date"
+	var folderFilter *models.FolderFilter
+	if folder == "default" {
+		folderFilter = &models.FolderFilter{Default: true}
+	} else if folder != "all" {
+		folderID, err := primitive.ObjectIDFromHex(folder)
+		utils.AssertErr(err, "invalid_folder", 400)
+		folderFilter = &models.FolderFilter{Folder: folderID}
 	}
 
-	collectionTasks := s.setModel.GetSets(id, models.SetOfCollectTask)
-	if len(collectionTasks.CollectTaskID) > 0 {
-		tasks, taskCount, err := s.taskModel.GetTasks(sortRule, collectionTasks.CollectTaskID, taskTypes, statuses, rewards, keywords, "", (page-1)*size, size)
-		utils.AssertErr(err, "", iris.StatusInternalServerError)
-		for _, t := range tasks {
-			taskCards = append(taskCards, GetServiceManger().Task.makeTaskDetail(t, id.Hex(), true))
-		}
-		return taskCount, taskCards
+	tasks, taskCount, err := s.reactionModel.GetCollectedTasks(id, folderFilter, taskTypes, statuses, rewards, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	taskCards = []TaskDetail{}
+	for _, t := range tasks {
+		taskCards = append(taskCards, GetServiceManger().Task.makeTaskDetail(t, id.Hex(), true))
 	}
-	return 0, []TaskDetail{}
+	return taskCount, taskCards
+}
+
+// GetCollectFolders 获取用户的收藏夹
+func (s *userService) GetCollectFolders(id primitive.ObjectID) []models.FolderSchema {
+	folders, err := s.folderModel.GetFolders(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return folders
+}
+
+// AddCollectFolder 添加收藏夹
+func (s *userService) AddCollectFolder(id primitive.ObjectID, name string) primitive.ObjectID {
+	count, err := s.folderModel.CountFolders(id)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	utils.Assert(count < maxCollectFolder, "max_folder", 403)
+	folderID, err := s.folderModel.AddFolder(id, name)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	return folderID
+}
+
+// SetCollectFolder 修改收藏夹名称
+func (s *userService) SetCollectFolder(id, folderID primitive.ObjectID, name string) {
+	err := s.folderModel.SetFolderName(id, folderID, name)
+	utils.AssertErr(err, "faked_folder", 403)
+}
+
+// RemoveCollectFolder 删除收藏夹，其中的收藏移回默认收藏夹
+func (s *userService) RemoveCollectFolder(id, folderID primitive.ObjectID) {
+	err := s.folderModel.RemoveFolder(id, folderID)
+	utils.AssertErr(err, "faked_folder", 403)
+	err = s.reactionModel.ClearFolder(id, folderID)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
 }
 
 // GetSearchHistory 获取用户搜索历史
@@ -541,61 +1324,79 @@ func (s *userService) ClearSearchHistory(id primitive.ObjectID) {
 	utils.AssertErr(err, "", 500)
 }
 
+// FollowUser 关注/粉丝列表中的用户
+type FollowUser struct {
+	models.UserBaseInfo
+	Time int64 // 关注时间
+}
+
+// FollowSuggestion 推荐关注的用户
+type FollowSuggestion struct {
+	models.UserBaseInfo
+	Mutual int64 // 共同关注人数
+}
+
 // GetFollowing 获取用户关注列表
-func (s *userService) GetFollowing(id primitive.ObjectID, page, size int64) ([]models.UserBaseInfo, int64) {
-	set := s.setModel.GetSets(id, models.SetOfFollowingUser)
-	followingIDs := set.FollowingUserID
-	lenOfIDs := int64(len(followingIDs))
-	beginIndex := (page - 1) * size
-	endIndex := page * size
-	if lenOfIDs < beginIndex {
-		return []models.UserBaseInfo{}, lenOfIDs
-	}
-	if lenOfIDs > endIndex {
-		followingIDs = followingIDs[beginIndex:endIndex]
-	} else {
-		followingIDs = followingIDs[beginIndex:]
-	}
-	var res []models.UserBaseInfo
-	for _, id := range followingIDs {
-		user, _ := s.cache.GetUserBaseInfo(id)
-		res = append(res, user)
+func (s *userService) GetFollowing(id primitive.ObjectID, page, size int64) ([]FollowUser, int64) {
+	follows, count, err := s.followModel.GetFollowing(id, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := []FollowUser{}
+	for _, follow := range follows {
+		user, _ := s.cache.GetUserBaseInfo(follow.Target)
+		res = append(res, FollowUser{UserBaseInfo: user, Time: follow.Time})
 	}
-	return res, lenOfIDs
+	return res, count
 }
 
 // GetFollower 获取用户粉丝列表
-func (s *userService) GetFollower(id primitive.ObjectID, page, size int64) ([]models.UserBaseInfo, int64) {
-	set := s.setModel.GetSets(id, models.SetOfFollowerUser)
-	followerIDs := set.FollowerUserID
-	lenOfIDs := int64(len(followerIDs))
-	beginIndex := (page - 1) * size
-	endIndex := page * size
-	if lenOfIDs < beginIndex {
-		return []models.UserBaseInfo{}, lenOfIDs
-	}
-	if lenOfIDs > endIndex {
-		followerIDs = followerIDs[beginIndex:endIndex]
-	} else {
-		followerIDs = followerIDs[beginIndex:]
+func (s *userService) GetFollower(id primitive.ObjectID, page, size int64) ([]FollowUser, int64) {
+	follows, count, err := s.followModel.GetFollowers(id, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := []FollowUser{}
+	for _, follow := range follows {
+		user, _ := s.cache.GetUserBaseInfo(follow.User)
+		res = append(res, FollowUser{UserBaseInfo: user, Time: follow.Time})
 	}
-	var res []models.UserBaseInfo
-	for _, id := range followerIDs {
-		user, _ := s.cache.GetUserBaseInfo(id)
-		res = append(res, user)
+	return res, count
+}
+
+// GetMutualFollow 获取与用户互相关注的用户列表
+func (s *userService) GetMutualFollow(id primitive.ObjectID, page, size int64) ([]FollowUser, int64) {
+	follows, count, err := s.followModel.GetMutual(id, (page-1)*size, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := []FollowUser{}
+	for _, follow := range follows {
+		user, _ := s.cache.GetUserBaseInfo(follow.Target)
+		res = append(res, FollowUser{UserBaseInfo: user, Time: follow.Time})
 	}
-	return res, lenOfIDs
+	return res, count
+}
+
+// GetFollowSuggestions 获取推荐关注的用户（关注的人也关注的用户），排除屏蔽关系
+func (s *userService) GetFollowSuggestions(id primitive.ObjectID, size int64) []FollowSuggestion {
+	blocked := s.setModel.GetSets(id, models.SetOfBlockUser).BlockUserID
+	suggestions, err := s.followModel.GetSuggestions(id, blocked, size)
+	utils.AssertErr(err, "", iris.StatusInternalServerError)
+	res := []FollowSuggestion{}
+	for _, suggestion := range suggestions {
+		if s.cache.IsBlockUser(suggestion.User, id) {
+			continue
+		}
+		user, _ := s.cache.GetUserBaseInfo(suggestion.User)
+		res = append(res, FollowSuggestion{UserBaseInfo: user, Mutual: suggestion.Mutual})
+	}
+	return res
 }
 
 // FollowUser 关注用户
 func (s *userService) FollowUser(userID, followID primitive.ObjectID) {
+	utils.Assert(userID != followID, "invalid_id", 400)
 	_, err := s.model.GetUserByID(followID)
 	utils.AssertErr(err, "faked_user", 403)
+	utils.Assert(!s.cache.IsBlockUser(followID, userID) && !s.cache.IsBlockUser(userID, followID), "blocked", 403)
 
-	err = s.setModel.AddToSet(userID, followID, models.SetOfFollowingUser)
-	utils.AssertErr(err, "exist_relation", 403)
-
-	err = s.setModel.AddToSet(followID, userID, models.SetOfFollowerUser)
+	err = s.followModel.AddFollow(userID, followID)
+	utils.Assert(err != models.ErrExist, "exist_follow", 403)
 	utils.AssertErr(err, "", 500)
 
 	err = s.model.UpdateUserDataCount(followID, models.UserDataCount{
@@ -606,12 +1407,6 @@ func (s *userService) FollowUser(userID, followID primitive.ObjectID) {
 		FollowingCount: 1,
 	})
 	utils.AssertErr(err, "", 500)
-
-	err = s.cache.WillUpdate(userID, models.KindOfFollowing)
-	utils.AssertErr(err, "", 500)
-
-	err = s.cache.WillUpdate(followID, models.KindOfFollower)
-	utils.AssertErr(err, "", 500)
 }
 
 // GetUserParticipate 获取用户参与的用户
@@ -647,12 +1442,9 @@ func (s *userService) GetUserParticipate(id primitive.ObjectID, page, size int64
 
 // UnFollowUser 取消关注用户
 func (s *userService) UnFollowUser(userID, followID primitive.ObjectID) {
-	err := s.setModel.RemoveFromSet(userID, followID, models.SetOfFollowingUser)
+	err := s.followModel.RemoveFollow(userID, followID)
 	utils.AssertErr(err, "faked_relation", 403)
 
-	err = s.setModel.RemoveFromSet(followID, userID, models.SetOfFollowerUser)
-	utils.AssertErr(err, "", 500)
-
 	err = s.model.UpdateUserDataCount(followID, models.UserDataCount{
 		FollowerCount: -1,
 	})
@@ -661,26 +1453,64 @@ func (s *userService) UnFollowUser(userID, followID primitive.ObjectID) {
 		FollowingCount: -1,
 	})
 	utils.AssertErr(err, "", 500)
-
-	err = s.cache.WillUpdate(userID, models.KindOfFollowing)
-	utils.AssertErr(err, "", 500)
-
-	err = s.cache.WillUpdate(followID, models.KindOfFollower)
-	utils.AssertErr(err, "", 500)
 }
 
 // IsFollower 是否是粉丝
 func (s *userService) IsFollower(userID, followID primitive.ObjectID) bool {
-	return s.cache.IsFollowerUser(userID, followID)
+	following, err := s.followModel.IsFollowing(followID, userID)
+	return err == nil && following
 }
 
 // IsFollowing 是否已关注
 func (s *userService) IsFollowing(userID, followID primitive.ObjectID) bool {
-	return s.cache.IsFollowingUser(userID, followID)
+	following, err := s.followModel.IsFollowing(userID, followID)
+	return err == nil && following
+}
+
+// GetBlockList 获取用户屏蔽列表
+func (s *userService) GetBlockList(id primitive.ObjectID, page, size int64) ([]models.UserBaseInfo, int64) {
+	blockIDs, count, err := s.setModel.GetSetPage(id, models.SetOfBlockUser, page, size)
+	utils.AssertErr(err, "", 500)
+	res := []models.UserBaseInfo{}
+	for _, id := range blockIDs {
+		user, _ := s.cache.GetUserBaseInfo(id)
+		res = append(res, user)
+	}
+	return res, count
+}
+
+// BlockUser 屏蔽用户，同时解除双方的关注关系
+func (s *userService) BlockUser(userID, blockID primitive.ObjectID) {
+	utils.Assert(userID != blockID, "invalid_id", 400)
+	_, err := s.model.GetUserByID(blockID)
+	utils.AssertErr(err, "faked_user", 403)
+
+	err = s.setModel.AddToSet(userID, blockID, models.SetOfBlockUser)
+	utils.AssertErr(err, "exist_relation", 403)
+	err = s.cache.WillUpdate(userID, models.KindOfBlock)
+	utils.AssertErr(err, "", 500)
+
+	if s.IsFollowing(userID, blockID) {
+		s.UnFollowUser(userID, blockID)
+	}
+	if s.IsFollowing(blockID, userID) {
+		s.UnFollowUser(blockID, userID)
+	}
+}
+
+// UnBlockUser 取消屏蔽用户
+func (s *userService) UnBlockUser(userID, blockID primitive.ObjectID) {
+	err := s.setModel.RemoveFromSet(userID, blockID, models.SetOfBlockUser)
+	utils.AssertErr(err, "faked_relation", 403)
+	err = s.cache.WillUpdate(userID, models.KindOfBlock)
+	utils.AssertErr(err, "", 500)
 }
 
-// makeUserRes 整合用户数据
-func (s *userService) makeUserRes(user models.UserSchema, all bool) UserDetail {
+// makeUserRes 整合用户数据，all 为 false 时按隐私设置隐藏浏览者不可见的信息
+func (s *userService) makeUserRes(user models.UserSchema, viewerID primitive.ObjectID, all bool) UserDetail {
+	if !all {
+		user.Info = s.visibleInfo(user, viewerID)
+	}
 	res := UserDetail{
 		UserID:       user.ID,
 		ID:           user.ID.Hex(),
@@ -691,31 +1521,140 @@ func (s *userService) makeUserRes(user models.UserSchema, all bool) UserDetail {
 		Data: &UserDataRes{
 			UserDataSchema: &user.Data,
 		},
-		Certification: &UserCertification{
-			Type:   user.Certification.Identity,
-			Status: user.Certification.Status,
-			Email:  user.Certification.Email,
-			Data:   user.Certification.Data,
-			Date:   user.Certification.Date,
-		},
+		Certifications: []UserCertification{},
 	}
+	res.Reputation = &UserReputation{
+		Player:    s.makeRatingDetail(&user.Reputation.Player),
+		Publisher: s.makeRatingDetail(&user.Reputation.Publisher),
+	}
+	res.Badges = GetServiceManger().Badge.getUserBadges(user.Badges)
 	nowTime := time.Now()
 	attendanceTime := time.Unix(user.Data.AttendanceDate, 0)
 	res.Data.Attendance = attendanceTime.Year() == nowTime.Year() && attendanceTime.YearDay() == nowTime.YearDay()
-	if !all {
-		res.Certification.Email = ""
-		if res.Certification.Status != models.CertificationTrue {
-			res.Certification = &UserCertification{
-				Type: models.IdentityNone,
+	user.Data.AttendanceStreak = s.currentStreak(user.Data)
+	for _, certification := range user.Certifications {
+		if !all && certification.Status != models.CertificationTrue {
+			continue
+		}
+		item := UserCertification{
+			Type:   certification.Identity,
+			Method: certification.Method,
+			Status: certification.Status,
+			Data:   certification.Data,
+			Date:   certification.Date,
+
+			ExpireTime: certification.ExpireTime,
+		}
+		if all {
+			item.Email = certification.Email
+			item.Feedback = certification.Feedback
+			item.Material = []models.FileSchema{}
+			for _, id := range certification.Material {
+				file, err := s.fileModel.GetFile(id)
+				utils.AssertErr(err, "", 500)
+				item.Material = append(item.Material, file)
 			}
 		}
-	} else {
-		res.Certification.Material = []models.FileSchema{}
-		for _, id := range user.Certification.Material {
-			file, err := s.fileModel.GetFile(id)
-			utils.AssertErr(err, "", 500)
-			res.Certification.Material = append(res.Certification.Material, file)
+		res.Certifications = append(res.Certifications, item)
+	}
+	return res
+}
+
+// visibleInfo 按隐私设置过滤浏览者不可见的个人信息
+func (s *userService) visibleInfo(user models.UserSchema, viewerID primitive.ObjectID) models.UserInfoSchema {
+	info := user.Info
+	if viewerID == user.ID {
+		return info
+	}
+	privacy := fillPrivacy(user.Privacy)
+	// 关系只在需要时查询，且只查询一次
+	relations := map[string]bool{}
+	relation := func(kind string, check func() bool) bool {
+		if res, ok := relations[kind]; ok {
+			return res
+		}
+		relations[kind] = !viewerID.IsZero() && check()
+		return relations[kind]
+	}
+	visible := func(level models.PrivacyLevel, contact bool) bool {
+		switch level {
+		case models.PrivacyPublic:
+			return true
+		case models.PrivacyFollowers:
+			return relation("follower", func() bool {
+				return s.IsFollowing(viewerID, user.ID)
+			})
+		case models.PrivacyPartners:
+			// 联系方式仅在任务进行中对任务伙伴可见
+			if contact {
+				return relation("running", func() bool {
+					partner, err := s.taskStatusModel.IsPartner(user.ID, viewerID, []models.PlayerStatus{models.PlayerRunning})
+					return err == nil && partner
+				})
+			}
+			return relation("partner", func() bool {
+				partner, err := s.taskStatusModel.IsPartner(user.ID, viewerID, []models.PlayerStatus{
+					models.PlayerRunning, models.PlayerFinish, models.PlayerFailure})
+				return err == nil && partner
+			})
 		}
+		return false
+	}
+	if !visible(privacy.Email, true) {
+		info.Email = ""
+	}
+	if !visible(privacy.Phone, true) {
+		info.Phone = ""
+	}
+	if !visible(privacy.School, false) {
+		info.School = ""
+	}
+	if !visible(privacy.Location, false) {
+		info.Location = ""
+	}
+	if !visible(privacy.Birthday, false) {
+		info.Birthday = 0
+	}
+	return info
+}
+
+// fillPrivacy 补全隐私设置默认值，联系方式默认仅任务伙伴可见
+func fillPrivacy(privacy models.UserPrivacySchema) models.UserPrivacySchema {
+	if privacy.Email == "" {
+		privacy.Email = models.PrivacyPartners
+	}
+	if privacy.Phone == "" {
+		privacy.Phone = models.PrivacyPartners
+	}
+	if privacy.School == "" {
+		privacy.School = models.PrivacyPublic
+	}
+	if privacy.Location == "" {
+		privacy.Location = models.PrivacyPublic
+	}
+	if privacy.Birthday == "" {
+		privacy.Birthday = models.PrivacyPublic
+	}
+	return privacy
+}
+
+// makeRatingDetail 组合评价统计数据
+func (s *userService) makeRatingDetail(rating *models.RatingSchema) RatingDetail {
+	if rating.Levels == nil {
+		rating.Levels = map[string]int64{}
+	}
+	res := RatingDetail{
+		RatingSchema: rating,
+		Reviews:      []ReviewDetail{},
+	}
+	if rating.Count > 0 {
+		rating.Average = float64(rating.Total) / float64(rating.Count)
+	}
+	for i := range rating.Recent {
+		res.Reviews = append(res.Reviews, ReviewDetail{
+			ReviewSchema: &rating.Recent[i],
+			User:         s.GetUserBaseInfo(rating.Recent[i].User),
+		})
 	}
 	return res
 }
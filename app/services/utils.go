@@ -49,8 +49,7 @@ func (s *utilsService) GetLogs(page, size int64, logsType string, userID, postUs
 
 	var logTypes []models.LogType
 
-	user, err := s.userModel.GetUserByID(postUserID)
-	isAdmin := user.Data.Type == models.UserTypeAdmin || user.Data.Type == models.UserTypeRoot
+	isAdmin := GetServiceManger().Role.HasPermission(postUserID, models.PermissionViewAllLogs)
 
 	split := strings.Split(logsType, ",")
 	for _, str := range split {
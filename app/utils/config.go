@@ -9,14 +9,27 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Dev    bool         `yaml:"dev"`    // 开发模式
-	HTTP   HTTPConfig   `yaml:"http"`   // HTTP 配置
-	Db     DBConfig     `yaml:"db"`     // 数据库配置
-	Redis  RedisConfig  `yaml:"redis"`  // Redis 配置
-	Violet VioletConfig `yaml:"violet"` // Violet 配置
-	Wechat WechatConfig `yaml:"wechat"` // 微信小程序 配置
-	COS    COSConfig    `yaml:"cos"`
-	Email  EmailConfig  `yaml:"email"`
+	Dev    bool          `yaml:"dev"`    // 开发模式
+	HTTP   HTTPConfig    `yaml:"http"`   // HTTP 配置
+	Db     DBConfig      `yaml:"db"`     // 数据库配置
+	Redis  RedisConfig   `yaml:"redis"`  // Redis 配置
+	Violet VioletConfig  `yaml:"violet"` // Violet 配置
+	Wechat WechatConfig  `yaml:"wechat"` // 微信小程序 配置
+	COS    COSConfig     `yaml:"cos"`
+	Email  EmailConfig   `yaml:"email"`
+	Credit CreditConfig  `yaml:"credit"` // 信用规则配置
+	Levels []LevelConfig `yaml:"levels"` // 用户等级配置，按所需积分从低到高排列
+	Badges []BadgeConfig `yaml:"badges"` // 徽章配置
+
+	Identities    []IdentityConfig    `yaml:"identities"`    // 可认证的身份类型
+	Certification CertificationConfig `yaml:"certification"` // 认证有效期配置
+
+	Attendance AttendanceConfig `yaml:"attendance"` // 签到配置
+
+	Token TokenConfig `yaml:"token"` // 令牌配置
+
+	DeleteGraceDays int64 `yaml:"delete_grace_days"` // 注销账号后私有文件的保留天数
+	ReportBanDays   int64 `yaml:"report_ban_days"`   // 举报处理封禁用户的天数，0 为永久
 }
 
 // HTTPConfig 服务器配置
@@ -80,6 +93,76 @@ type EmailConfig struct {
 	From     string `yaml:"from"`
 }
 
+// CreditConfig 信用规则配置
+// 变动值为 0 表示该事件不影响信用，门槛为 0 表示不限制
+type CreditConfig struct {
+	Max             int64 `yaml:"max"`               // 信用上限，0 为不限制
+	TaskFinish      int64 `yaml:"task_finish"`       // 完成任务
+	TaskFailure     int64 `yaml:"task_failure"`      // 任务失败
+	TaskGiveUp      int64 `yaml:"task_give_up"`      // 放弃进行中的任务
+	CloseWithPlayer int64 `yaml:"close_with_player"` // 发布者关闭任务时仍有进行中的参与者(按人数计)
+	ReportUpheld    int64 `yaml:"report_upheld"`     // 被举报且举报成立
+	MinAddPlayer    int64 `yaml:"min_add_player"`    // 参与任务所需最低信用
+	HighReward      int64 `yaml:"high_reward"`       // 高酬劳任务的总酬劳(酬劳 x 人数)阈值
+	MinHighReward   int64 `yaml:"min_high_reward"`   // 发布高酬劳任务所需最低信用
+}
+
+// LevelConfig 用户等级配置
+// 特权数值为 0 表示不限制(置顶天数为 0 表示不能置顶)
+type LevelConfig struct {
+	Name        string `yaml:"name"`         // 等级名称
+	Experience  int64  `yaml:"experience"`   // 所需累计获得积分
+	MaxPlayer   int64  `yaml:"max_player"`   // 单个任务参与人数上限
+	RunningTask int64  `yaml:"running_task"` // 同时进行中的发布任务数上限
+	TopDays     int64  `yaml:"top_days"`     // 任务最长置顶天数
+}
+
+// BadgeConfig 徽章配置
+type BadgeConfig struct {
+	ID          string `yaml:"id"`          // 徽章 ID，发放后不可修改
+	Name        string `yaml:"name"`        // 名称
+	Description string `yaml:"description"` // 描述
+	Icon        string `yaml:"icon"`        // 图标链接
+	Event       string `yaml:"event"`       // 触发事件 task_finish/five_star/attendance/certified/top_publisher
+	Threshold   int64  `yaml:"threshold"`   // 达成数量
+	Identity    string `yaml:"identity"`    // 认证身份(仅 certified 事件)，为空则不限
+}
+
+// IdentityConfig 认证身份配置
+type IdentityConfig struct {
+	ID      string   `yaml:"id"`      // 身份 ID，发放后不可修改
+	Name    string   `yaml:"name"`    // 名称
+	Methods []string `yaml:"methods"` // 允许的认证方式 email/material/invite
+}
+
+// CertificationConfig 认证有效期配置
+type CertificationConfig struct {
+	ValidDays  int64 `yaml:"valid_days"`  // 认证默认有效天数(自动认证后缀可单独设置)，0 为永不过期
+	RemindDays int64 `yaml:"remind_days"` // 过期前多少天发送提醒，0 为不提醒
+}
+
+// TokenConfig 令牌配置
+type TokenConfig struct {
+	Secret         string `yaml:"secret"`          // 签名密钥，为空时每次启动随机生成
+	AccessExpires  int64  `yaml:"access_expires"`  // 访问令牌有效分钟数
+	RefreshExpires int64  `yaml:"refresh_expires"` // 刷新令牌有效天数
+	MaxAPITokens   int64  `yaml:"max_api_tokens"`  // 每个用户最多可创建的个人 API 令牌数量
+}
+
+// AttendanceConfig 签到配置
+type AttendanceConfig struct {
+	Rewards     []AttendanceReward `yaml:"rewards"`       // 连续签到奖励，按天数从低到高排列
+	MakeUpCost  int64              `yaml:"make_up_cost"`  // 补签花费的金币
+	MakeUpLimit int64              `yaml:"make_up_limit"` // 每月补签次数上限，0 为不允许补签
+	MakeUpDays  int64              `yaml:"make_up_days"`  // 最多补签多少天前的日期，0 为不限制
+}
+
+// AttendanceReward 连续签到奖励
+type AttendanceReward struct {
+	Streak int64 `yaml:"streak"` // 连续签到天数
+	Value  int64 `yaml:"value"`  // 奖励积分
+}
+
 var config *Config
 
 // LoadConf 从文件读取配置信息
@@ -103,3 +186,21 @@ func (c *Config) LoadConf(path string) *Config {
 func GetConf() *Config {
 	return config
 }
+
+// GetIdentities 获取可认证的身份类型，未配置时仅支持学生认证
+func GetIdentities() []IdentityConfig {
+	if config != nil && len(config.Identities) > 0 {
+		return config.Identities
+	}
+	return []IdentityConfig{{ID: "student", Name: "学生", Methods: []string{"email", "material"}}}
+}
+
+// GetIdentityConf 获取指定身份的认证配置
+func GetIdentityConf(identity string) (IdentityConfig, bool) {
+	for _, conf := range GetIdentities() {
+		if conf.ID == identity {
+			return conf, true
+		}
+	}
+	return IdentityConfig{}, false
+}
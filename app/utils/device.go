@@ -0,0 +1,57 @@
+package utils
+
+import "strings"
+
+// deviceRule User-Agent 关键字与名称
+type deviceRule struct {
+	key  string
+	name string
+}
+
+// 按顺序匹配，靠前的规则优先
+var (
+	browserRules = []deviceRule{
+		{"miniprogram", "微信小程序"},
+		{"micromessenger", "微信"},
+		{"qq/", "QQ"},
+		{"edg", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox", "Firefox"},
+		{"chrome", "Chrome"},
+		{"safari", "Safari"},
+		{"curl", "curl"},
+		{"python", "Python"},
+	}
+	systemRules = []deviceRule{
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"android", "Android"},
+		{"windows", "Windows"},
+		{"mac os", "macOS"},
+		{"linux", "Linux"},
+	}
+)
+
+// DeviceName 根据 User-Agent 获取设备描述，例如 "Chrome (Windows)"
+func DeviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	match := func(rules []deviceRule) string {
+		for _, rule := range rules {
+			if strings.Contains(ua, rule.key) {
+				return rule.name
+			}
+		}
+		return ""
+	}
+	browser, system := match(browserRules), match(systemRules)
+	switch {
+	case browser != "" && system != "":
+		return browser + " (" + system + ")"
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "未知设备"
+	}
+}
@@ -0,0 +1,100 @@
+package utils
+
+import "unicode"
+
+// Matcher Aho-Corasick 多模式字符串匹配器
+// 匹配时忽略大小写，位置以 rune 为单位
+type Matcher struct {
+	nodes   []matcherNode
+	lengths []int // 模式串长度
+}
+
+type matcherNode struct {
+	next   map[rune]int // 子节点
+	fail   int          // 失配指针
+	output []int        // 以该节点结尾的模式串序号
+}
+
+// MatchResult 匹配结果
+type MatchResult struct {
+	Index int // 模式串序号
+	Start int // 起始位置(包含)
+	End   int // 结束位置(不包含)
+}
+
+// NewMatcher 根据模式串列表构建匹配器
+func NewMatcher(words []string) *Matcher {
+	m := &Matcher{
+		nodes:   []matcherNode{{next: map[rune]int{}}},
+		lengths: make([]int, len(words)),
+	}
+	// 构建字典树
+	for i, word := range words {
+		cur := 0
+		for _, r := range word {
+			r = unicode.ToLower(r)
+			child, ok := m.nodes[cur].next[r]
+			if !ok {
+				m.nodes = append(m.nodes, matcherNode{next: map[rune]int{}})
+				child = len(m.nodes) - 1
+				m.nodes[cur].next[r] = child
+			}
+			cur = child
+			m.lengths[i]++
+		}
+		if cur != 0 {
+			m.nodes[cur].output = append(m.nodes[cur].output, i)
+		}
+	}
+	// 广度优先构建失配指针
+	var queue []int
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			fail := m.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].next[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			m.nodes[child].output = append(m.nodes[child].output, m.nodes[m.nodes[child].fail].output...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// Match 查找文本中出现的所有模式串
+func (m *Matcher) Match(text string) (res []MatchResult) {
+	cur := 0
+	pos := 0
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		for cur != 0 {
+			if _, ok := m.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if next, ok := m.nodes[cur].next[r]; ok {
+			cur = next
+		}
+		pos++
+		for _, index := range m.nodes[cur].output {
+			res = append(res, MatchResult{
+				Index: index,
+				Start: pos - m.lengths[index],
+				End:   pos,
+			})
+		}
+	}
+	return
+}
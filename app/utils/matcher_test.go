@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	m := NewMatcher([]string{"he", "she", "his", "hers"})
+	got := m.Match("uSHErs")
+	want := []MatchResult{
+		{Index: 1, Start: 1, End: 4},
+		{Index: 0, Start: 2, End: 4},
+		{Index: 3, Start: 2, End: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overlapping match = %v, want %v", got, want)
+	}
+}
+
+func TestMatcherUnicode(t *testing.T) {
+	m := NewMatcher([]string{"敏感", "敏感词", "词汇", "Ünï"})
+	got := m.Match("这是敏感词汇，üNÏcode")
+	want := []MatchResult{
+		{Index: 0, Start: 2, End: 4},
+		{Index: 1, Start: 2, End: 5},
+		{Index: 2, Start: 4, End: 6},
+		{Index: 3, Start: 7, End: 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unicode match = %v, want %v", got, want)
+	}
+}
+
+func TestMatcherEmpty(t *testing.T) {
+	if res := NewMatcher(nil).Match("任意文本"); len(res) != 0 {
+		t.Error("match with empty dictionary", res)
+	}
+	if res := NewMatcher([]string{""}).Match("abc"); len(res) != 0 {
+		t.Error("match empty pattern", res)
+	}
+	if res := NewMatcher([]string{"abc"}).Match(""); len(res) != 0 {
+		t.Error("match empty text", res)
+	}
+}
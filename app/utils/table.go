@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TableWriter 表格流式写入
+// 逐行写入，不在内存中保存整个表格
+type TableWriter interface {
+	WriteRow(row []string) error
+	Close() error
+}
+
+// sanitizeCell 防止公式注入，以 = + - @ 或制表符、回车开头的内容前加单引号，表格软件会将其作为文本
+func sanitizeCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// csvWriter CSV 表格
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+// NewCSVWriter 创建 CSV 表格，写入 UTF-8 BOM 以便 Excel 正确识别中文
+func NewCSVWriter(w io.Writer) (TableWriter, error) {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: csv.NewWriter(w)}, nil
+}
+
+// WriteRow 写入一行
+func (w *csvWriter) WriteRow(row []string) error {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = sanitizeCell(cell)
+	}
+	return w.writer.Write(cells)
+}
+
+// Close 结束写入
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// xlsxWriter XLSX 表格(单个工作表，单元格均为文本)
+type xlsxWriter struct {
+	zip    *zip.Writer
+	sheet  *bufio.Writer
+	rowNum int
+}
+
+// xlsx 固定部分
+var xlsxFiles = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// NewXLSXWriter 创建 XLSX 表格
+func NewXLSXWriter(w io.Writer, sheetName string) (TableWriter, error) {
+	// 工作表名称不能包含 []:*?/\ 且最长 31 个字符
+	sheetName = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("[]:*?/\\", r) {
+			return -1
+		}
+		return r
+	}, sheetName)
+	if name := []rune(sheetName); len(name) > 31 {
+		sheetName = string(name[:31])
+	} else if len(name) == 0 {
+		sheetName = "Sheet1"
+	}
+	z := zip.NewWriter(w)
+	for _, file := range xlsxFiles {
+		f, err := z.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(f, file.content); err != nil {
+			return nil, err
+		}
+	}
+	// 工作簿
+	f, err := z.Create("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.WriteString(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`); err != nil {
+		return nil, err
+	}
+	if err = xml.EscapeText(f, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	if _, err = io.WriteString(f, `" sheetId="1" r:id="rId1"/></sheets></workbook>`); err != nil {
+		return nil, err
+	}
+	// 工作表，最后写入
+	f, err = z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err = sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{zip: z, sheet: sheet}, nil
+}
+
+// WriteRow 写入一行
+func (w *xlsxWriter) WriteRow(row []string) error {
+	w.rowNum++
+	if _, err := w.sheet.WriteString(`<row r="` + strconv.Itoa(w.rowNum) + `">`); err != nil {
+		return err
+	}
+	for _, cell := range row {
+		if _, err := w.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(w.sheet, []byte(sanitizeCell(cell))); err != nil {
+			return err
+		}
+		if _, err := w.sheet.WriteString(`</t></is></c>`); err != nil {
+			return err
+		}
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close 结束写入
+func (w *xlsxWriter) Close() error {
+	if _, err := w.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
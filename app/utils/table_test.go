@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := NewCSVWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]string{
+		{"昵称", "备注"},
+		{"小明", "=HYPERLINK(\"http://x\")"},
+		{"@SUM(A1)", "a,b"},
+	} {
+		if err = writer.WriteRow(row); err != nil {
+			t.Error(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Error(err)
+	}
+	res := buf.String()
+	if !strings.HasPrefix(res, "\xEF\xBB\xBF") {
+		t.Error("missing BOM")
+	}
+	// 公式前加单引号，包含逗号的内容加引号
+	want := "昵称,备注\n小明,\"'=HYPERLINK(\"\"http://x\"\")\"\n'@SUM(A1),\"a,b\"\n"
+	if res[3:] != want {
+		t.Errorf("wrong csv %q", res[3:])
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := NewXLSXWriter(buf, "任务[1]/参与者")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.WriteRow([]string{"<小明>", "+1", "-cmd"}); err != nil {
+		t.Error(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Error(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, file := range reader.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(content)
+	}
+	// 工作表名称去除非法字符
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="任务1参与者"`) {
+		t.Error("wrong sheet name", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, cell := range []string{"&lt;小明&gt;", "&#39;+1", "&#39;-cmd"} {
+		if !strings.Contains(sheet, `<t xml:space="preserve">`+cell+`</t>`) {
+			t.Error("missing cell", cell, sheet)
+		}
+	}
+	if !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Error("sheet not closed")
+	}
+}
+
+func TestSanitizeCell(t *testing.T) {
+	for cell, want := range map[string]string{
+		"":        "",
+		"普通文本":    "普通文本",
+		"1+1":     "1+1",
+		"=1+1":    "'=1+1",
+		"\tcmd":   "'\tcmd",
+		"@import": "'@import",
+	} {
+		if got := sanitizeCell(cell); got != want {
+			t.Errorf("sanitizeCell(%q) = %q, want %q", cell, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TokenType 令牌类型
+type TokenType string
+
+// TokenType 令牌类型
+const (
+	TokenAccess  TokenType = "access"  // 访问令牌
+	TokenRefresh TokenType = "refresh" // 刷新令牌
+	TokenAPI     TokenType = "api"     // 个人 API 令牌
+	TokenQRLogin TokenType = "qr"      // 扫码登陆二维码
+)
+
+// ErrInvalidToken 令牌格式、签名错误或已过期
+var ErrInvalidToken = errors.New("invalid_token")
+
+// TokenClaims 令牌内容
+type TokenClaims struct {
+	ID      string    `json:"jti"`           // 令牌 ID
+	Session string    `json:"sid"`           // 所属会话 ID，吊销会话后该会话的全部令牌失效
+	User    string    `json:"sub"`           // 用户 ID
+	Type    TokenType `json:"typ"`           // 令牌类型
+	Scopes  []string  `json:"scp,omitempty"` // 权限范围(仅个人 API 令牌)
+	Expire  int64     `json:"exp"`           // 过期时间，0 为永不过期
+}
+
+var tokenSecret []byte
+var tokenSecretOnce sync.Once
+
+// getTokenSecret 获取令牌签名密钥，未配置时使用随机密钥(重启后全部令牌失效)
+func getTokenSecret() []byte {
+	tokenSecretOnce.Do(func() {
+		if config != nil && config.Token.Secret != "" {
+			tokenSecret = []byte(config.Token.Secret)
+			return
+		}
+		log.Warn().Msg("Token secret is not configured, use random secret")
+		tokenSecret = make([]byte, 32)
+		_, _ = rand.Read(tokenSecret)
+	})
+	return tokenSecret
+}
+
+// NewTokenID 生成随机令牌 ID
+func NewTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// SignToken 签发令牌
+func SignToken(claims TokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + signTokenBody(body), nil
+}
+
+// ParseToken 校验令牌签名和有效期并解析令牌内容
+func ParseToken(token string) (claims TokenClaims, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(signTokenBody(parts[0]))) {
+		return claims, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+	if claims.Expire != 0 && claims.Expire < time.Now().Unix() {
+		return claims, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// signTokenBody 计算令牌签名
+func signTokenBody(body string) string {
+	mac := hmac.New(sha256.New, getTokenSecret())
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -45,6 +45,12 @@ func IsTaskStatus(status string) bool {
 	return false
 }
 
+// IsIdentity 判断是否为已配置的认证身份
+func IsIdentity(identity string) bool {
+	_, ok := GetIdentityConf(identity)
+	return ok
+}
+
 // CheckReward 判断是否为合法酬劳
 func CheckReward(reward, rewardObject string, rewardValue float32) {
 	if reward == "money" || reward == "rmb" {